@@ -3,6 +3,7 @@ CREATE TABLE `users` (
   `id` bigint NOT NULL AUTO_INCREMENT,
   `username` varchar(200) NOT NULL UNIQUE,
   `password` varchar(200) NOT NULL,
+  `create_time` bigint NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
@@ -17,4 +18,20 @@ CREATE TABLE `sessions` (
   `create_time` bigint NOT NULL,
   `exp_time` bigint NOT NULL,
   PRIMARY KEY (`id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `preferences`;
+CREATE TABLE `preferences` (
+  `user_id` bigint NOT NULL,
+  `show_nsfw` tinyint(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `bans`;
+CREATE TABLE `bans` (
+  `user_id` bigint NOT NULL,
+  `reason` varchar(500) NOT NULL DEFAULT '',
+  `create_time` bigint NOT NULL,
+  `exp_time` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,195 @@
+package automod
+
+import (
+	"net/url"
+	"reddit/pkg/user"
+	"time"
+)
+
+// Moderator is the author of the reports the engine files.
+var Moderator = &user.User{Username: "AutoModerator"}
+
+// Accounts tells the engine about authors, it is asked only when a rule
+// needs it. Submissions counts the posts or the comments of the author made
+// since the time.
+type Accounts interface {
+	Created(userID int64) (time.Time, error)
+	Karma(userID int64) (int, error)
+	Submissions(userID int64, kind string, since time.Time) (int, error)
+}
+
+// Submission is a post or a comment which is about to be saved. Comments
+// have only Body.
+type Submission struct {
+	Kind     string
+	Author   *user.User
+	Category string
+	Title    string
+	Body     string
+	Link     string
+}
+
+// Decision is the action of the first live rule matched by a submission or
+// of the first dry run rule when no live rule matched. Dry run decisions are
+// only to be logged. DryRuns names the other dry run rules the submission
+// matched.
+type Decision struct {
+	Rule    string
+	Action  string
+	Reason  string
+	DryRun  bool
+	DryRuns []string
+}
+
+type Engine struct {
+	Config   *Config
+	Accounts Accounts
+	now      func() time.Time
+}
+
+func NewEngine(config *Config, accounts Accounts) *Engine {
+	return &Engine{
+		Config:   config,
+		Accounts: accounts,
+		now:      time.Now,
+	}
+}
+
+// author caches what the engine knows about the author while a submission
+// is checked.
+type author struct {
+	engine      *Engine
+	user        *user.User
+	created     *time.Time
+	karma       *int
+	submissions map[time.Duration]int
+}
+
+func (a *author) age() (time.Duration, error) {
+	if a.created == nil {
+		created, err := a.engine.Accounts.Created(a.user.ID)
+		if err != nil {
+			return 0, err
+		}
+		a.created = &created
+	}
+	return a.engine.now().Sub(*a.created), nil
+}
+
+func (a *author) getKarma() (int, error) {
+	if a.karma == nil {
+		karma, err := a.engine.Accounts.Karma(a.user.ID)
+		if err != nil {
+			return 0, err
+		}
+		a.karma = &karma
+	}
+	return *a.karma, nil
+}
+
+// recentSubmissions counts the submissions of the kind in the last window.
+func (a *author) recentSubmissions(kind string, window time.Duration) (int, error) {
+	if count, ok := a.submissions[window]; ok {
+		return count, nil
+	}
+	count, err := a.engine.Accounts.Submissions(a.user.ID, kind, a.engine.now().Add(-window))
+	if err != nil {
+		return 0, err
+	}
+	if a.submissions == nil {
+		a.submissions = make(map[time.Duration]int)
+	}
+	a.submissions[window] = count
+	return count, nil
+}
+
+// Check returns the decision of the first live rule the submission matches,
+// dry run rules don't stop the check. The first dry run match is returned
+// only if no live rule matched, nil if the submission matches nothing.
+func (e *Engine) Check(s *Submission) (*Decision, error) {
+	a := &author{engine: e, user: s.Author}
+	var dryRun *Decision
+	var dryRuns []string
+	for _, rule := range e.Config.Rules {
+		matched, err := e.matches(rule, s, a)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = rule.Name
+		}
+		decision := &Decision{
+			Rule:   rule.Name,
+			Action: rule.Action,
+			Reason: reason,
+			DryRun: e.Config.DryRun || rule.DryRun,
+		}
+		if !decision.DryRun {
+			decision.DryRuns = dryRuns
+			return decision, nil
+		}
+		if dryRun == nil {
+			dryRun = decision
+		}
+		dryRuns = append(dryRuns, rule.Name)
+	}
+	if len(dryRuns) > 1 {
+		dryRun.DryRuns = dryRuns[1:]
+	}
+	return dryRun, nil
+}
+
+// matches checks the cheap conditions first, so the author is looked up only
+// for rules which match otherwise.
+func (e *Engine) matches(rule *Rule, s *Submission, a *author) (bool, error) {
+	if rule.Type != "" && rule.Type != s.Kind {
+		return false, nil
+	}
+	if !rule.matchesCategory(s.Category) {
+		return false, nil
+	}
+	if rule.title != nil && !rule.title.MatchString(s.Title) {
+		return false, nil
+	}
+	if rule.body != nil && !rule.body.MatchString(s.Body) {
+		return false, nil
+	}
+	if len(rule.Domains) != 0 {
+		link, err := url.Parse(s.Link)
+		if s.Link == "" || err != nil || !rule.matchesDomain(link.Hostname()) {
+			return false, nil
+		}
+	}
+	if rule.accountAge != 0 {
+		age, err := a.age()
+		if err != nil {
+			return false, err
+		}
+		if age >= rule.accountAge {
+			return false, nil
+		}
+	}
+	if rule.KarmaBelow != nil {
+		karma, err := a.getKarma()
+		if err != nil {
+			return false, err
+		}
+		if karma >= *rule.KarmaBelow {
+			return false, nil
+		}
+	}
+	if rule.within != 0 {
+		count, err := a.recentSubmissions(s.Kind, rule.within)
+		if err != nil {
+			return false, err
+		}
+		if count < rule.MaxSubmissions {
+			return false, nil
+		}
+	}
+	return true, nil
+}
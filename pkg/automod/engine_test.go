@@ -0,0 +1,226 @@
+package automod
+
+import (
+	"fmt"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+
+type testAccounts struct {
+	created     map[int64]time.Time
+	karma       map[int64]int
+	submissions map[string][]time.Time // by kind
+	lookups     int
+	err         error
+}
+
+func (a *testAccounts) Created(userID int64) (time.Time, error) {
+	a.lookups++
+	return a.created[userID], a.err
+}
+
+func (a *testAccounts) Karma(userID int64) (int, error) {
+	a.lookups++
+	return a.karma[userID], a.err
+}
+
+func (a *testAccounts) Submissions(userID int64, kind string, since time.Time) (int, error) {
+	a.lookups++
+	count := 0
+	for _, created := range a.submissions[kind] {
+		if !created.Before(since) {
+			count++
+		}
+	}
+	return count, a.err
+}
+
+var (
+	veteran  = &user.User{ID: 1, Username: "rvasily"}
+	newcomer = &user.User{ID: 2, Username: "igor"}
+)
+
+func newTestEngine(t *testing.T, rules string) (*Engine, *testAccounts) {
+	config, err := Parse([]byte(rules))
+	if err != nil {
+		t.Fatalf("Bad rules: %v", err)
+	}
+	accounts := &testAccounts{
+		created: map[int64]time.Time{1: now.Add(-365 * 24 * time.Hour), 2: now.Add(-time.Hour)},
+		karma:   map[int64]int{1: 100, 2: 0},
+	}
+	engine := NewEngine(config, accounts)
+	engine.now = func() time.Time { return now }
+	return engine, accounts
+}
+
+func checkRule(t *testing.T, engine *Engine, s *Submission, expectRule string) {
+	decision, err := engine.Check(s)
+	assert.Nil(t, err)
+	if expectRule == "" {
+		assert.Nil(t, decision, fmt.Sprintf("%+v", s))
+	} else if assert.NotNil(t, decision, fmt.Sprintf("%+v", s)) {
+		assert.Equal(t, expectRule, decision.Rule)
+	}
+}
+
+func TestTitleAndBodyRules(t *testing.T) {
+	engine, accounts := newTestEngine(t, `
+rules:
+  - name: money
+    title: (?i)free money
+    action: remove
+    reason: Spam
+  - name: casino
+    type: comment
+    body: casino
+    action: flag
+`)
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: veteran, Title: "FREE MONEY here"}, "money")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: veteran, Title: "Money", Body: "free money"}, "")
+	checkRule(t, engine, &Submission{Kind: posts.ItemComment, Author: veteran, Body: "best casino"}, "casino")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: veteran, Title: "casino", Body: "casino"}, "")
+	assert.Equal(t, 0, accounts.lookups)
+
+	decision, _ := engine.Check(&Submission{Kind: posts.ItemPost, Author: veteran, Title: "free money"})
+	assert.Equal(t, &Decision{Rule: "money", Action: ActionRemove, Reason: "Spam"}, decision)
+	decision, _ = engine.Check(&Submission{Kind: posts.ItemComment, Author: veteran, Body: "casino"})
+	assert.Equal(t, "casino", decision.Reason)
+}
+
+func TestDomainRule(t *testing.T) {
+	engine, _ := newTestEngine(t, `
+rules:
+  - name: shorteners
+    domains: [bit.ly]
+    categories: [music]
+    action: remove
+`)
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Category: "music", Link: "https://bit.ly/abc"}, "shorteners")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Category: "music", Link: "http://WWW.Bit.ly/abc"}, "shorteners")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Category: "music", Link: "https://notbit.ly/abc"}, "")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Category: "news", Link: "https://bit.ly/abc"}, "")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Category: "music"}, "")
+}
+
+func TestAccountRules(t *testing.T) {
+	engine, accounts := newTestEngine(t, `
+rules:
+  - name: newcomers
+    account_age: 24h
+    action: require_approval
+  - name: lowkarma
+    karma_below: 10
+    title: link
+    action: flag
+`)
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: newcomer, Title: "Hi"}, "newcomers")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: veteran, Title: "link"}, "")
+
+	accounts.created[2] = now.Add(-48 * time.Hour)
+	accounts.lookups = 0
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: newcomer, Title: "Hi"}, "")
+	assert.Equal(t, 1, accounts.lookups, "karma isn't needed without the title")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: newcomer, Title: "link"}, "lowkarma")
+
+	accounts.err = fmt.Errorf("DB err")
+	_, err := engine.Check(&Submission{Kind: posts.ItemPost, Author: newcomer, Title: "Hi"})
+	assert.EqualError(t, err, "DB err")
+}
+
+func TestSubmissionsRule(t *testing.T) {
+	engine, accounts := newTestEngine(t, `
+rules:
+  - name: flooding
+    type: comment
+    max_submissions: 3
+    within: 10m
+    action: remove
+`)
+	accounts.submissions = map[string][]time.Time{
+		posts.ItemComment: {now.Add(-time.Minute), now.Add(-5 * time.Minute), now.Add(-20 * time.Minute)},
+		posts.ItemPost:    {now, now, now},
+	}
+	checkRule(t, engine, &Submission{Kind: posts.ItemComment, Author: newcomer, Body: "Hi"}, "")
+	checkRule(t, engine, &Submission{Kind: posts.ItemPost, Author: newcomer, Title: "Hi"}, "")
+
+	accounts.submissions[posts.ItemComment] = append(accounts.submissions[posts.ItemComment], now.Add(-9*time.Minute))
+	checkRule(t, engine, &Submission{Kind: posts.ItemComment, Author: newcomer, Body: "Hi"}, "flooding")
+
+	accounts.err = fmt.Errorf("DB err")
+	_, err := engine.Check(&Submission{Kind: posts.ItemComment, Author: newcomer, Body: "Hi"})
+	assert.EqualError(t, err, "DB err")
+}
+
+func TestDryRun(t *testing.T) {
+	engine, _ := newTestEngine(t, `
+rules:
+  - name: test
+    title: test
+    action: remove
+    dry_run: true
+`)
+	decision, err := engine.Check(&Submission{Kind: posts.ItemPost, Title: "test"})
+	assert.Nil(t, err)
+	assert.True(t, decision.DryRun)
+
+	engine, _ = newTestEngine(t, `
+dry_run: true
+rules:
+  - name: test
+    title: test
+    action: remove
+`)
+	decision, err = engine.Check(&Submission{Kind: posts.ItemPost, Title: "test"})
+	assert.Nil(t, err)
+	assert.True(t, decision.DryRun)
+}
+
+func TestDryRunDoesNotShadowLiveRules(t *testing.T) {
+	engine, _ := newTestEngine(t, `
+rules:
+  - name: trial
+    title: free
+    action: flag
+    dry_run: true
+  - name: spam
+    title: free
+    action: remove
+`)
+	decision, err := engine.Check(&Submission{Kind: posts.ItemPost, Title: "free stuff"})
+	assert.Nil(t, err)
+	assert.Equal(t, &Decision{
+		Rule:    "spam",
+		Action:  ActionRemove,
+		Reason:  "spam",
+		DryRuns: []string{"trial"},
+	}, decision)
+
+	//Only dry run rules matched
+	engine, _ = newTestEngine(t, `
+rules:
+  - name: trial
+    title: free
+    action: flag
+    dry_run: true
+  - name: second
+    title: stuff
+    action: remove
+    dry_run: true
+`)
+	decision, err = engine.Check(&Submission{Kind: posts.ItemPost, Title: "free stuff"})
+	assert.Nil(t, err)
+	assert.Equal(t, &Decision{
+		Rule:    "trial",
+		Action:  ActionFlag,
+		Reason:  "trial",
+		DryRun:  true,
+		DryRuns: []string{"second"},
+	}, decision)
+}
@@ -0,0 +1,169 @@
+package automod
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reddit/pkg/posts"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Actions a rule can take on a matching submission.
+const (
+	ActionRemove          = "remove"           // reject, nothing is saved
+	ActionFlag            = "flag"             // save and report to the moderators
+	ActionRequireApproval = "require_approval" // save hidden until approved
+)
+
+// Config is the YAML rules file:
+//
+//	dry_run: false
+//	rules:
+//	  - name: shorteners
+//	    type: post
+//	    domains: [bit.ly, tinyurl.com]
+//	    action: remove
+//	    reason: Link shorteners are not allowed
+//	  - name: newcomers
+//	    account_age: 24h
+//	    karma_below: 5
+//	    action: require_approval
+//	  - name: flooding
+//	    type: comment
+//	    max_submissions: 10
+//	    within: 10m
+//	    action: remove
+//
+// Conditions of a rule must all match, the first matching rule wins.
+type Config struct {
+	DryRun bool    `yaml:"dry_run"`
+	Rules  []*Rule `yaml:"rules"`
+}
+
+// Rule matches a submission by its text, link, category and author. Empty
+// conditions match everything. AccountAge matches accounts younger than it.
+// MaxSubmissions matches authors who already made that many submissions of
+// the same type in the last Within.
+type Rule struct {
+	Name           string   `yaml:"name"`
+	Type           string   `yaml:"type"` // post, comment or empty for both
+	Categories     []string `yaml:"categories"`
+	Title          string   `yaml:"title"`
+	Body           string   `yaml:"body"`
+	Domains        []string `yaml:"domains"`
+	AccountAge     string   `yaml:"account_age"`
+	KarmaBelow     *int     `yaml:"karma_below"`
+	MaxSubmissions int      `yaml:"max_submissions"`
+	Within         string   `yaml:"within"`
+	Action         string   `yaml:"action"`
+	Reason         string   `yaml:"reason"`
+	DryRun         bool     `yaml:"dry_run"`
+
+	title      *regexp.Regexp
+	body       *regexp.Regexp
+	accountAge time.Duration
+	within     time.Duration
+}
+
+// Load reads the rules file.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads and checks the rules, the errors name the broken rule.
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	err := yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("Bad rules: %v", err)
+	}
+	for i, rule := range config.Rules {
+		err = rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("Bad rule %d %q: %v", i+1, rule.Name, err)
+		}
+	}
+	return config, nil
+}
+
+func (rule *Rule) compile() error {
+	var err error
+	switch rule.Type {
+	case "", posts.ItemPost, posts.ItemComment:
+	default:
+		return fmt.Errorf("type must be post or comment")
+	}
+	switch rule.Action {
+	case ActionRemove, ActionFlag, ActionRequireApproval:
+	default:
+		return fmt.Errorf("action must be one of: %v, %v, %v", ActionRemove, ActionFlag, ActionRequireApproval)
+	}
+	if rule.Title != "" {
+		rule.title, err = regexp.Compile(rule.Title)
+		if err != nil {
+			return fmt.Errorf("bad title: %v", err)
+		}
+	}
+	if rule.Body != "" {
+		rule.body, err = regexp.Compile(rule.Body)
+		if err != nil {
+			return fmt.Errorf("bad body: %v", err)
+		}
+	}
+	if rule.AccountAge != "" {
+		rule.accountAge, err = time.ParseDuration(rule.AccountAge)
+		if err != nil || rule.accountAge <= 0 {
+			return fmt.Errorf("bad account_age %q", rule.AccountAge)
+		}
+	}
+	if rule.MaxSubmissions < 0 {
+		return fmt.Errorf("bad max_submissions %d", rule.MaxSubmissions)
+	}
+	if (rule.MaxSubmissions == 0) != (rule.Within == "") {
+		return fmt.Errorf("max_submissions and within go together")
+	}
+	if rule.Within != "" {
+		rule.within, err = time.ParseDuration(rule.Within)
+		if err != nil || rule.within <= 0 {
+			return fmt.Errorf("bad within %q", rule.Within)
+		}
+	}
+	for i, domain := range rule.Domains {
+		rule.Domains[i] = strings.ToLower(strings.TrimPrefix(domain, "www."))
+	}
+	if rule.title == nil && rule.body == nil && len(rule.Domains) == 0 &&
+		rule.accountAge == 0 && rule.KarmaBelow == nil && rule.within == 0 {
+		return fmt.Errorf("has no conditions")
+	}
+	return nil
+}
+
+// matchesDomain tells if host is one of the domains or their subdomain.
+func (rule *Rule) matchesDomain(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range rule.Domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *Rule) matchesCategory(category string) bool {
+	if len(rule.Categories) == 0 {
+		return true
+	}
+	for _, name := range rule.Categories {
+		if name == category {
+			return true
+		}
+	}
+	return false
+}
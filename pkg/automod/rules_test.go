@@ -0,0 +1,50 @@
+package automod
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(`
+dry_run: true
+rules:
+  - name: shorteners
+    type: post
+    domains: [www.Bit.ly]
+    action: remove
+  - name: newcomers
+    account_age: 24h
+    karma_below: 5
+    action: require_approval
+  - name: flooding
+    max_submissions: 10
+    within: 10m
+    action: remove
+`))
+	assert.Nil(t, err)
+	assert.True(t, config.DryRun)
+	assert.Len(t, config.Rules, 3)
+	assert.Equal(t, []string{"bit.ly"}, config.Rules[0].Domains)
+	assert.Equal(t, 5, *config.Rules[1].KarmaBelow)
+	assert.Equal(t, 10*time.Minute, config.Rules[2].within)
+
+	badRules := map[string]string{
+		"rules: [{name: a, title: x, action: ban}]":                         `Bad rule 1 "a": action must be one of: remove, flag, require_approval`,
+		"rules: [{name: a, title: x, type: link, action: flag}]":            `Bad rule 1 "a": type must be post or comment`,
+		"rules: [{name: a, title: '(', action: flag}]":                      "Bad rule 1 \"a\": bad title: error parsing regexp: missing closing ): `(`",
+		"rules: [{name: a, account_age: soon, action: flag}]":               `Bad rule 1 "a": bad account_age "soon"`,
+		"rules: [{name: a, action: flag}]":                                  `Bad rule 1 "a": has no conditions`,
+		"rules: [{name: a, max_submissions: 3, action: flag}]":              `Bad rule 1 "a": max_submissions and within go together`,
+		"rules: [{name: a, within: 1h, action: flag}]":                      `Bad rule 1 "a": max_submissions and within go together`,
+		"rules: [{name: a, max_submissions: 3, within: 0s, action: flag}]":  `Bad rule 1 "a": bad within "0s"`,
+		"rules: [{name: a, max_submissions: -1, within: 1h, action: flag}]": `Bad rule 1 "a": bad max_submissions -1`,
+		"rules: [{name: a, title: x, action: flag, unknown: true}]":         "Bad rules: yaml: unmarshal errors:\n  line 1: field unknown not found in type automod.Rule",
+	}
+	for data, expectErr := range badRules {
+		_, err = Parse([]byte(data))
+		assert.EqualError(t, err, expectErr)
+	}
+}
@@ -0,0 +1,98 @@
+package category
+
+import (
+	"log"
+	"reddit/pkg/user"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Ban keeps the user from posting and commenting in the category. Times are
+// RFC3339 in UTC so they compare as strings, empty Expires means the ban
+// never ends.
+type Ban struct {
+	Category  string     `json:"category" bson:"category"`
+	User      *user.User `json:"user" bson:"user"`
+	Moderator *user.User `json:"moderator" bson:"moderator"`
+	Reason    string     `json:"reason,omitempty" bson:"reason,omitempty"`
+	Created   string     `json:"created" bson:"created"`
+	Expires   string     `json:"expires,omitempty" bson:"expires"`
+}
+
+type BansRepo struct {
+	DB  CollectionInterface
+	now func() time.Time
+}
+
+func NewBansRepo(collection CollectionInterface) *BansRepo {
+	return &BansRepo{DB: collection, now: time.Now}
+}
+
+func (repo *BansRepo) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"category", "user.id"},
+		Unique: true,
+	})
+}
+
+// activeQuery matches the bans of the category which haven't expired yet.
+func (repo *BansRepo) activeQuery(category string) bson.M {
+	return bson.M{
+		"category": category,
+		"$or": []bson.M{
+			{"expires": ""},
+			{"expires": bson.M{"$gt": repo.now().UTC().Format(time.RFC3339)}},
+		},
+	}
+}
+
+// Ban bans the user, banning again replaces the reason and the expiry.
+func (repo *BansRepo) Ban(ban *Ban) error {
+	ban.Created = repo.now().UTC().Format(time.RFC3339)
+	_, err := repo.DB.Upsert(bson.M{"category": ban.Category, "user.id": ban.User.ID}, ban)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *BansRepo) Unban(category string, userID int64) (bool, error) {
+	err := repo.DB.Remove(bson.M{"category": category, "user.id": userID})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get returns the active ban of the user in the category or nil if the user
+// isn't banned there.
+func (repo *BansRepo) Get(category string, userID int64) (*Ban, error) {
+	query := repo.activeQuery(category)
+	query["user.id"] = userID
+	ban := &Ban{}
+	err := repo.DB.Find(query).One(ban)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return ban, nil
+}
+
+// GetByCategory returns the active bans of the category, newest first.
+func (repo *BansRepo) GetByCategory(category string) ([]*Ban, error) {
+	bans := []*Ban{}
+	err := repo.DB.Find(repo.activeQuery(category)).Sort("-created").All(&bans)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return bans, nil
+}
@@ -0,0 +1,96 @@
+package category
+
+import (
+	"fmt"
+	"reddit/pkg/user"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestBans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	mockQuery := NewMockQueryInterface(ctrl)
+	testRepo := NewBansRepo(mockDB)
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.FixedZone("MSK", 3*60*60))
+	testRepo.now = func() time.Time { return now }
+
+	banned := &user.User{ID: 2, Username: "igor"}
+	ban := &Ban{
+		Category:  "music",
+		User:      banned,
+		Moderator: &user.User{ID: 1, Username: "rvasily"},
+		Expires:   "2020-05-13T19:33:02Z",
+	}
+	// expired bans are filtered out by the query, compared in UTC
+	active := bson.M{
+		"category": "music",
+		"$or": []bson.M{
+			{"expires": ""},
+			{"expires": bson.M{"$gt": "2020-05-12T19:33:02Z"}},
+		},
+	}
+
+	//Ban
+	mockDB.EXPECT().Upsert(bson.M{"category": "music", "user.id": int64(2)}, ban).Return(&mgo.ChangeInfo{}, nil)
+
+	err := testRepo.Ban(ban)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "2020-05-12T19:33:02Z", ban.Created)
+
+	//Active ban
+	activeOfUser := bson.M{"user.id": int64(2)}
+	for key, value := range active {
+		activeOfUser[key] = value
+	}
+	mockDB.EXPECT().Find(activeOfUser).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).SetArg(0, *ban)
+
+	found, err := testRepo.Get("music", 2)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, ban, found)
+
+	//Expired or no ban
+	mockDB.EXPECT().Find(activeOfUser).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).Return(mgo.ErrNotFound)
+
+	found, err = testRepo.Get("music", 2)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Nil(t, found)
+
+	//Active bans of the category
+	mockDB.EXPECT().Find(active).Return(mockQuery)
+	mockQuery.EXPECT().Sort("-created").Return(mockQuery)
+	mockQuery.EXPECT().All(gomock.Any()).SetArg(0, []*Ban{ban})
+
+	bans, err := testRepo.GetByCategory("music")
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, []*Ban{ban}, bans)
+
+	//Unban
+	mockDB.EXPECT().Remove(bson.M{"category": "music", "user.id": int64(2)}).Return(nil)
+
+	removed, err := testRepo.Unban("music", 2)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.True(t, removed)
+
+	mockDB.EXPECT().Remove(bson.M{"category": "music", "user.id": int64(2)}).Return(mgo.ErrNotFound)
+
+	removed, err = testRepo.Unban("music", 2)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.False(t, removed)
+
+	//BD error
+	mockDB.EXPECT().Find(activeOfUser).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	_, err = testRepo.Get("music", 2)
+	assert.EqualError(t, err, "Internal error")
+}
@@ -0,0 +1,138 @@
+package category
+
+import (
+	"errors"
+	"log"
+	"reddit/pkg/user"
+	"regexp"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Category struct {
+	Name        string     `json:"name" bson:"_id"`
+	Owner       *user.User `json:"owner" bson:"owner"`
+	Description string     `json:"description" bson:"description"`
+	Rules       []string   `json:"rules" bson:"rules"`
+	Created     string     `json:"created" bson:"created"`
+	Flairs      []Flair    `json:"flairs,omitempty" bson:"flairs,omitempty"`
+}
+
+// Flair is a tag which posts of a category can be marked with.
+type Flair struct {
+	Name  string `json:"name" bson:"name"`
+	Color string `json:"color,omitempty" bson:"color,omitempty"`
+}
+
+type CategoryRepo struct {
+	DB CollectionInterface
+}
+
+var (
+	ErrNoCategory      = errors.New("No category found")
+	ErrBadName         = errors.New("Bad category name")
+	ErrAlreadyExisting = errors.New("This category already existing")
+)
+
+// DefaultCategories are the categories the frontend offers out of the box,
+// they are created on start so old posts keep pointing to a real category.
+var DefaultCategories = []string{"music", "funny", "videos", "programming", "news", "fashion"}
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9_]{2,21}$`)
+
+// NameHint explains to users what a valid category name looks like.
+const NameHint = "must be 2-21 lowercase letters, digits or underscores"
+
+func ValidName(name string) bool {
+	return nameRegexp.MatchString(name)
+}
+
+// IsModerator reports whether u may moderate the category.
+func (c *Category) IsModerator(u *user.User) bool {
+	return u != nil && c.Owner != nil && c.Owner.ID == u.ID
+}
+
+func (c *Category) HasFlair(name string) bool {
+	for _, flair := range c.Flairs {
+		if flair.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func NewRepo(collection CollectionInterface) *CategoryRepo {
+	return &CategoryRepo{DB: collection}
+}
+
+func (repo *CategoryRepo) EnsureDefaults() error {
+	for _, name := range DefaultCategories {
+		_, err := repo.Add(nil, name, "", []string{})
+		if err != nil && err != ErrAlreadyExisting {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *CategoryRepo) GetAll() ([]*Category, error) {
+	categories := []*Category{}
+	err := repo.DB.Find(bson.M{}).Sort("_id").All(&categories)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (repo *CategoryRepo) GetByName(name string) (*Category, error) {
+	var category *Category
+	err := repo.DB.Find(bson.M{"_id": name}).One(&category)
+	if err == mgo.ErrNotFound {
+		return nil, ErrNoCategory
+	}
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return category, nil
+}
+
+func (repo *CategoryRepo) SetFlairs(name string, flairs []Flair) error {
+	err := repo.DB.Update(bson.M{"_id": name}, bson.M{"$set": bson.M{"flairs": flairs}})
+	if err == mgo.ErrNotFound {
+		return ErrNoCategory
+	}
+	return err
+}
+
+func (repo *CategoryRepo) Add(
+	owner *user.User,
+	name string,
+	description string,
+	rules []string) (*Category, error) {
+	if !ValidName(name) {
+		return nil, ErrBadName
+	}
+	if rules == nil {
+		rules = make([]string, 0)
+	}
+	newCategory := &Category{
+		Name:        name,
+		Owner:       owner,
+		Description: description,
+		Rules:       rules,
+		Created:     time.Now().Format(time.RFC3339),
+	}
+	err := repo.DB.Insert(newCategory)
+	if mgo.IsDup(err) {
+		return nil, ErrAlreadyExisting
+	}
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return nil, err
+	}
+	return newCategory, nil
+}
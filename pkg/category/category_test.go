@@ -0,0 +1,109 @@
+package category
+
+import (
+	"fmt"
+	"reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testCategory = &Category{
+	Name:        "music",
+	Owner:       &user.User{ID: 1, Username: "rvasily"},
+	Description: "Music",
+	Rules:       []string{"Be nice"},
+	Created:     "2020-05-12T22:33:02+03:00",
+}
+
+func TestGetByName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	mockQuery := NewMockQueryInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Find(bson.M{"_id": "music"}).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).SetArg(0, testCategory)
+
+	cat, err := testRepo.GetByName("music")
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, testCategory, cat)
+
+	//No category
+	mockDB.EXPECT().Find(bson.M{"_id": "jazz"}).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).Return(mgo.ErrNotFound)
+
+	cat, err = testRepo.GetByName("jazz")
+	assert.Nil(t, cat)
+	assert.Equal(t, ErrNoCategory, err)
+
+	//BD error
+	mockDB.EXPECT().Find(bson.M{"_id": "music"}).Return(mockQuery)
+	mockQuery.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	_, err = testRepo.GetByName("music")
+	assert.EqualError(t, err, "Internal error")
+}
+
+func TestGetAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	mockQuery := NewMockQueryInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Find(bson.M{}).Return(mockQuery)
+	mockQuery.EXPECT().Sort("_id").Return(mockQuery)
+	mockQuery.EXPECT().All(gomock.Any()).SetArg(0, []*Category{testCategory})
+
+	categories, err := testRepo.GetAll()
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, []*Category{testCategory}, categories)
+}
+
+func TestAdd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+	owner := &user.User{ID: 1, Username: "rvasily"}
+
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+
+	cat, err := testRepo.Add(owner, "music", "Music", nil)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "music", cat.Name)
+	assert.Equal(t, []string{}, cat.Rules)
+
+	//Bad name
+	_, err = testRepo.Add(owner, "Music!", "", nil)
+	assert.Equal(t, ErrBadName, err)
+
+	//Existing
+	mockDB.EXPECT().Insert(gomock.Any()).Return(&mgo.LastError{Code: 11000})
+
+	_, err = testRepo.Add(owner, "music", "", nil)
+	assert.Equal(t, ErrAlreadyExisting, err)
+}
+
+func TestSetFlairs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+	flairs := []Flair{{Name: "News"}}
+
+	mockDB.EXPECT().Update(bson.M{"_id": "music"}, bson.M{"$set": bson.M{"flairs": flairs}}).Return(nil)
+	assert.Nil(t, testRepo.SetFlairs("music", flairs))
+
+	mockDB.EXPECT().Update(bson.M{"_id": "jazz"}, bson.M{"$set": bson.M{"flairs": flairs}}).Return(mgo.ErrNotFound)
+	assert.Equal(t, ErrNoCategory, testRepo.SetFlairs("jazz", flairs))
+}
@@ -0,0 +1,41 @@
+package category
+
+import (
+	"gopkg.in/mgo.v2"
+)
+
+// QueryInterface is the part of *mgo.Query the repos use.
+type QueryInterface interface {
+	One(interface{}) error
+	All(interface{}) error
+	Sort(...string) QueryInterface
+}
+
+// CollectionInterface is the part of *mgo.Collection the repos use, tests
+// put a mock in its place.
+type CollectionInterface interface {
+	Find(interface{}) QueryInterface
+	Insert(...interface{}) error
+	Update(interface{}, interface{}) error
+	Upsert(interface{}, interface{}) (*mgo.ChangeInfo, error)
+	Remove(interface{}) error
+	EnsureIndex(mgo.Index) error
+}
+
+// MongoCollection lets *mgo.Collection be used as CollectionInterface, mgo
+// returns the concrete *mgo.Query from Find so it can't satisfy it alone.
+type MongoCollection struct {
+	*mgo.Collection
+}
+
+func (c *MongoCollection) Find(query interface{}) QueryInterface {
+	return &mongoQuery{c.Collection.Find(query)}
+}
+
+type mongoQuery struct {
+	*mgo.Query
+}
+
+func (q *mongoQuery) Sort(fields ...string) QueryInterface {
+	return &mongoQuery{q.Query.Sort(fields...)}
+}
@@ -0,0 +1,65 @@
+package category
+
+import (
+	"log"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Subscription struct {
+	UserID   int64  `json:"user,string" bson:"user"`
+	Category string `json:"category" bson:"category"`
+}
+
+type SubscriptionRepo struct {
+	DB CollectionInterface
+}
+
+func NewSubscriptionRepo(collection CollectionInterface) *SubscriptionRepo {
+	return &SubscriptionRepo{DB: collection}
+}
+
+func (repo *SubscriptionRepo) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"user", "category"},
+		Unique: true,
+	})
+}
+
+func (repo *SubscriptionRepo) GetByUser(userID int64) ([]string, error) {
+	subscriptions := []*Subscription{}
+	err := repo.DB.Find(bson.M{"user": userID}).Sort("category").All(&subscriptions)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	categories := make([]string, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		categories = append(categories, subscription.Category)
+	}
+	return categories, nil
+}
+
+func (repo *SubscriptionRepo) Subscribe(userID int64, category string) error {
+	subscription := &Subscription{
+		UserID:   userID,
+		Category: category,
+	}
+	_, err := repo.DB.Upsert(bson.M{"user": userID, "category": category}, subscription)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SubscriptionRepo) Unsubscribe(userID int64, category string) (bool, error) {
+	err := repo.DB.Remove(bson.M{"user": userID, "category": category})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package category
+
+import (
+	"fmt"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSubscriptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockCollectionInterface(ctrl)
+	mockQuery := NewMockQueryInterface(ctrl)
+	testRepo := NewSubscriptionRepo(mockDB)
+	selector := bson.M{"user": int64(1), "category": "music"}
+
+	//Subscribe
+	mockDB.EXPECT().Upsert(selector, &Subscription{UserID: 1, Category: "music"}).Return(&mgo.ChangeInfo{}, nil)
+
+	err := testRepo.Subscribe(1, "music")
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//List
+	mockDB.EXPECT().Find(bson.M{"user": int64(1)}).Return(mockQuery)
+	mockQuery.EXPECT().Sort("category").Return(mockQuery)
+	mockQuery.EXPECT().All(gomock.Any()).SetArg(0, []*Subscription{
+		{UserID: 1, Category: "music"},
+		{UserID: 1, Category: "news"},
+	})
+
+	categories, err := testRepo.GetByUser(1)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, []string{"music", "news"}, categories)
+
+	//Unsubscribe
+	mockDB.EXPECT().Remove(selector).Return(nil)
+
+	removed, err := testRepo.Unsubscribe(1, "music")
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.True(t, removed)
+
+	//Not subscribed
+	mockDB.EXPECT().Remove(selector).Return(mgo.ErrNotFound)
+
+	removed, err = testRepo.Unsubscribe(1, "music")
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.False(t, removed)
+
+	//BD error
+	mockDB.EXPECT().Upsert(selector, gomock.Any()).Return(nil, fmt.Errorf("Internal error"))
+
+	err = testRepo.Subscribe(1, "music")
+	assert.EqualError(t, err, "Internal error")
+}
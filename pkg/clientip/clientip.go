@@ -0,0 +1,73 @@
+// Package clientip finds the address of the client behind trusted reverse
+// proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Proxies are the networks of the reverse proxies in front of the server.
+// Only they are believed about X-Forwarded-For.
+type Proxies []*net.IPNet
+
+// ParseProxies reads a comma separated list of CIDRs or single IPs.
+func ParseProxies(list string) (Proxies, error) {
+	var proxies Proxies
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if strings.Contains(item, ":") {
+				item += "/128"
+			} else {
+				item += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy %q: %v", item, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func (p Proxies) trusted(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP finds the address of the client. X-Forwarded-For is read from the
+// right while the hops are trusted proxies, as anything left of the first
+// untrusted hop can be made up by the client.
+func (p Proxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !p.trusted(ip) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !p.trusted(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
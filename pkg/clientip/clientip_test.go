@@ -0,0 +1,41 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies("10.0.0.0/8, 192.168.1.1")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		remote    string
+		forwarded string
+		ip        string
+	}{
+		{"203.0.113.5:1234", "", "203.0.113.5"},
+		//Untrusted clients can't forge the header
+		{"203.0.113.5:1234", "1.2.3.4", "203.0.113.5"},
+		{"10.0.0.1:80", "198.51.100.7", "198.51.100.7"},
+		//The rightmost untrusted hop is the client
+		{"10.0.0.1:80", "1.2.3.4, 198.51.100.7, 192.168.1.1", "198.51.100.7"},
+		//Only proxies in the chain
+		{"10.0.0.1:80", "10.0.0.2", "10.0.0.2"},
+		{"10.0.0.1:80", "", "10.0.0.1"},
+		{"10.0.0.1:80", "garbage, 198.51.100.7", "198.51.100.7"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/api/login", nil)
+		r.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		assert.Equal(t, c.ip, proxies.ClientIP(r), c.forwarded)
+	}
+
+	_, err = ParseProxies("10.0.0.0/40")
+	assert.Error(t, err)
+}
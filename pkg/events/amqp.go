@@ -0,0 +1,289 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+var errNacked = errors.New("amqp: the broker didn't take the event")
+
+// AMQP publishes the events to a topic exchange of RabbitMQ with the event
+// type as the routing key, and consumes them from a durable queue bound by
+// the patterns of the subscribers. Publish returns after the broker
+// confirmed the event, and a delivery is acked after all the handlers
+// succeeded, so the events are delivered at least once. A failed delivery
+// is retried once and then dropped. The connections exchange heartbeats,
+// so a dead broker or a half-open connection is noticed and redialed.
+type AMQP struct {
+	URL       string
+	Exchange  string
+	Queue     string
+	Prefetch  int
+	Heartbeat time.Duration
+	Timeout   time.Duration
+	Retry     time.Duration
+	Logger    *zap.SugaredLogger
+
+	mu    sync.Mutex // guards the publishing connection
+	pub   *amqp.Connection
+	pubCh *amqp.Channel
+
+	subsMu sync.RWMutex
+	subs   []subscription
+
+	consumerMu sync.Mutex
+	consumer   *amqp.Connection
+	closing    chan struct{}
+	wg         sync.WaitGroup
+}
+
+func NewAMQP(url, exchange, queue string, logger *zap.SugaredLogger) *AMQP {
+	return &AMQP{
+		URL:       url,
+		Exchange:  exchange,
+		Queue:     queue,
+		Prefetch:  16,
+		Heartbeat: 10 * time.Second,
+		Timeout:   5 * time.Second,
+		Retry:     5 * time.Second,
+		Logger:    logger,
+	}
+}
+
+// Subscribe adds a handler, the subscribers must be added before Start.
+func (a *AMQP) Subscribe(pattern string, handler Handler) {
+	a.subsMu.Lock()
+	defer a.subsMu.Unlock()
+	a.subs = append(a.subs, subscription{pattern: pattern, handler: handler})
+}
+
+// Publish sends the event and waits for the confirm of the broker. A broken
+// connection is dropped, the next event opens a new one.
+func (a *AMQP) Publish(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pub == nil || a.pub.IsClosed() {
+		conn, ch, err := a.dial()
+		if err != nil {
+			return err
+		}
+		err = ch.Confirm(false)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		a.pub, a.pubCh = conn, ch
+	}
+	err = a.publish(event, body)
+	if err != nil {
+		a.pub.Close()
+		a.pub, a.pubCh = nil, nil
+	}
+	return err
+}
+
+func (a *AMQP) publish(event *Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+	confirm, err := a.pubCh.PublishWithDeferredConfirmWithContext(ctx, a.Exchange, event.Type, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    event.ID,
+		Type:         event.Type,
+		Body:         body,
+	})
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errNacked
+	}
+	return nil
+}
+
+// Start consumes the queue in the background and reconnects after errors.
+func (a *AMQP) Start() {
+	a.closing = make(chan struct{})
+	a.wg.Add(1)
+	go a.run()
+}
+
+// Close stops the consumer and closes the connections. The unacked
+// deliveries go back to the queue.
+func (a *AMQP) Close() error {
+	if a.closing != nil {
+		close(a.closing)
+		a.consumerMu.Lock()
+		if a.consumer != nil {
+			a.consumer.Close()
+		}
+		a.consumerMu.Unlock()
+		a.wg.Wait()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pub == nil {
+		return nil
+	}
+	err := a.pub.Close()
+	a.pub, a.pubCh = nil, nil
+	return err
+}
+
+func (a *AMQP) run() {
+	defer a.wg.Done()
+	for {
+		err := a.consume()
+		select {
+		case <-a.closing:
+			return
+		default:
+		}
+		a.Logger.Errorf("AMQP consumer error: %v", err)
+		select {
+		case <-a.closing:
+			return
+		case <-time.After(a.Retry):
+		}
+	}
+}
+
+func (a *AMQP) consume() error {
+	conn, ch, err := a.dial()
+	if err != nil {
+		return err
+	}
+	a.consumerMu.Lock()
+	select {
+	case <-a.closing:
+		a.consumerMu.Unlock()
+		return conn.Close()
+	default:
+	}
+	a.consumer = conn
+	a.consumerMu.Unlock()
+	defer func() {
+		a.consumerMu.Lock()
+		a.consumer = nil
+		a.consumerMu.Unlock()
+		conn.Close()
+	}()
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	_, err = ch.QueueDeclare(a.Queue, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range a.patterns() {
+		err = ch.QueueBind(a.Queue, pattern, a.Exchange, false, nil)
+		if err != nil {
+			return err
+		}
+	}
+	err = ch.Qos(a.Prefetch, 0, false)
+	if err != nil {
+		return err
+	}
+	deliveries, err := ch.Consume(a.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	// the deliveries end when the connection is closed, by Close or by the
+	// missed heartbeats of a dead broker
+	for delivery := range deliveries {
+		err = a.deliver(delivery)
+		if err != nil {
+			return err
+		}
+	}
+	if closeErr := <-closed; closeErr != nil {
+		return closeErr
+	}
+	return amqp.ErrClosed
+}
+
+// deliver runs the handlers, it acks a handled delivery and rejects a
+// failed one, a delivery which already failed once isn't requeued.
+func (a *AMQP) deliver(delivery amqp.Delivery) error {
+	err := a.handle(delivery.Body)
+	if err == nil {
+		return delivery.Ack(false)
+	}
+	a.Logger.Errorf("Event delivery %v error (redelivered %v): %v", delivery.DeliveryTag, delivery.Redelivered, err)
+	return delivery.Reject(!delivery.Redelivered)
+}
+
+// patterns are the distinct patterns of the subscribers.
+func (a *AMQP) patterns() []string {
+	a.subsMu.RLock()
+	defer a.subsMu.RUnlock()
+	var patterns []string
+	seen := map[string]bool{}
+	for _, sub := range a.subs {
+		if !seen[sub.pattern] {
+			seen[sub.pattern] = true
+			patterns = append(patterns, sub.pattern)
+		}
+	}
+	return patterns
+}
+
+// handle runs all the matching handlers and returns the last error.
+func (a *AMQP) handle(body []byte) error {
+	event := &Event{}
+	err := json.Unmarshal(body, event)
+	if err != nil {
+		return err
+	}
+	a.subsMu.RLock()
+	subs := a.subs
+	a.subsMu.RUnlock()
+	var failed error
+	for _, sub := range subs {
+		if !Match(sub.pattern, event.Type) {
+			continue
+		}
+		err = sub.handler(event)
+		if err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// dial opens a connection with heartbeats and a channel, and declares the
+// exchange.
+func (a *AMQP) dial() (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.DialConfig(a.URL, amqp.Config{
+		Heartbeat:  a.Heartbeat,
+		Dial:       amqp.DefaultDial(a.Timeout),
+		Properties: amqp.Table{"product": "reddit"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := conn.Channel()
+	if err == nil {
+		err = ch.ExchangeDeclare(a.Exchange, "topic", true, false, false, false, nil)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, ch, nil
+}
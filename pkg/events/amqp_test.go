@@ -0,0 +1,116 @@
+package events
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeAcknowledger records what the consumer answers to the broker.
+type fakeAcknowledger struct {
+	acks    []uint64
+	rejects []bool
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.acks = append(f.acks, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	f.rejects = append(f.rejects, requeue)
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	f.rejects = append(f.rejects, requeue)
+	return nil
+}
+
+func delivery(t *testing.T, ack amqp.Acknowledger, tag uint64, redelivered bool, event *Event) amqp.Delivery {
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Redelivered: redelivered, Body: body}
+}
+
+func TestAMQPDeliver(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+
+	var comments, all []*Event
+	var fail error
+	consumer := NewAMQP("amqp://localhost", "reddit", "notifications", zapLogger.Sugar())
+	consumer.Subscribe("comment.*", func(event *Event) error {
+		comments = append(comments, event)
+		return fail
+	})
+	consumer.Subscribe("#", func(event *Event) error {
+		all = append(all, event)
+		return nil
+	})
+	consumer.Subscribe("comment.*", func(event *Event) error {
+		return nil
+	})
+	assert.Equal(t, []string{"comment.*", "#"}, consumer.patterns())
+
+	ack := &fakeAcknowledger{}
+	post, _ := New(PostCreated, map[string]string{"title": "hi"})
+	comment, _ := New(CommentAdded, map[string]string{"body": "hi"})
+	assert.NoError(t, consumer.deliver(delivery(t, ack, 1, false, post)))
+	assert.NoError(t, consumer.deliver(delivery(t, ack, 2, false, comment)))
+	assert.Equal(t, []*Event{post, comment}, all)
+	assert.Equal(t, []*Event{comment}, comments)
+	assert.Equal(t, []uint64{1, 2}, ack.acks)
+
+	// retried once, then dropped
+	fail = ErrQueueFull
+	assert.NoError(t, consumer.deliver(delivery(t, ack, 3, false, comment)))
+	assert.NoError(t, consumer.deliver(delivery(t, ack, 4, true, comment)))
+	assert.Equal(t, []bool{true, false}, ack.rejects)
+	assert.Equal(t, []uint64{1, 2}, ack.acks)
+
+	// a broken body isn't acked
+	assert.NoError(t, consumer.deliver(amqp.Delivery{Acknowledger: ack, DeliveryTag: 5, Body: []byte("{")}))
+	assert.Equal(t, []bool{true, false, true}, ack.rejects)
+}
+
+func TestAMQPNoBroker(t *testing.T) {
+	// nothing listens on the port of a closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+
+	publisher := NewAMQP("amqp://guest:guest@"+addr+"/", "reddit", "", zapLogger.Sugar())
+	post, _ := New(PostCreated, nil)
+	assert.Error(t, publisher.Publish(post))
+	assert.NoError(t, publisher.Close())
+
+	// the consumer keeps retrying until it is closed
+	consumer := NewAMQP("amqp://guest:guest@"+addr+"/", "reddit", "search", zapLogger.Sugar())
+	consumer.Retry = 10 * time.Millisecond
+	consumer.Subscribe("post.*", func(event *Event) error { return nil })
+	consumer.Start()
+	time.Sleep(50 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		consumer.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close hangs")
+	}
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var ErrQueueFull = errors.New("Event queue is full")
+
+type subscription struct {
+	pattern string
+	handler Handler
+}
+
+// Bus delivers events to the subscribers of the same process. A synchronous
+// bus runs the handlers inside Publish and returns the last handler error,
+// so the outbox marks an event sent only after it was handled, and retries
+// it otherwise. An asynchronous bus runs them in a background worker, in the
+// order of publishing, and only logs the handler errors: its queue is lost on
+// a restart.
+type Bus struct {
+	Logger *zap.SugaredLogger
+	mu     sync.RWMutex
+	subs   []subscription
+	queue  chan *Event
+	wg     sync.WaitGroup
+}
+
+func NewBus(logger *zap.SugaredLogger) *Bus {
+	return &Bus{Logger: logger}
+}
+
+// NewAsyncBus queues up to size events, Publish fails when the queue is full.
+func NewAsyncBus(logger *zap.SugaredLogger, size int) *Bus {
+	b := &Bus{
+		Logger: logger,
+		queue:  make(chan *Event, size),
+	}
+	b.wg.Add(1)
+	go b.work()
+	return b
+}
+
+func (b *Bus) Subscribe(pattern string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, subscription{pattern: pattern, handler: handler})
+}
+
+func (b *Bus) Publish(event *Event) error {
+	if b.queue == nil {
+		return b.dispatch(event)
+	}
+	select {
+	case b.queue <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close waits for the queued events of an asynchronous bus.
+func (b *Bus) Close() {
+	if b.queue == nil {
+		return
+	}
+	close(b.queue)
+	b.wg.Wait()
+}
+
+func (b *Bus) work() {
+	defer b.wg.Done()
+	for event := range b.queue {
+		b.dispatch(event)
+	}
+}
+
+// dispatch runs all the matching handlers and returns the last error.
+func (b *Bus) dispatch(event *Event) error {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	var failed error
+	for _, sub := range subs {
+		if !Match(sub.pattern, event.Type) {
+			continue
+		}
+		err := sub.handler(event)
+		if err != nil {
+			b.Logger.Errorf("Event %v %v handler error: %v", event.Type, event.ID, err)
+			failed = err
+		}
+	}
+	return failed
+}
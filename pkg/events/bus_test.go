@@ -0,0 +1,65 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestBus(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	bus := NewBus(zapLogger.Sugar())
+
+	var got []string
+	bus.Subscribe("post.*", func(event *Event) error {
+		got = append(got, "post "+event.Type)
+		return errors.New("index is down")
+	})
+	bus.Subscribe("#", func(event *Event) error {
+		got = append(got, "all "+event.Type)
+		return nil
+	})
+
+	// a failing handler doesn't stop the others, the publisher gets the error
+	post, _ := New(PostCreated, nil)
+	assert.EqualError(t, bus.Publish(post), "index is down")
+	vote, _ := New(VoteCast, nil)
+	assert.NoError(t, bus.Publish(vote))
+	assert.Equal(t, []string{"post post.created", "all post.created", "all vote.cast"}, got)
+	bus.Close()
+}
+
+func TestAsyncBus(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	bus := NewAsyncBus(zapLogger.Sugar(), 2)
+
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var got []string
+	bus.Subscribe("#", func(event *Event) error {
+		started <- struct{}{}
+		<-release
+		got = append(got, event.Type)
+		return nil
+	})
+
+	post, _ := New(PostCreated, nil)
+	comment, _ := New(CommentAdded, nil)
+	vote, _ := New(VoteCast, nil)
+	assert.NoError(t, bus.Publish(post))
+	<-started
+	assert.NoError(t, bus.Publish(comment))
+	assert.NoError(t, bus.Publish(vote))
+
+	// the worker holds the first event, the queue is full
+	user, _ := New(UserRegistered, nil)
+	assert.Equal(t, ErrQueueFull, bus.Publish(user))
+
+	close(release)
+	bus.Close()
+	assert.Equal(t, []string{PostCreated, CommentAdded, VoteCast}, got)
+}
@@ -0,0 +1,225 @@
+package events
+
+import (
+	"encoding/json"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// The types of the domain events, they are the routing keys on AMQP too.
+const (
+	PostCreated    = "post.created"
+	PostDeleted    = "post.deleted"
+	CommentAdded   = "comment.added"
+	CommentDeleted = "comment.deleted"
+	VoteCast       = "vote.cast"
+	UserRegistered = "user.registered"
+)
+
+// Event is a fact which already happened. Data is kept as JSON, so an event
+// looks the same in process and on the wire.
+type Event struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Time string          `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Publisher delivers events to the subscribers.
+type Publisher interface {
+	Publish(*Event) error
+}
+
+// Handler reacts to an event. A handler may get an event twice, e.g. after
+// a redelivery, so it must tolerate duplicates.
+type Handler func(*Event) error
+
+// Subscriber calls the handler for the events whose type matches the
+// pattern. Patterns are AMQP topic patterns: "*" is one word, "#" is any
+// number of them, e.g. "post.*" or "#".
+type Subscriber interface {
+	Subscribe(string, Handler)
+}
+
+// Broker both publishes and subscribes.
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
+func New(eventType string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:   bson.NewObjectId().Hex(),
+		Type: eventType,
+		Time: time.Now().Format(time.RFC3339),
+		Data: raw,
+	}, nil
+}
+
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Staged is the event to stage in the post of its change.
+func (e *Event) Staged() posts.StagedEvent {
+	return posts.StagedEvent{
+		ID:   e.ID,
+		Type: e.Type,
+		Time: e.Time,
+		Data: string(e.Data),
+	}
+}
+
+func stagedEvent(staged posts.StagedEvent) *Event {
+	return &Event{
+		ID:   staged.ID,
+		Type: staged.Type,
+		Time: staged.Time,
+		Data: json.RawMessage(staged.Data),
+	}
+}
+
+// Match tells if the event type matches the topic pattern.
+func Match(pattern, eventType string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(eventType, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) != 0 && matchWords(pattern[1:], words[1:])
+	}
+	return len(words) != 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+}
+
+// PostData is the data of post.created.
+type PostData struct {
+	ID       bson.ObjectId `json:"id"`
+	Category string        `json:"category"`
+	Author   *user.User    `json:"author"`
+	Title    string        `json:"title"`
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	Link     string        `json:"url,omitempty"`
+}
+
+func NewPostData(post *posts.Post) *PostData {
+	return &PostData{
+		ID:       post.ID,
+		Category: post.Category,
+		Author:   post.Author,
+		Title:    post.Title,
+		Type:     post.Type,
+		Text:     post.Text,
+		Link:     post.Link,
+	}
+}
+
+// Post makes a post of the data, with the fields the data has.
+func (d *PostData) Post() *posts.Post {
+	return &posts.Post{
+		ID:       d.ID,
+		Category: d.Category,
+		Author:   d.Author,
+		Title:    d.Title,
+		Type:     d.Type,
+		Text:     d.Text,
+		Link:     d.Link,
+	}
+}
+
+// CommentData is the data of comment.added.
+type CommentData struct {
+	Post    *PostData      `json:"post"`
+	Comment *posts.Comment `json:"comment"`
+}
+
+// DeletedData is the data of post.deleted and comment.deleted.
+type DeletedData struct {
+	PostID    bson.ObjectId `json:"post"`
+	Category  string        `json:"category,omitempty"`
+	CommentID bson.ObjectId `json:"comment,omitempty"`
+}
+
+// VoteData is the data of vote.cast, Author is the author of the post.
+type VoteData struct {
+	PostID bson.ObjectId `json:"post"`
+	Author *user.User    `json:"author"`
+	Voter  *user.User    `json:"user"`
+	Vote   int           `json:"vote"`
+	Score  int           `json:"score"`
+}
+
+// UserData is the data of user.registered.
+type UserData struct {
+	User *user.User `json:"user"`
+}
+
+type PostFinder interface {
+	GetByID(bson.ObjectId) (*posts.Post, error)
+}
+
+// ConfirmPostDeleted sends a held post.deleted if the post is gone.
+func ConfirmPostDeleted(finder PostFinder) Confirm {
+	return func(event *Event) (*Event, error) {
+		data := &DeletedData{}
+		err := event.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = finder.GetByID(data.PostID)
+		if err == mgo.ErrNotFound {
+			return event, nil
+		}
+		return nil, err
+	}
+}
+
+type UserFinder interface {
+	GetByUsername(string) (*user.User, error)
+}
+
+// ConfirmUserRegistered sends a held user.registered if the user exists, the
+// event is held before the user has an id, so the id is filled in.
+func ConfirmUserRegistered(finder UserFinder) Confirm {
+	return func(event *Event) (*Event, error) {
+		data := &UserData{}
+		err := event.Decode(data)
+		if err != nil || data.User == nil {
+			return nil, err
+		}
+		found, err := finder.GetByUsername(data.User.Username)
+		if err == user.ErrNoUser {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(&UserData{User: &user.User{ID: found.ID, Username: found.Username}})
+		if err != nil {
+			return nil, err
+		}
+		confirmed := *event
+		confirmed.Data = raw
+		return &confirmed, nil
+	}
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"errors"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("post.created", PostCreated))
+	assert.True(t, Match("post.*", PostDeleted))
+	assert.True(t, Match("*.deleted", CommentDeleted))
+	assert.True(t, Match("#", VoteCast))
+	assert.True(t, Match("post.#", "post.created.late"))
+	assert.True(t, Match("post.#", "post"))
+	assert.False(t, Match("post.*", "post"))
+	assert.False(t, Match("post.*", CommentAdded))
+	assert.False(t, Match("post", PostCreated))
+}
+
+func TestEventData(t *testing.T) {
+	post := &posts.Post{
+		ID:       bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244"),
+		Category: "music",
+		Author:   &user.User{ID: 1, Username: "rvasily"},
+		Title:    "News",
+		Type:     "text",
+		Text:     "Hello",
+		Score:    5,
+	}
+	event, err := New(PostCreated, NewPostData(post))
+	assert.NoError(t, err)
+	assert.Len(t, event.ID, 24)
+	assert.Equal(t, PostCreated, event.Type)
+	assert.Equal(t, `{"id":"5ebaf9ee3c04c17c56f51244","category":"music","author":{"username":"rvasily","id":"1"},"title":"News","type":"text","text":"Hello"}`, string(event.Data))
+
+	data := &PostData{}
+	assert.NoError(t, event.Decode(data))
+	post.Score = 0
+	assert.Equal(t, post, data.Post())
+
+	assert.Equal(t, event, stagedEvent(event.Staged()))
+}
+
+type testFinder struct {
+	posts map[bson.ObjectId]*posts.Post
+	users map[string]*user.User
+	err   error
+}
+
+func (f *testFinder) GetByID(id bson.ObjectId) (*posts.Post, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if post, ok := f.posts[id]; ok {
+		return post, nil
+	}
+	return nil, mgo.ErrNotFound
+}
+
+func (f *testFinder) GetByUsername(username string) (*user.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if u, ok := f.users[username]; ok {
+		return u, nil
+	}
+	return nil, user.ErrNoUser
+}
+
+func TestConfirm(t *testing.T) {
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	finder := &testFinder{
+		posts: map[bson.ObjectId]*posts.Post{postID: {ID: postID}},
+		users: map[string]*user.User{"anna": {ID: 3, Username: "anna"}},
+	}
+
+	// the post is still there
+	deleted, _ := New(PostDeleted, &DeletedData{PostID: postID})
+	confirmed, err := ConfirmPostDeleted(finder)(deleted)
+	assert.NoError(t, err)
+	assert.Nil(t, confirmed)
+
+	delete(finder.posts, postID)
+	confirmed, err = ConfirmPostDeleted(finder)(deleted)
+	assert.NoError(t, err)
+	assert.Equal(t, deleted, confirmed)
+
+	// the user is found with the id
+	registered, _ := New(UserRegistered, &UserData{User: &user.User{Username: "anna"}})
+	confirmed, err = ConfirmUserRegistered(finder)(registered)
+	assert.NoError(t, err)
+	assert.Equal(t, registered.ID, confirmed.ID)
+	assert.Equal(t, `{"user":{"username":"anna","id":"3"}}`, string(confirmed.Data))
+
+	missing, _ := New(UserRegistered, &UserData{User: &user.User{Username: "bob"}})
+	confirmed, err = ConfirmUserRegistered(finder)(missing)
+	assert.NoError(t, err)
+	assert.Nil(t, confirmed)
+
+	// a DB error is tried again later
+	finder.err = errors.New("db is down")
+	_, err = ConfirmPostDeleted(finder)(deleted)
+	assert.Error(t, err)
+	_, err = ConfirmUserRegistered(finder)(registered)
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+// KeyStore remembers the events a consumer already handled.
+type KeyStore interface {
+	Handled(string) (bool, error)
+	Mark(string) error
+}
+
+// Idempotent skips the redelivered copies of the events the consumer already
+// handled. The key is marked only after the handler succeeded, so an event
+// whose handler failed or never finished, e.g. because of a crash, is
+// handled again. Two copies delivered at the same time may both run, which
+// the at least once delivery allows anyway.
+func Idempotent(consumer string, keys KeyStore, handler Handler) Handler {
+	return func(event *Event) error {
+		key := consumer + ":" + event.ID
+		handled, err := keys.Handled(key)
+		if err != nil || handled {
+			return err
+		}
+		err = handler(event)
+		if err != nil {
+			return err
+		}
+		return keys.Mark(key)
+	}
+}
+
+// MongoKeys keeps the keys of the handled events for KeyTTL.
+type MongoKeys struct {
+	DB *mgo.Collection
+}
+
+type handledKey struct {
+	Key     string    `bson:"_id"`
+	Created time.Time `bson:"created"`
+}
+
+// KeyTTL is longer than the outbox keeps retrying an event.
+const KeyTTL = 7 * 24 * time.Hour
+
+func NewMongoKeys(collection *mgo.Collection) *MongoKeys {
+	return &MongoKeys{DB: collection}
+}
+
+func (repo *MongoKeys) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:         []string{"created"},
+		ExpireAfter: KeyTTL,
+	})
+}
+
+func (repo *MongoKeys) Handled(key string) (bool, error) {
+	n, err := repo.DB.FindId(key).Count()
+	return n != 0, err
+}
+
+func (repo *MongoKeys) Mark(key string) error {
+	err := repo.DB.Insert(&handledKey{Key: key, Created: time.Now()})
+	if mgo.IsDup(err) {
+		// a concurrent copy of the event was handled too
+		return nil
+	}
+	return err
+}
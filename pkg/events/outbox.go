@@ -0,0 +1,443 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reddit/pkg/posts"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	OutboxHeld    = "held" // saved before its change, waits for Release
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed" // gave up after MaxAttempts
+)
+
+// OutboxEntry is an event waiting for delivery. Its id is the id of the
+// event, the idempotency key of the consumers.
+type OutboxEntry struct {
+	ID       string    `bson:"_id"`
+	Type     string    `bson:"type"`
+	Time     string    `bson:"time"`
+	Data     string    `bson:"data"`
+	Status   string    `bson:"status"`
+	Attempts int       `bson:"attempts"`
+	Next     time.Time `bson:"next"` // the next delivery attempt
+	Error    string    `bson:"error,omitempty"`
+	Sent     time.Time `bson:"sent,omitempty"`
+}
+
+func newOutboxEntry(event *Event, status string, next time.Time) *OutboxEntry {
+	return &OutboxEntry{
+		ID:     event.ID,
+		Type:   event.Type,
+		Time:   event.Time,
+		Data:   string(event.Data),
+		Status: status,
+		Next:   next,
+	}
+}
+
+func (e *OutboxEntry) Event() *Event {
+	return &Event{
+		ID:   e.ID,
+		Type: e.Type,
+		Time: e.Time,
+		Data: json.RawMessage(e.Data),
+	}
+}
+
+type OutboxStore interface {
+	// Add saves a new entry, an entry which is already there is kept as is.
+	Add(*OutboxEntry) error
+	// Pending returns the held and the pending entries due by now, the
+	// oldest first, and postpones them by lease, so the other relays skip
+	// them meanwhile.
+	Pending(now time.Time, lease time.Duration, limit int) ([]*OutboxEntry, error)
+	Update(*OutboxEntry) error
+	Remove(id string) error
+}
+
+// StagedStore keeps the events staged in the documents of their changes,
+// e.g. the events of the posts in the posts.
+type StagedStore interface {
+	Staged(limit int) ([]*Event, error)
+	Unstage([]*Event) error
+}
+
+// Confirm tells if the change of a held event happened: it returns the event
+// to send, or nil if there was no change.
+type Confirm func(*Event) (*Event, error)
+
+// Outbox is the publisher of the request handlers, it delivers the events of
+// the saved changes to the broker with retries, so a broker outage or a
+// restart doesn't lose them.
+//
+// An event gets into the outbox in the same write as its change: the repos
+// stage the events in the documents they change, e.g. in the posts, and the
+// relay worker moves them from the Staged stores into the outbox before the
+// delivery. A change which can't carry its event, e.g. a removal, holds the
+// event in the outbox before the change and releases it after. If the
+// process dies in between, the relay sends the held event only if Confirm of
+// its type finds the change happened, an event without Confirm is sent.
+//
+// An event is marked sent after the broker took it, so a crash in between
+// sends it again: the delivery is at least once and the consumers
+// deduplicate by the event id.
+type Outbox struct {
+	Store       OutboxStore
+	Staged      []StagedStore
+	Confirm     map[string]Confirm
+	Publisher   Publisher
+	Logger      *zap.SugaredLogger
+	Batch       int
+	HoldTime    time.Duration // how long a held event waits for its change
+	Lease       time.Duration
+	Poll        time.Duration
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	now         func() time.Time
+	wake        chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewOutbox(store OutboxStore, publisher Publisher, logger *zap.SugaredLogger, staged ...StagedStore) *Outbox {
+	o := &Outbox{
+		Store:       store,
+		Staged:      staged,
+		Confirm:     map[string]Confirm{},
+		Publisher:   publisher,
+		Logger:      logger,
+		Batch:       100,
+		HoldTime:    time.Minute,
+		Lease:       time.Minute,
+		Poll:        5 * time.Second,
+		MaxAttempts: 20,
+		MinBackoff:  time.Second,
+		MaxBackoff:  10 * time.Minute,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	o.wg.Add(1)
+	go o.relay()
+	return o
+}
+
+// Publish saves an event which is the change itself, e.g. of an external
+// system, the events of the saved changes are staged or held instead.
+func (o *Outbox) Publish(event *Event) error {
+	err := o.Store.Add(newOutboxEntry(event, OutboxPending, o.now()))
+	if err != nil {
+		return err
+	}
+	o.Wake()
+	return nil
+}
+
+// Hold saves the event before its change, it isn't sent until Release or
+// until the HoldTime is over and the change is confirmed.
+func (o *Outbox) Hold(event *Event) error {
+	return o.Store.Add(newOutboxEntry(event, OutboxHeld, o.now().Add(o.HoldTime)))
+}
+
+// Release sends the held event after its change was saved, the data may be
+// completed by the change, e.g. with a new id.
+func (o *Outbox) Release(event *Event) error {
+	err := o.Store.Update(newOutboxEntry(event, OutboxPending, o.now()))
+	if err != nil {
+		return err
+	}
+	o.Wake()
+	return nil
+}
+
+// Drop forgets the held event of a change which didn't happen.
+func (o *Outbox) Drop(event *Event) error {
+	return o.Store.Remove(event.ID)
+}
+
+// Wake makes the relay look for the events right away, e.g. after a change
+// staged one.
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the relay, the undelivered events wait for the next start.
+func (o *Outbox) Close() {
+	close(o.done)
+	o.wg.Wait()
+}
+
+func (o *Outbox) relay() {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.Poll)
+	defer ticker.Stop()
+	for {
+		o.Flush()
+		select {
+		case <-o.done:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush moves the staged events into the outbox, delivers the due events
+// and returns how many were delivered.
+func (o *Outbox) Flush() int {
+	for _, staged := range o.Staged {
+		err := o.collect(staged)
+		if err != nil {
+			o.Logger.Error(err)
+		}
+	}
+	delivered := 0
+	for {
+		entries, err := o.Store.Pending(o.now(), o.Lease, o.Batch)
+		if err != nil {
+			o.Logger.Errorf("Can't read outbox: %v", err)
+			return delivered
+		}
+		for _, entry := range entries {
+			if o.deliver(entry) {
+				delivered++
+			}
+		}
+		if len(entries) < o.Batch {
+			return delivered
+		}
+	}
+}
+
+// Collect moves the staged events into the outbox right away, e.g. before
+// the document which carries them is removed. The relay delivers them later.
+func (o *Outbox) Collect() error {
+	for _, staged := range o.Staged {
+		err := o.collect(staged)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect moves the staged events into the outbox. An event is unstaged after
+// it was added, so a crash in between adds it again, which changes nothing.
+func (o *Outbox) collect(staged StagedStore) error {
+	for {
+		events, err := staged.Staged(o.Batch)
+		if err != nil {
+			return fmt.Errorf("Can't read staged events: %v", err)
+		}
+		for _, event := range events {
+			err = o.Store.Add(newOutboxEntry(event, OutboxPending, o.now()))
+			if err != nil {
+				return fmt.Errorf("Can't add staged event %v %v: %v", event.Type, event.ID, err)
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		err = staged.Unstage(events)
+		if err != nil {
+			return fmt.Errorf("Can't unstage events: %v", err)
+		}
+	}
+}
+
+// confirm turns a held entry whose change wasn't released into a pending one,
+// it returns false if the entry is removed or has to wait.
+func (o *Outbox) confirm(entry *OutboxEntry) bool {
+	confirm := o.Confirm[entry.Type]
+	if confirm == nil {
+		entry.Status = OutboxPending
+		return true
+	}
+	event, err := confirm(entry.Event())
+	if err != nil {
+		// the lease runs out and the confirmation is tried again
+		o.Logger.Errorf("Can't confirm event %v %v: %v", entry.Type, entry.ID, err)
+		return false
+	}
+	if event == nil {
+		o.Logger.Infof("Event %v %v has no change, dropped", entry.Type, entry.ID)
+		err = o.Store.Remove(entry.ID)
+		if err != nil {
+			o.Logger.Errorf("Can't remove outbox entry %v: %v", entry.ID, err)
+		}
+		return false
+	}
+	entry.Data = string(event.Data)
+	entry.Status = OutboxPending
+	return true
+}
+
+func (o *Outbox) deliver(entry *OutboxEntry) bool {
+	if entry.Status == OutboxHeld && !o.confirm(entry) {
+		return false
+	}
+	err := o.Publisher.Publish(entry.Event())
+	now := o.now()
+	if err == nil {
+		entry.Status = OutboxSent
+		entry.Sent = now
+		entry.Error = ""
+	} else {
+		entry.Attempts++
+		entry.Error = err.Error()
+		entry.Next = now.Add(o.backoff(entry.Attempts))
+		if entry.Attempts >= o.MaxAttempts {
+			entry.Status = OutboxFailed
+			o.Logger.Errorf("Event %v %v failed %v times, giving up: %v", entry.Type, entry.ID, entry.Attempts, err)
+		}
+	}
+	// the lease runs out if the update fails, and the event is sent again
+	updateErr := o.Store.Update(entry)
+	if updateErr != nil {
+		o.Logger.Errorf("Can't update outbox entry %v: %v", entry.ID, updateErr)
+	}
+	return err == nil
+}
+
+// backoff doubles the wait after every failed attempt.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	wait := o.MaxBackoff
+	if attempts < 32 {
+		wait = o.MinBackoff << uint(attempts-1)
+	}
+	if wait <= 0 || wait > o.MaxBackoff {
+		wait = o.MaxBackoff
+	}
+	return wait
+}
+
+// MongoOutbox keeps the outbox in the database of the posts.
+type MongoOutbox struct {
+	DB *mgo.Collection
+}
+
+func NewMongoOutbox(collection *mgo.Collection) *MongoOutbox {
+	return &MongoOutbox{DB: collection}
+}
+
+// SentTTL is how long the sent events are kept.
+const SentTTL = 7 * 24 * time.Hour
+
+func (repo *MongoOutbox) EnsureIndexes() error {
+	err := repo.DB.EnsureIndexKey("status", "next")
+	if err != nil {
+		return err
+	}
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:         []string{"sent"},
+		ExpireAfter: SentTTL,
+	})
+}
+
+func (repo *MongoOutbox) Add(entry *OutboxEntry) error {
+	err := repo.DB.Insert(entry)
+	if mgo.IsDup(err) {
+		return nil
+	}
+	return err
+}
+
+func (repo *MongoOutbox) Pending(now time.Time, lease time.Duration, limit int) ([]*OutboxEntry, error) {
+	due := []*OutboxEntry{}
+	err := repo.DB.Find(bson.M{
+		"status": bson.M{"$in": []string{OutboxHeld, OutboxPending}},
+		"next":   bson.M{"$lte": now},
+	}).Sort("_id").Limit(limit).All(&due)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*OutboxEntry, 0, len(due))
+	for _, entry := range due {
+		next := now.Add(lease)
+		err = repo.DB.Update(
+			bson.M{"_id": entry.ID, "status": entry.Status, "next": entry.Next},
+			bson.M{"$set": bson.M{"next": next}},
+		)
+		if err == mgo.ErrNotFound {
+			// another relay leased the entry first
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry.Next = next
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (repo *MongoOutbox) Update(entry *OutboxEntry) error {
+	return repo.DB.UpdateId(entry.ID, entry)
+}
+
+func (repo *MongoOutbox) Remove(id string) error {
+	err := repo.DB.RemoveId(id)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// MongoStaged reads the events staged in the outbox field of the documents
+// of a collection, e.g. of the posts.
+type MongoStaged struct {
+	DB *mgo.Collection
+}
+
+func NewMongoStaged(collection *mgo.Collection) *MongoStaged {
+	return &MongoStaged{DB: collection}
+}
+
+func (repo *MongoStaged) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"outbox.id"},
+		Sparse: true,
+	})
+}
+
+func (repo *MongoStaged) Staged(limit int) ([]*Event, error) {
+	docs := []struct {
+		Outbox []posts.StagedEvent `bson:"outbox"`
+	}{}
+	err := repo.DB.Find(bson.M{"outbox.id": bson.M{"$exists": true}}).
+		Select(bson.M{"outbox": 1}).Limit(limit).All(&docs)
+	if err != nil {
+		return nil, err
+	}
+	var events []*Event
+	for _, doc := range docs {
+		for _, staged := range doc.Outbox {
+			events = append(events, stagedEvent(staged))
+		}
+	}
+	return events, nil
+}
+
+func (repo *MongoStaged) Unstage(events []*Event) error {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	_, err := repo.DB.UpdateAll(
+		bson.M{"outbox.id": bson.M{"$in": ids}},
+		bson.M{"$pull": bson.M{"outbox": bson.M{"id": bson.M{"$in": ids}}}})
+	return err
+}
@@ -0,0 +1,426 @@
+package events
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type memoryOutbox struct {
+	mu      sync.Mutex
+	entries map[string]OutboxEntry
+}
+
+func (s *memoryOutbox) Add(entry *OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[entry.ID]; !ok {
+		s.entries[entry.ID] = *entry
+	}
+	return nil
+}
+
+func (s *memoryOutbox) Pending(now time.Time, lease time.Duration, limit int) ([]*OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []*OutboxEntry
+	for _, entry := range s.entries {
+		if (entry.Status == OutboxPending || entry.Status == OutboxHeld) && !entry.Next.After(now) {
+			due := entry
+			entries = append(entries, &due)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for _, entry := range entries {
+		entry.Next = now.Add(lease)
+		s.entries[entry.ID] = *entry
+	}
+	return entries, nil
+}
+
+func (s *memoryOutbox) Update(entry *OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.ID] = *entry
+	return nil
+}
+
+func (s *memoryOutbox) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *memoryOutbox) get(id string) OutboxEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[id]
+}
+
+// flakyPublisher fails while down and keeps the delivered events.
+type flakyPublisher struct {
+	mu   sync.Mutex
+	down bool
+	got  []*Event
+}
+
+func (p *flakyPublisher) Publish(event *Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return errors.New("broker is down")
+	}
+	p.got = append(p.got, event)
+	return nil
+}
+
+func (p *flakyPublisher) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+func (p *flakyPublisher) delivered() []*Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Event(nil), p.got...)
+}
+
+func TestOutbox(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	publisher := &flakyPublisher{down: true}
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	outbox := &Outbox{
+		Store:       store,
+		Publisher:   publisher,
+		Logger:      zapLogger.Sugar(),
+		Batch:       1,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		now:         func() time.Time { return now },
+		wake:        make(chan struct{}, 1),
+	}
+
+	post, _ := New(PostCreated, map[string]string{"title": "News"})
+	vote, _ := New(VoteCast, map[string]int{"vote": 1})
+	assert.NoError(t, outbox.Publish(post))
+	assert.NoError(t, outbox.Publish(vote))
+	assert.Len(t, outbox.wake, 1)
+
+	// the broker is down, both events wait for a retry
+	assert.Equal(t, 0, outbox.Flush())
+	entry := store.get(post.ID)
+	assert.Equal(t, OutboxPending, entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.Equal(t, "broker is down", entry.Error)
+	assert.Equal(t, now.Add(time.Second), entry.Next)
+
+	// not due yet
+	publisher.setDown(false)
+	assert.Equal(t, 0, outbox.Flush())
+
+	now = now.Add(time.Second)
+	assert.Equal(t, 2, outbox.Flush())
+	assert.Equal(t, []*Event{post, vote}, publisher.delivered())
+	entry = store.get(vote.ID)
+	assert.Equal(t, OutboxSent, entry.Status)
+	assert.Equal(t, now, entry.Sent)
+	assert.Equal(t, "", entry.Error)
+	assert.Equal(t, 0, outbox.Flush())
+
+	// the retries back off and give up
+	publisher.setDown(true)
+	user, _ := New(UserRegistered, nil)
+	assert.NoError(t, outbox.Publish(user))
+	for i := 0; i < 3; i++ {
+		outbox.Flush()
+		now = now.Add(time.Hour)
+	}
+	entry = store.get(user.ID)
+	assert.Equal(t, OutboxFailed, entry.Status)
+	assert.Equal(t, 3, entry.Attempts)
+}
+
+// memoryStaged is a post with staged events, Unstage fails while broken.
+type memoryStaged struct {
+	events []*Event
+	broken bool
+}
+
+func (s *memoryStaged) Staged(limit int) ([]*Event, error) {
+	if len(s.events) > limit {
+		return s.events[:limit], nil
+	}
+	return s.events, nil
+}
+
+func (s *memoryStaged) Unstage(events []*Event) error {
+	if s.broken {
+		return errors.New("db is down")
+	}
+	s.events = s.events[len(events):]
+	return nil
+}
+
+func TestOutboxStaged(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	publisher := &flakyPublisher{}
+	post, _ := New(PostCreated, map[string]string{"title": "News"})
+	vote, _ := New(VoteCast, map[string]int{"vote": 1})
+	staged := &memoryStaged{events: []*Event{post, vote}, broken: true}
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	outbox := &Outbox{
+		Store:       store,
+		Staged:      []StagedStore{staged},
+		Publisher:   publisher,
+		Logger:      zapLogger.Sugar(),
+		Batch:       1,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		now:         func() time.Time { return now },
+		wake:        make(chan struct{}, 1),
+	}
+
+	// the event got into the outbox, but is still staged
+	assert.Equal(t, 1, outbox.Flush())
+	assert.Equal(t, []*Event{post}, publisher.delivered())
+	assert.Len(t, staged.events, 2)
+
+	// collected again, the sent event isn't sent again
+	staged.broken = false
+	assert.Equal(t, 1, outbox.Flush())
+	assert.Equal(t, []*Event{post, vote}, publisher.delivered())
+	assert.Len(t, staged.events, 0)
+	assert.Equal(t, OutboxSent, store.get(post.ID).Status)
+	assert.Equal(t, OutboxSent, store.get(vote.ID).Status)
+}
+
+func TestOutboxCollect(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	publisher := &flakyPublisher{}
+	vote, _ := New(VoteCast, map[string]int{"vote": 1})
+	staged := &memoryStaged{events: []*Event{vote}, broken: true}
+	outbox := &Outbox{
+		Store:       store,
+		Staged:      []StagedStore{staged},
+		Publisher:   publisher,
+		Logger:      zapLogger.Sugar(),
+		Batch:       10,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		now:         func() time.Time { return time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC) },
+		wake:        make(chan struct{}, 1),
+	}
+
+	// the post can't go while its events are still staged
+	assert.EqualError(t, outbox.Collect(), "Can't unstage events: db is down")
+	assert.Len(t, staged.events, 1)
+
+	staged.broken = false
+	assert.NoError(t, outbox.Collect())
+	assert.Len(t, staged.events, 0)
+	assert.Empty(t, publisher.delivered())
+
+	// the post is removed, its event is delivered anyway
+	outbox.Staged = nil
+	assert.Equal(t, 1, outbox.Flush())
+	assert.Equal(t, []*Event{vote}, publisher.delivered())
+}
+
+func TestOutboxHold(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	publisher := &flakyPublisher{}
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	gone := map[string]bool{}
+	outbox := &Outbox{
+		Store: store,
+		Confirm: map[string]Confirm{
+			PostDeleted: func(event *Event) (*Event, error) {
+				if gone[event.ID] {
+					return event, nil
+				}
+				return nil, nil
+			},
+		},
+		Publisher:   publisher,
+		Logger:      zapLogger.Sugar(),
+		Batch:       10,
+		HoldTime:    time.Minute,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		now:         func() time.Time { return now },
+		wake:        make(chan struct{}, 1),
+	}
+
+	// a held event waits for its change
+	deleted, _ := New(PostDeleted, map[string]string{"post": "1"})
+	assert.NoError(t, outbox.Hold(deleted))
+	assert.Equal(t, 0, outbox.Flush())
+	assert.Equal(t, OutboxHeld, store.get(deleted.ID).Status)
+
+	// and is sent after it
+	deleted.Data = []byte(`{"post":"1","category":"music"}`)
+	assert.NoError(t, outbox.Release(deleted))
+	assert.Len(t, outbox.wake, 1)
+	assert.Equal(t, 1, outbox.Flush())
+	assert.Equal(t, []*Event{deleted}, publisher.delivered())
+
+	// a dropped event is never sent
+	dropped, _ := New(PostDeleted, nil)
+	assert.NoError(t, outbox.Hold(dropped))
+	assert.NoError(t, outbox.Drop(dropped))
+	now = now.Add(time.Minute)
+	assert.Equal(t, 0, outbox.Flush())
+
+	// the process died before the release: the event is sent only if
+	// the change is confirmed
+	happened, _ := New(PostDeleted, nil)
+	missed, _ := New(PostDeleted, nil)
+	user, _ := New(UserRegistered, nil)
+	gone[happened.ID] = true
+	assert.NoError(t, outbox.Hold(happened))
+	assert.NoError(t, outbox.Hold(missed))
+	assert.NoError(t, outbox.Hold(user))
+	assert.Equal(t, 0, outbox.Flush())
+	now = now.Add(time.Minute)
+	assert.Equal(t, 2, outbox.Flush())
+	assert.Equal(t, []*Event{deleted, happened, user}, publisher.delivered())
+	_, ok := store.entries[missed.ID]
+	assert.False(t, ok)
+}
+
+func TestOutboxRelay(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	publisher := &flakyPublisher{}
+	outbox := NewOutbox(store, publisher, zapLogger.Sugar())
+
+	comment, _ := New(CommentAdded, nil)
+	assert.NoError(t, outbox.Publish(comment))
+	for i := 0; i < 100 && len(publisher.delivered()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	outbox.Close()
+	assert.Equal(t, []*Event{comment}, publisher.delivered())
+	assert.Equal(t, OutboxSent, store.get(comment.ID).Status)
+}
+
+func TestOutboxBus(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	store := &memoryOutbox{entries: map[string]OutboxEntry{}}
+	bus := NewBus(zapLogger.Sugar())
+	fail := true
+	handled := 0
+	bus.Subscribe("#", func(event *Event) error {
+		if fail {
+			return errors.New("db is down")
+		}
+		handled++
+		return nil
+	})
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	outbox := &Outbox{
+		Store:       store,
+		Publisher:   bus,
+		Logger:      zapLogger.Sugar(),
+		Batch:       10,
+		Lease:       time.Minute,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Hour,
+		now:         func() time.Time { return now },
+		wake:        make(chan struct{}, 1),
+	}
+
+	// the event is sent only after the handlers of the process succeeded
+	comment, _ := New(CommentAdded, nil)
+	assert.NoError(t, outbox.Publish(comment))
+	assert.Equal(t, 0, outbox.Flush())
+	assert.Equal(t, OutboxPending, store.get(comment.ID).Status)
+
+	fail = false
+	now = now.Add(time.Second)
+	assert.Equal(t, 1, outbox.Flush())
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, OutboxSent, store.get(comment.ID).Status)
+}
+
+func TestBackoff(t *testing.T) {
+	outbox := &Outbox{MinBackoff: time.Second, MaxBackoff: time.Minute}
+	assert.Equal(t, time.Second, outbox.backoff(1))
+	assert.Equal(t, 8*time.Second, outbox.backoff(4))
+	assert.Equal(t, time.Minute, outbox.backoff(7))
+	assert.Equal(t, time.Minute, outbox.backoff(100))
+}
+
+type memoryKeys map[string]bool
+
+func (k memoryKeys) Handled(key string) (bool, error) {
+	return k[key], nil
+}
+
+func (k memoryKeys) Mark(key string) error {
+	k[key] = true
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	keys := memoryKeys{}
+	calls := 0
+	fail := true
+	handler := Idempotent("notifications", keys, func(event *Event) error {
+		calls++
+		if fail {
+			return errors.New("db is down")
+		}
+		return nil
+	})
+	event, _ := New(CommentAdded, nil)
+
+	// a failed event is handled again
+	assert.Error(t, handler(event))
+	assert.False(t, keys["notifications:"+event.ID])
+	fail = false
+	assert.NoError(t, handler(event))
+	assert.Equal(t, 2, calls)
+
+	// a duplicate is skipped
+	assert.NoError(t, handler(event))
+	assert.Equal(t, 2, calls)
+	assert.True(t, keys["notifications:"+event.ID])
+
+	// other consumers handle it on their own
+	other := Idempotent("search", keys, func(event *Event) error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, other(event))
+	assert.Equal(t, 3, calls)
+}
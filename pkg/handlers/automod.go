@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"reddit/pkg/automod"
+	"reddit/pkg/moderation"
+	"reddit/pkg/posts"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type AutomodInterface interface {
+	Check(*automod.Submission) (*automod.Decision, error)
+}
+
+// automodCheck runs the rules on a submission before it is saved. It answers
+// with an error and returns false if the submission is removed. Broken rule
+// lookups shouldn't stop users from posting, so they only get logged.
+func (h *PostsHandler) automodCheck(w http.ResponseWriter, s *automod.Submission) (*automod.Decision, bool) {
+	if h.Automod == nil {
+		return nil, true
+	}
+	decision, err := h.Automod.Check(s)
+	if err != nil {
+		h.Logger.Errorf("Automod error: %v", err)
+		return nil, true
+	}
+	if decision == nil {
+		return nil, true
+	}
+	for _, rule := range decision.DryRuns {
+		h.Logger.Infof("Automod dry run: rule %v matched %v of %v", rule, s.Kind, s.Author.Username)
+	}
+	if decision.DryRun {
+		h.Logger.Infof("Automod dry run: rule %v would %v %v of %v", decision.Rule, decision.Action, s.Kind, s.Author.Username)
+		return nil, true
+	}
+	if decision.Action == automod.ActionRemove {
+		writeRejected(w, "Removed by "+automod.Moderator.Username, decision.Reason, "")
+		h.Logger.Infof("Automod rule %v removed %v of %v", decision.Rule, s.Kind, s.Author.Username)
+		return nil, false
+	}
+	return decision, true
+}
+
+func needsApproval(decision *automod.Decision) bool {
+	return decision != nil && decision.Action == automod.ActionRequireApproval
+}
+
+// applyAutomod carries out a flag or require_approval decision on the saved
+// post or comment, post is the item itself or the post of the comment. The
+// item is saved already, so errors are only logged.
+func (h *PostsHandler) applyAutomod(decision *automod.Decision, kind string, itemID bson.ObjectId, post *posts.Post) {
+	if decision == nil {
+		return
+	}
+	if needsApproval(decision) && kind == posts.ItemPost {
+		err := h.PostsRepo.SetPending(post, true, nil)
+		if err != nil {
+			h.Logger.Errorf("Can't hide post %v for approval: %v", post.ID, err)
+		}
+	}
+	h.autoReport(kind, itemID, post, decision.Reason)
+	h.Logger.Infof("Automod rule %v: %v %v", decision.Rule, decision.Action, itemID)
+}
+
+// autoReport puts the saved item into the moderation queue on behalf of the
+// automoderator, errors are only logged.
+func (h *PostsHandler) autoReport(kind string, itemID bson.ObjectId, post *posts.Post, reason string) {
+	if h.ReportsRepo == nil {
+		return
+	}
+	_, err := h.ReportsRepo.Add(&moderation.Report{
+		Kind:     kind,
+		ItemID:   itemID,
+		PostID:   post.ID,
+		Category: post.Category,
+		Reporter: automod.Moderator,
+		Reason:   reason,
+	})
+	if err != nil {
+		h.Logger.Errorf("Can't report %v %v: %v", kind, itemID, err)
+	}
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/automod"
+	"reddit/pkg/moderation"
+	posts "reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestAutomod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockReportsRepo := NewMockReportsRepositoryInterface(ctrl)
+	mockModLogRepo := NewMockModLogRepositoryInterface(ctrl)
+	mockAutomod := NewMockAutomodInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		ReportsRepo:  mockReportsRepo,
+		ModLogRepo:   mockModLogRepo,
+		Automod:      mockAutomod,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	commentID := bson.ObjectIdHex("5ebaf9f33c04c17c56f51299")
+	newPost := func() *posts.Post {
+		return &posts.Post{
+			Author:     testUser,
+			Category:   "music",
+			CommentsID: []bson.ObjectId{},
+			Created:    "2020-05-12T22:33:02+03:00",
+			ID:         postID,
+			Title:      "Free stuff",
+			Type:       "link",
+			Link:       "https://bit.ly/free",
+			Votes:      []posts.Vote{},
+		}
+	}
+	// the repo marks the post as it is stored
+	setPending := func(post *posts.Post, pending bool, announce posts.Announce) {
+		post.Pending = pending
+	}
+	pendingPost := func() *posts.Post {
+		post := newPost()
+		post.Pending = true
+		return post
+	}
+	postSubmission := &automod.Submission{
+		Kind:     posts.ItemPost,
+		Author:   testUser,
+		Category: "music",
+		Title:    "Free stuff",
+		Link:     "https://bit.ly/free",
+	}
+	postRequest := func() *http.Request {
+		reqBody, _ := json.Marshal(NewPostRequest{
+			Category: "music",
+			Title:    "Free stuff",
+			Type:     "link",
+			Link:     "https://bit.ly/free",
+		})
+		return requestWithSession(httptest.NewRequest("POST", "/api/posts", bytes.NewReader(reqBody)))
+	}
+	commentRequest := func() *http.Request {
+		r := httptest.NewRequest("POST", "/api/post/{POST_ID}", bytes.NewReader([]byte(`{"comment": "Buy now"}`)))
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"POST_ID": postID.Hex()})
+	}
+	approveRequest := func(itemID bson.ObjectId, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, testModerator), map[string]string{"CATEGORY": "music", "ITEM_ID": itemID.Hex()})
+	}
+	postPageRequest := func(r *http.Request) *http.Request {
+		return mux.SetURLVars(r, map[string]string{"ID": postID.Hex()})
+	}
+	postResponse := `{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"url":"https://bit.ly/free","title":"Free stuff","type":"link","upvotePercentage":0,"views":0,"votes":[]`
+
+	testCases := []TestCase{
+		{ //Post removed by a rule
+			Request: postRequest(),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("bit.ly/free"),
+				mockAutomod.EXPECT().Check(postSubmission),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{}, nil},
+				{&automod.Decision{Rule: "shorteners", Action: automod.ActionRemove, Reason: "No shorteners"}, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"Removed by AutoModerator","reason":"No shorteners"}` + "\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Dry run rule only logs
+			Request: postRequest(),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("bit.ly/free"),
+				mockAutomod.EXPECT().Check(postSubmission),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Free stuff", "link", "", "https://bit.ly/free", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{}, nil},
+				{&automod.Decision{Rule: "shorteners", Action: automod.ActionRemove, DryRun: true}, nil},
+				{newPost(), nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Flagged post is saved and reported
+			Request: postRequest(),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("bit.ly/free"),
+				mockAutomod.EXPECT().Check(postSubmission),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Free stuff", "link", "", "https://bit.ly/free", posts.Flags{}, gomock.Any()),
+				mockReportsRepo.EXPECT().Add(&moderation.Report{
+					Kind:     posts.ItemPost,
+					ItemID:   postID,
+					PostID:   postID,
+					Category: "music",
+					Reporter: automod.Moderator,
+					Reason:   "Shortened link",
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{}, nil},
+				{&automod.Decision{Rule: "shorteners", Action: automod.ActionFlag, Reason: "Shortened link"}, nil},
+				{newPost(), nil},
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Post waits for approval
+			Request: postRequest(),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("bit.ly/free"),
+				mockAutomod.EXPECT().Check(postSubmission),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Free stuff", "link", "", "https://bit.ly/free", posts.Flags{}, gomock.Any()),
+				mockPostsRepo.EXPECT().SetPending(gomock.Any(), true, nil).Do(setPending),
+				mockReportsRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{}, nil},
+				{&automod.Decision{Rule: "newcomers", Action: automod.ActionRequireApproval, Reason: "newcomers"}, nil},
+				{newPost(), nil},
+				{nil},
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `,"pending":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Comment waits for approval and isn't attached
+			Request: commentRequest(),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockAutomod.EXPECT().Check(&automod.Submission{
+					Kind:     posts.ItemComment,
+					Author:   testUser,
+					Category: "music",
+					Body:     "Buy now",
+				}),
+				mockCommentsRepo.EXPECT().NewComment(testUser, "Buy now", bson.ObjectId("")),
+				mockReportsRepo.EXPECT().Add(&moderation.Report{
+					Kind:     posts.ItemComment,
+					ItemID:   commentID,
+					PostID:   postID,
+					Category: "music",
+					Reporter: automod.Moderator,
+					Reason:   "spam",
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{&automod.Decision{Rule: "spam", Action: automod.ActionRequireApproval, Reason: "spam"}, nil},
+				{commentID, nil},
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Approve a pending post
+			Request: approveRequest(postID, `{"action": "approve", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockPostsRepo.EXPECT().SetPending(gomock.Any(), false, gomock.Any()).Do(setPending),
+				mockReportsRepo.EXPECT().Resolve(postID, moderation.StatusApproved),
+				mockModLogRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemPost, postID, postID, "music"), nil},
+				{pendingPost(), nil},
+				{nil},
+				{1, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Approve a pending comment
+			Request: approveRequest(commentID, `{"action": "approve", "type": "comment", "postId": "5ebaf9ee3c04c17c56f51244"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(commentID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockPostsRepo.EXPECT().AddComment(postID, commentID, gomock.Any()),
+				mockReportsRepo.EXPECT().Resolve(commentID, moderation.StatusApproved),
+				mockModLogRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemComment, commentID, postID, "music"), nil},
+				{newPost(), nil},
+				{newPost(), nil},
+				{1, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Pending posts are hidden from listings
+			Request: httptest.NewRequest("GET", "/api/posts/", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetAll(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{pendingPost()}, nil},
+			},
+			HandlerFunc: postsTestHandler.ListAll,
+			ExpectResult: Result{
+				Body: []byte(`[]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Pending post page is hidden from anonymous users
+			Request: postPageRequest(httptest.NewRequest("GET", "/api/post/{ID}", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{pendingPost(), nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //and from other users
+			Request: postPageRequest(requestWithUser(httptest.NewRequest("GET", "/api/post/{ID}", nil), &user.User{ID: 3, Username: "stranger"})),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{pendingPost(), nil},
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //The moderator sees it
+			Request: postPageRequest(requestWithUser(httptest.NewRequest("GET", "/api/post/{ID}", nil), testModerator)),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{pendingPost(), nil},
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `,"pending":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //and so does the author
+			Request: postPageRequest(requestWithSession(httptest.NewRequest("GET", "/api/post/{ID}", nil))),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{pendingPost(), nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte(postResponse + `,"pending":true}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
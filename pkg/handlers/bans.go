@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type BansRepositoryInterface interface {
+	Ban(*user.Ban) error
+	Unban(int64) (bool, error)
+	Get(int64) (*user.Ban, error)
+}
+
+type CategoryBansRepositoryInterface interface {
+	Ban(*category.Ban) error
+	Unban(string, int64) (bool, error)
+	Get(string, int64) (*category.Ban, error)
+	GetByCategory(string) ([]*category.Ban, error)
+}
+
+type BanRequest struct {
+	Reason  string `json:"reason,omitempty"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// RejectedResponse tells the user why the request is refused, for a ban or
+// a removal by the automoderator.
+type RejectedResponse struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason,omitempty"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// readBanRequest reads and checks the reason and the expiry of a ban, the
+// expiry comes back in UTC. It answers with an error and returns nil if the
+// request is bad.
+func readBanRequest(w http.ResponseWriter, r *http.Request, logger *zap.SugaredLogger) *BanRequest {
+	banRequest := new(BanRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, banRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return nil
+	}
+	validationErrors := user.ValidateBan(banRequest.Reason, banRequest.Expires, time.Now())
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		logger.Errorf("Bad ban: %v", validationErrors)
+		return nil
+	}
+	if banRequest.Expires != "" {
+		expires, _ := time.Parse(time.RFC3339, banRequest.Expires)
+		banRequest.Expires = expires.UTC().Format(time.RFC3339)
+	}
+	return banRequest
+}
+
+func writeRejected(w http.ResponseWriter, message, reason, expires string) {
+	ans, _ := json.Marshal(&RejectedResponse{
+		Message: message,
+		Reason:  reason,
+		Expires: expires,
+	})
+	http.Error(w, string(ans), http.StatusForbidden)
+}
+
+// checkNotBanned answers with an error and returns false if the user is
+// banned from the site.
+func (h *UserHandler) checkNotBanned(w http.ResponseWriter, u *user.User) bool {
+	if h.BansRepo == nil {
+		return true
+	}
+	ban, err := h.BansRepo.Get(u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return false
+	}
+	if ban != nil {
+		writeRejected(w, "You are banned", ban.Reason, ban.Expires)
+		h.Logger.Infof("Banned user %v tried to log in", u.Username)
+		return false
+	}
+	return true
+}
+
+// bannedUser checks that the session user is an admin and finds the user in
+// the URL. It answers with an error and returns nil if not.
+func (h *UserHandler) bannedUser(w http.ResponseWriter, r *http.Request) *user.User {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return nil
+	}
+	if !h.Admins[sess.User.Username] {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("%v isn't an admin", sess.User.Username)
+		return nil
+	}
+	login := mux.Vars(r)["USER_LOGIN"]
+	u, err := h.UserRepo.GetByUsername(login)
+	if err == user.ErrNoUser {
+		http.Error(w, `No user`, http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	return u
+}
+
+// Ban bans the user in the URL from the site and logs the user out
+// everywhere. Only admins can do it.
+func (h *UserHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	u := h.bannedUser(w, r)
+	if u == nil {
+		return
+	}
+	banRequest := readBanRequest(w, r, h.Logger)
+	if banRequest == nil {
+		return
+	}
+	ban := &user.Ban{
+		UserID:  u.ID,
+		Reason:  banRequest.Reason,
+		Expires: banRequest.Expires,
+	}
+	err := h.BansRepo.Ban(ban)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	revoked, err := h.Sessions.RevokeAll(u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("Can't revoke sessions of %v: %v", u.Username, err)
+		return
+	}
+	resp, _ := json.Marshal(ban)
+	w.Write(resp)
+	h.Logger.Infof("%v was banned, %v sessions revoked", u.Username, revoked)
+}
+
+func (h *UserHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	u := h.bannedUser(w, r)
+	if u == nil {
+		return
+	}
+	ok, err := h.BansRepo.Unban(u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v was unbanned", u.Username)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v wasn't banned", u.Username)
+	}
+}
+
+// bannedUser finds the user in the URL, it answers with an error and returns
+// nil if there is no such user.
+func (h *CategoryHandler) bannedUser(w http.ResponseWriter, r *http.Request) *user.User {
+	login := mux.Vars(r)["USER_LOGIN"]
+	u, err := h.UserRepo.GetByUsername(login)
+	if err == user.ErrNoUser {
+		http.Error(w, `No user`, http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	return u
+}
+
+// Ban keeps the user in the URL from posting and commenting in the category.
+func (h *CategoryHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	cat, sess := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger)
+	if cat == nil {
+		return
+	}
+	u := h.bannedUser(w, r)
+	if u == nil {
+		return
+	}
+	if isModerator(cat, u, h.Admins) {
+		writeFieldError(w, "user", u.Username, "can't ban a moderator")
+		return
+	}
+	banRequest := readBanRequest(w, r, h.Logger)
+	if banRequest == nil {
+		return
+	}
+	ban := &category.Ban{
+		Category:  cat.Name,
+		User:      u,
+		Moderator: sess.User,
+		Reason:    banRequest.Reason,
+		Expires:   banRequest.Expires,
+	}
+	err := h.BansRepo.Ban(ban)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(ban)
+	w.Write(resp)
+	h.Logger.Infof("%v was banned from %v", u.Username, cat.Name)
+}
+
+func (h *CategoryHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	cat, _ := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger)
+	if cat == nil {
+		return
+	}
+	u := h.bannedUser(w, r)
+	if u == nil {
+		return
+	}
+	ok, err := h.BansRepo.Unban(cat.Name, u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v was unbanned in %v", u.Username, cat.Name)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v wasn't banned in %v", u.Username, cat.Name)
+	}
+}
+
+// ListBans shows the active bans of the category to its moderators.
+func (h *CategoryHandler) ListBans(w http.ResponseWriter, r *http.Request) {
+	cat, _ := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger)
+	if cat == nil {
+		return
+	}
+	bans, err := h.BansRepo.GetByCategory(cat.Name)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(bans)
+	w.Write(resp)
+	h.Logger.Infof("Bans of %v", cat.Name)
+}
+
+// checkNotBanned answers with an error and returns false if the user is
+// banned from the category.
+func (h *PostsHandler) checkNotBanned(w http.ResponseWriter, name string, u *user.User) bool {
+	if h.BansRepo == nil {
+		return true
+	}
+	ban, err := h.BansRepo.Get(name, u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return false
+	}
+	if ban != nil {
+		writeRejected(w, "You are banned from "+name, ban.Reason, ban.Expires)
+		h.Logger.Infof("%v is banned from %v", u.Username, name)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	posts "reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSiteBans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockUserRepositoryInterface(ctrl)
+	mockSessionManager := NewMockSessionManagerInterface(ctrl)
+	mockBansRepo := NewMockBansRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	userTestHandler := &UserHandler{
+		UserRepo: mockRepo,
+		Logger:   zapLogger.Sugar(),
+		Sessions: mockSessionManager,
+		BansRepo: mockBansRepo,
+		Admins:   map[string]bool{"igor": true},
+	}
+	banRequest := func(method string, u *user.User, body string) *http.Request {
+		r := httptest.NewRequest(method, "/api/admin/bans/rvasily", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"USER_LOGIN": "rvasily"})
+	}
+
+	testCases := []TestCase{
+		{ //Login of a banned user
+			Request: httptest.NewRequest("POST", "/api/login",
+				bytes.NewReader([]byte(`{"username": "rvasily", "password": "lovelove"}`))),
+			ExpectMockFunc: []*gomock.Call{
+				mockRepo.EXPECT().Authorize("rvasily", "lovelove"),
+				mockBansRepo.EXPECT().Get(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testUser, nil},
+				{&user.Ban{UserID: 1, Reason: "spam", Created: "2020-05-12T22:33:02Z"}, nil},
+			},
+			HandlerFunc: userTestHandler.Login,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"You are banned","reason":"spam"}` + "\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Ban SUCCESS
+			Request: banRequest("POST", testModerator, `{"reason": "spam", "expires": "2030-01-01T03:00:00+03:00"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockRepo.EXPECT().GetByUsername("rvasily"),
+				mockBansRepo.EXPECT().Ban(&user.Ban{UserID: 1, Reason: "spam", Expires: "2030-01-01T00:00:00Z"}),
+				mockSessionManager.EXPECT().RevokeAll(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testUser, nil},
+				{nil},
+				{int64(2), nil},
+			},
+			HandlerFunc: userTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte(`{"user":"1","reason":"spam","created":"","expires":"2030-01-01T00:00:00Z"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Ban by a user who isn't an admin
+			Request:        banRequest("POST", testUser, `{}`),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    userTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Ban with an expiry in the past
+			Request: banRequest("POST", testModerator, `{"expires": "2020-01-01T00:00:00Z"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockRepo.EXPECT().GetByUsername("rvasily"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testUser, nil},
+			},
+			HandlerFunc: userTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"expires","value":"2020-01-01T00:00:00Z","msg":"must be in the future"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Ban an unknown user
+			Request: banRequest("POST", testModerator, `{}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockRepo.EXPECT().GetByUsername("rvasily"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, user.ErrNoUser},
+			},
+			HandlerFunc: userTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte("No user\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Unban
+			Request: banRequest("DELETE", testModerator, ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockRepo.EXPECT().GetByUsername("rvasily"),
+				mockBansRepo.EXPECT().Unban(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testUser, nil},
+				{true, nil},
+			},
+			HandlerFunc: userTestHandler.Unban,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestCategoryBans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockUserRepo := NewMockUserRepositoryInterface(ctrl)
+	mockBansRepo := NewMockCategoryBansRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	categoryTestHandler := &CategoryHandler{
+		Logger:       zapLogger.Sugar(),
+		CategoryRepo: mockCategoryRepo,
+		UserRepo:     mockUserRepo,
+		BansRepo:     mockBansRepo,
+	}
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		BansRepo:     mockBansRepo,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	banRequest := func(method string, u *user.User, login, body string) *http.Request {
+		r := httptest.NewRequest(method, "/api/categories/music/bans/"+login, bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"CATEGORY": "music", "USER_LOGIN": login})
+	}
+	musicBan := &category.Ban{
+		Category:  "music",
+		User:      testUser,
+		Moderator: testModerator,
+		Reason:    "flood",
+		Created:   "2020-05-12T22:33:02Z",
+		Expires:   "2030-01-01T00:00:00Z",
+	}
+
+	testCases := []TestCase{
+		{ //Ban SUCCESS
+			Request: banRequest("POST", testModerator, "rvasily", `{"reason": "flood"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockUserRepo.EXPECT().GetByUsername("rvasily"),
+				mockBansRepo.EXPECT().Ban(&category.Ban{
+					Category:  "music",
+					User:      testUser,
+					Moderator: testModerator,
+					Reason:    "flood",
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{testUser, nil},
+				{nil},
+			},
+			HandlerFunc: categoryTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte(`{"category":"music","user":{"username":"rvasily","id":"1"},"moderator":{"username":"igor","id":"2"},"reason":"flood","created":""}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Ban by a user who isn't a moderator
+			Request: banRequest("POST", testUser, "igor", `{}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: categoryTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Ban the moderator
+			Request: banRequest("POST", testModerator, "igor", `{}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockUserRepo.EXPECT().GetByUsername("igor"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{testModerator, nil},
+			},
+			HandlerFunc: categoryTestHandler.Ban,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"user","value":"igor","msg":"can't ban a moderator"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //List bans
+			Request: mux.SetURLVars(requestWithUser(httptest.NewRequest("GET", "/api/categories/music/bans", nil), testModerator),
+				map[string]string{"CATEGORY": "music"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockBansRepo.EXPECT().GetByCategory("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{[]*category.Ban{musicBan}, nil},
+			},
+			HandlerFunc: categoryTestHandler.ListBans,
+			ExpectResult: Result{
+				Body: []byte(`[{"category":"music","user":{"username":"rvasily","id":"1"},"moderator":{"username":"igor","id":"2"},"reason":"flood","created":"2020-05-12T22:33:02Z","expires":"2030-01-01T00:00:00Z"}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unban
+			Request: banRequest("DELETE", testModerator, "rvasily", ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockUserRepo.EXPECT().GetByUsername("rvasily"),
+				mockBansRepo.EXPECT().Unban("music", int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{testUser, nil},
+				{false, nil},
+			},
+			HandlerFunc: categoryTestHandler.Unban,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Post in a category the user is banned from
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/posts",
+				bytes.NewReader([]byte(`{"category": "music", "type": "text", "title": "Hi", "text": "Hello"}`)))),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockBansRepo.EXPECT().Get("music", int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{musicBan, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"You are banned from music","reason":"flood","expires":"2030-01-01T00:00:00Z"}` + "\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Comment in a category the user is banned from
+			Request: mux.SetURLVars(requestWithSession(httptest.NewRequest("POST", "/api/post/{POST_ID}",
+				bytes.NewReader([]byte(`{"comment": "Hi"}`)))), map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockBansRepo.EXPECT().Get("music", int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&posts.Post{ID: postID, Category: "music"}, nil},
+				{musicBan, nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"You are banned from music","reason":"flood","expires":"2030-01-01T00:00:00Z"}` + "\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type CategoryRepositoryInterface interface {
+	GetAll() ([]*category.Category, error)
+	GetByName(string) (*category.Category, error)
+	Add(*user.User, string, string, []string) (*category.Category, error)
+	SetFlairs(string, []category.Flair) error
+}
+
+type SubscriptionRepositoryInterface interface {
+	GetByUser(int64) ([]string, error)
+	Subscribe(int64, string) error
+	Unsubscribe(int64, string) (bool, error)
+}
+
+type CategoryHandler struct {
+	CategoryRepo     CategoryRepositoryInterface
+	SubscriptionRepo SubscriptionRepositoryInterface
+	UserRepo         UserRepositoryInterface
+	BansRepo         CategoryBansRepositoryInterface
+	Admins           map[string]bool // usernames of the site admins
+	Logger           *zap.SugaredLogger
+}
+
+type NewCategoryRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Rules       []string `json:"rules"`
+}
+
+func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryRepo.GetAll()
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(categories)
+	w.Write(resp)
+	h.Logger.Infof("List categories")
+}
+
+func (h *CategoryHandler) Describe(w http.ResponseWriter, r *http.Request) {
+	arg := mux.Vars(r)
+	name := arg["CATEGORY"]
+	cat, err := h.CategoryRepo.GetByName(name)
+	if err == category.ErrNoCategory {
+		http.Error(w, `No category`, http.StatusNotFound)
+		h.Logger.Errorf("No category: %v", name)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(cat)
+	w.Write(resp)
+	h.Logger.Infof("Describe category %v", name)
+}
+
+func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	newRequest := new(NewCategoryRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, newRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+
+	newCategory, err := h.CategoryRepo.Add(
+		sess.User,
+		newRequest.Name,
+		newRequest.Description,
+		newRequest.Rules,
+	)
+	if err == category.ErrBadName {
+		writeFieldError(w, "name", newRequest.Name, category.NameHint)
+		h.Logger.Errorf("Bad category name: %v", newRequest.Name)
+		return
+	}
+	if err == category.ErrAlreadyExisting {
+		writeFieldError(w, "name", newRequest.Name, "already exists")
+		h.Logger.Errorf("Category already exists: %v", newRequest.Name)
+		return
+	}
+	if err != nil {
+		http.Error(w, "BD error", http.StatusInternalServerError)
+		h.Logger.Errorf("Bad add category. Error: %v", err)
+		return
+	}
+
+	resp, _ := json.Marshal(newCategory)
+	w.Write(resp)
+	h.Logger.Infof("New category was created: %v", newCategory.Name)
+}
+
+// SetFlairs replaces the flairs of a category, only its moderator can do it.
+func (h *CategoryHandler) SetFlairs(w http.ResponseWriter, r *http.Request) {
+	cat, _ := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger)
+	if cat == nil {
+		return
+	}
+	name := cat.Name
+
+	flairs := make([]category.Flair, 0)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, &flairs)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	validationErrors := posts.ValidateFlairs(flairs)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad flairs: %v", validationErrors)
+		return
+	}
+
+	err = h.CategoryRepo.SetFlairs(name, flairs)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	cat.Flairs = flairs
+	resp, _ := json.Marshal(cat)
+	w.Write(resp)
+	h.Logger.Infof("Flairs of %v were updated", name)
+}
+
+func (h *CategoryHandler) Subscriptions(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	categories, err := h.SubscriptionRepo.GetByUser(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(categories)
+	w.Write(resp)
+	h.Logger.Infof("List subscriptions of %v", sess.User.Username)
+}
+
+func (h *CategoryHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	name := arg["CATEGORY"]
+	_, err = h.CategoryRepo.GetByName(name)
+	if err == category.ErrNoCategory {
+		http.Error(w, `No category`, http.StatusNotFound)
+		h.Logger.Errorf("No category: %v", name)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	err = h.SubscriptionRepo.Subscribe(sess.User.ID, name)
+	if err != nil {
+		http.Error(w, `Subscribe error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Subscribe error: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v subscribed to %v", sess.User.Username, name)
+}
+
+func (h *CategoryHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	name := arg["CATEGORY"]
+	ok, err := h.SubscriptionRepo.Unsubscribe(sess.User.ID, name)
+	if err != nil {
+		http.Error(w, `Unsubscribe error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Unsubscribe error: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v unsubscribed from %v", sess.User.Username, name)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v wasn't subscribed to %v", sess.User.Username, name)
+	}
+}
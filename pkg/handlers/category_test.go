@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	"reddit/pkg/session"
+	"strings"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func requestWithSession(r *http.Request) *http.Request {
+	sess := &session.Session{
+		ID:   1,
+		User: testUser,
+	}
+	ctx := context.WithValue(r.Context(), session.SessionKey, sess)
+	return r.WithContext(ctx)
+}
+
+func TestCategories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockSubscriptionRepo := NewMockSubscriptionRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	categoryTestHandler := &CategoryHandler{
+		Logger:           logger,
+		CategoryRepo:     mockCategoryRepo,
+		SubscriptionRepo: mockSubscriptionRepo,
+	}
+	subscribeRequest := func(method string) *http.Request {
+		r := httptest.NewRequest(method, "/api/categories/{CATEGORY}/subscribe", nil)
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"CATEGORY": "music"})
+	}
+	newCategory := &category.Category{
+		Name:        "golang",
+		Owner:       testUser,
+		Description: "Gophers",
+		Rules:       []string{"be nice"},
+		Created:     "2020-05-12T22:33:02+03:00",
+	}
+	createRequest := func(reqBody NewCategoryRequest) *http.Request {
+		bodyJSON, _ := json.Marshal(reqBody)
+		r := httptest.NewRequest("POST", "/api/categories", bytes.NewReader(bodyJSON))
+		return requestWithSession(r)
+	}
+
+	testCases := []TestCase{
+		{ //List SUCCESS
+			Request: httptest.NewRequest("GET", "/api/categories", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetAll(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*category.Category{testCategory}, nil},
+			},
+			HandlerFunc: categoryTestHandler.List,
+			ExpectResult: Result{
+				Body: []byte(`[{"name":"music","owner":null,"description":"Music","rules":[],"created":"2020-05-12T22:33:02+03:00"}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //List. Repo error
+			Request: httptest.NewRequest("GET", "/api/categories", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetAll(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.List,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Describe SUCCESS
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/categories/{CATEGORY}", nil),
+				map[string]string{"CATEGORY": "golang"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("golang"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newCategory, nil},
+			},
+			HandlerFunc: categoryTestHandler.Describe,
+			ExpectResult: Result{
+				Body: []byte(`{"name":"golang","owner":{"username":"rvasily","id":"1"},"description":"Gophers","rules":["be nice"],"created":"2020-05-12T22:33:02+03:00"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Describe. No category
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/categories/{CATEGORY}", nil),
+				map[string]string{"CATEGORY": "golang"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("golang"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, category.ErrNoCategory},
+			},
+			HandlerFunc: categoryTestHandler.Describe,
+			ExpectResult: Result{
+				Body: []byte("No category\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Describe. Repo error
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/categories/{CATEGORY}", nil),
+				map[string]string{"CATEGORY": "golang"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("golang"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.Describe,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Create SUCCESS
+			Request: createRequest(NewCategoryRequest{
+				Name:        "golang",
+				Description: "Gophers",
+				Rules:       []string{"be nice"},
+			}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().Add(testUser, "golang", "Gophers", []string{"be nice"}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newCategory, nil},
+			},
+			HandlerFunc: categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte(`{"name":"golang","owner":{"username":"rvasily","id":"1"},"description":"Gophers","rules":["be nice"],"created":"2020-05-12T22:33:02+03:00"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Create. Session error
+			Request:        httptest.NewRequest("POST", "/api/categories", nil),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte("Bad auth\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Create. Bad name
+			Request: createRequest(NewCategoryRequest{
+				Name: "Go Lang",
+			}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().Add(testUser, "Go Lang", "", nil),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, category.ErrBadName},
+			},
+			HandlerFunc: categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"name","value":"Go Lang","msg":"must be 2-21 lowercase letters, digits or underscores"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Create. Already exists
+			Request: createRequest(NewCategoryRequest{
+				Name: "music",
+			}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().Add(testUser, "music", "", nil),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, category.ErrAlreadyExisting},
+			},
+			HandlerFunc: categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"name","value":"music","msg":"already exists"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Create. Too big body
+			Request: createRequest(NewCategoryRequest{
+				Name:        "golang",
+				Description: strings.Repeat("a", maxRequestBody),
+			}),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte("Bad request\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Create. Repo error
+			Request: createRequest(NewCategoryRequest{
+				Name: "golang",
+			}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().Add(testUser, "golang", "", nil),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.Create,
+			ExpectResult: Result{
+				Body: []byte("BD error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Subscriptions SUCCESS
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/me/subscriptions", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().GetByUser(testUser.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]string{"music", "news"}, nil},
+			},
+			HandlerFunc: categoryTestHandler.Subscriptions,
+			ExpectResult: Result{
+				Body: []byte(`["music","news"]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Subscriptions. Session error
+			Request:        httptest.NewRequest("GET", "/api/me/subscriptions", nil),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    categoryTestHandler.Subscriptions,
+			ExpectResult: Result{
+				Body: []byte("Bad auth\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Subscriptions. Repo error
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/me/subscriptions", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().GetByUser(testUser.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.Subscriptions,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Subscribe SUCCESS
+			Request: subscribeRequest("POST"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockSubscriptionRepo.EXPECT().Subscribe(testUser.ID, "music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{nil},
+			},
+			HandlerFunc: categoryTestHandler.Subscribe,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Subscribe. No category
+			Request: subscribeRequest("POST"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, category.ErrNoCategory},
+			},
+			HandlerFunc: categoryTestHandler.Subscribe,
+			ExpectResult: Result{
+				Body: []byte("No category\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Subscribe. Repo error
+			Request: subscribeRequest("POST"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockSubscriptionRepo.EXPECT().Subscribe(testUser.ID, "music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.Subscribe,
+			ExpectResult: Result{
+				Body: []byte("Subscribe error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Unsubscribe SUCCESS
+			Request: subscribeRequest("DELETE"),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().Unsubscribe(testUser.ID, "music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{true, nil},
+			},
+			HandlerFunc: categoryTestHandler.Unsubscribe,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unsubscribe. Not subscribed
+			Request: subscribeRequest("DELETE"),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().Unsubscribe(testUser.ID, "music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{false, nil},
+			},
+			HandlerFunc: categoryTestHandler.Unsubscribe,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unsubscribe. Repo error
+			Request: subscribeRequest("DELETE"),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().Unsubscribe(testUser.ID, "music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{false, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: categoryTestHandler.Unsubscribe,
+			ExpectResult: Result{
+				Body: []byte("Unsubscribe error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"reddit/pkg/automod"
+	"reddit/pkg/posts"
 	"reddit/pkg/session"
 
 	"github.com/gorilla/mux"
@@ -12,6 +14,7 @@ import (
 
 type AddCommentRequest struct {
 	Comment string `json:"comment"`
+	Parent  string `json:"parent,omitempty"` // id of the comment to reply to
 }
 
 func (h *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
@@ -24,36 +27,68 @@ func (h *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	arg := mux.Vars(r)
 
 	newRequest := new(AddCommentRequest)
-	body, errReadBody := ioutil.ReadAll(r.Body)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
 	err = json.Unmarshal(body, newRequest)
 	if errReadBody != nil || err != nil {
 		http.Error(w, "", http.StatusBadRequest)
 		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
 		return
 	}
+	validationErrors := posts.ValidateComment(newRequest.Comment)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad new comment: %v", validationErrors)
+		return
+	}
 	if !bson.IsObjectIdHex(arg["POST_ID"]) {
 		http.Error(w, "bad id", 500)
 		return
 	}
 	postID := bson.ObjectIdHex(arg["POST_ID"])
-	commentID, err := h.CommentRepo.NewComment(sess.User, newRequest.Comment)
-	if err != nil {
-		http.Error(w, "", http.StatusInternalServerError)
-		h.Logger.Errorf("Bad add comment to comment repo. Error: %v, %v", err)
+	post := h.commentablePost(w, postID, sess.User)
+	if post == nil {
 		return
 	}
-	post, err := h.PostsRepo.AddComment(postID, commentID)
+	parentID, ok := commentParent(w, post, newRequest.Parent)
+	if !ok {
+		return
+	}
+	decision, ok := h.automodCheck(w, &automod.Submission{
+		Kind:     posts.ItemComment,
+		Author:   sess.User,
+		Category: post.Category,
+		Body:     newRequest.Comment,
+	})
+	if !ok {
+		return
+	}
+	commentID, err := h.CommentRepo.NewComment(sess.User, newRequest.Comment, parentID)
 	if err != nil {
 		http.Error(w, "", http.StatusInternalServerError)
-		h.Logger.Errorf("Bad add comment to post repo. Error: %v, %v", err)
+		h.Logger.Errorf("Bad add comment to comment repo. Error: %v, %v", err)
 		return
 	}
+	// a comment waiting for approval is attached to the post once approved
+	if !needsApproval(decision) {
+		post, err = h.PostsRepo.AddComment(postID, commentID, h.commentAdded(commentID))
+		if err != nil {
+			http.Error(w, "", http.StatusInternalServerError)
+			h.Logger.Errorf("Bad add comment to post repo. Error: %v, %v", err)
+			return
+		}
+	}
+	h.applyAutomod(decision, posts.ItemComment, commentID, post)
 	postResponse, err := PostToPostResponse(post, h.CommentRepo)
 	if err != nil {
 		http.Error(w, ``, http.StatusInternalServerError)
 		h.Logger.Errorf("Post Transform error: %v", err)
 		return
 	}
+	h.indexPost(post, postResponse.Comments)
+	if !needsApproval(decision) {
+		h.eventsStaged()
+		h.commentLive(post, postResponse.Comments, commentID)
+	}
 	answer, errAnswer := json.Marshal(postResponse)
 	if errAnswer != nil {
 		http.Error(w, `Bad form`, http.StatusBadRequest)
@@ -64,6 +99,36 @@ func (h *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	h.Logger.Infof("Post %v was updated by new comment", post.ID)
 }
 
+// commentParent checks the comment to reply to, it must be a comment of the
+// post. It answers with an error and returns false if it isn't.
+func commentParent(w http.ResponseWriter, post *posts.Post, parent string) (bson.ObjectId, bool) {
+	if parent == "" {
+		return "", true
+	}
+	if bson.IsObjectIdHex(parent) {
+		parentID := bson.ObjectIdHex(parent)
+		for _, commentID := range post.CommentsID {
+			if commentID == parentID {
+				return parentID, true
+			}
+		}
+	}
+	writeFieldError(w, "parent", parent, "must be a comment of the post")
+	return "", false
+}
+
+// commentLive tells the live watchers about the new comment, it is looked up
+// in the loaded comments of the post.
+func (h *PostsHandler) commentLive(post *posts.Post, comments []*posts.Comment, commentID bson.ObjectId) {
+	for _, comment := range comments {
+		if comment.ID != commentID {
+			continue
+		}
+		h.publish(post, LiveComment, &LiveCommentData{Post: post.ID, Comment: comment})
+		return
+	}
+}
+
 func (h *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	_, err := session.SessionFromContext(r.Context())
 	if err != nil {
@@ -84,8 +149,13 @@ func (h *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Delete comment fall, %v", err)
 		return
 	}
+	h.forgetSaved(commentID)
+	h.closeReports(commentID)
 
-	post, err := h.PostsRepo.DeleteComment(postID, commentID)
+	post, err := h.PostsRepo.DeleteComment(postID, commentID, h.commentDeleted(commentID))
+	if err == nil {
+		h.eventsStaged()
+	}
 
 	postResponse, err := PostToPostResponse(post, h.CommentRepo)
 	if err != nil {
@@ -93,6 +163,8 @@ func (h *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Post Transform error: %v", err)
 		return
 	}
+	h.indexPost(post, postResponse.Comments)
+	h.publish(post, LiveCommentDeleted, &LiveDeleteData{Post: post.ID, Comment: commentID})
 
 	answer, errAnswer := json.Marshal(postResponse)
 	if errAnswer != nil {
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/automod"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CrosspostRequest names the category to share into, the title of the
+// original is used when Title is empty. Flair is one of the flairs of that
+// category, the NSFW and spoiler marks come from the original.
+type CrosspostRequest struct {
+	Category string `json:"category"`
+	Title    string `json:"title,omitempty"`
+	Flair    string `json:"flair,omitempty"`
+}
+
+func (h *PostsHandler) Crosspost(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+
+	crosspostRequest := new(CrosspostRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, crosspostRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+
+	original, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	// what the user can't open can't be shared either
+	if h.hidden(r, original) {
+		http.Error(w, "No post", http.StatusNotFound)
+		return
+	}
+
+	validationErrors := posts.ValidateCrosspost(original, crosspostRequest.Category, crosspostRequest.Title)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad crosspost: %v", validationErrors)
+		return
+	}
+	cat, ok := h.checkCategory(w, crosspostRequest.Category, sess.User)
+	if !ok {
+		return
+	}
+	flags := posts.Flags{
+		Flair:   crosspostRequest.Flair,
+		NSFW:    original.NSFW,
+		Spoiler: original.Spoiler,
+	}
+	if !h.checkFlair(w, cat, flags.Flair) {
+		return
+	}
+	title := crosspostRequest.Title
+	if title == "" {
+		title = original.Title
+		if original.Crosspost != nil {
+			title = original.Crosspost.Title
+		}
+	}
+	decision, ok := h.automodCheck(w, &automod.Submission{
+		Kind:     posts.ItemPost,
+		Author:   sess.User,
+		Category: crosspostRequest.Category,
+		Title:    title,
+		Body:     original.Text,
+		Link:     original.Link,
+	})
+	if !ok {
+		return
+	}
+
+	announce := h.postCreated()
+	if needsApproval(decision) {
+		// announced once a moderator approves it
+		announce = nil
+	}
+	newPost, err := h.PostsRepo.Crosspost(sess.User, original, crosspostRequest.Category, crosspostRequest.Title, flags, announce)
+	if err == posts.ErrOriginalDeleted {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, "BD error", http.StatusInternalServerError)
+		h.Logger.Errorf("Bad crosspost. Error: %v", err)
+		return
+	}
+	h.applyAutomod(decision, posts.ItemPost, newPost.ID, newPost)
+	h.eventsStaged()
+
+	postResponse, err := PostToPostResponse(newPost, h.CommentRepo)
+	if err != nil {
+		http.Error(w, `BD error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Post Transform error: %v", err)
+		return
+	}
+	h.indexPost(newPost, postResponse.Comments)
+
+	answer, _ := json.Marshal(postResponse)
+	w.Write(answer)
+	h.Logger.Infof("Post %v was crossposted with ID: %v", postID, newPost.ID)
+}
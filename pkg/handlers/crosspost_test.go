@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/automod"
+	"reddit/pkg/category"
+	posts "reddit/pkg/posts"
+	"reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCrosspost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockPreferences := NewMockPreferencesRepositoryInterface(ctrl)
+	mockAutomod := NewMockAutomodInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Preferences:  mockPreferences,
+		Automod:      mockAutomod,
+	}
+	crosspostRequest := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/post/{POST_ID}/crosspost", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"POST_ID": "5ebaf9ee3c04c17c56f51244"})
+	}
+	original := &posts.Post{
+		Author:     testUser,
+		Category:   "music",
+		CommentsID: []bson.ObjectId{},
+		ID:         "^\xba\xf9\xee<\x04\xc1|V\xf5\x12D",
+		Title:      "Lorem",
+		Type:       "text",
+		Text:       "Something",
+	}
+	nsfwOriginal := *original
+	nsfwOriginal.NSFW = true
+	nsfwOriginal.Spoiler = true
+	funnyCategory := *testCategory
+	funnyCategory.Name = "funny"
+	funnyCategory.Flairs = []category.Flair{{Name: "Video"}}
+	submission := func(category, title, body string) *automod.Submission {
+		return &automod.Submission{
+			Kind:     posts.ItemPost,
+			Author:   testUser,
+			Category: category,
+			Title:    title,
+			Body:     body,
+		}
+	}
+	crosspost := &posts.Post{
+		Author:           testUser,
+		Category:         "funny",
+		CommentsID:       []bson.ObjectId{},
+		Created:          "2020-05-12T22:33:02+03:00",
+		ID:               "^\xbb\xfes<\x04\xc1+\x9b\xf8]\x1b",
+		Score:            1,
+		Title:            "Lorem",
+		Type:             "crosspost",
+		UpvotePercentage: 100,
+		Votes:            []posts.Vote{{UserID: 1, Rating: 1}},
+		Crosspost: &posts.Crosspost{
+			PostID:   original.ID,
+			Title:    "Lorem",
+			Author:   testUser,
+			Category: "music",
+		},
+	}
+
+	testCases := []TestCase{
+		{ //Crosspost SUCCESS
+			Request: crosspostRequest(`{"category": "funny"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockCategoryRepo.EXPECT().GetByName("funny"),
+				mockAutomod.EXPECT().Check(submission("funny", "Lorem", "Something")),
+				mockPostsRepo.EXPECT().Crosspost(testUser, original, "funny", "", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{original, nil},
+				{&funnyCategory, nil},
+				{nil, nil},
+				{crosspost, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"funny","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebbfe733c04c12b9bf85d1b","score":1,"title":"Lorem","type":"crosspost","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"crosspost":{"id":"5ebaf9ee3c04c17c56f51244","title":"Lorem","author":{"username":"rvasily","id":"1"},"category":"music","deleted":false}}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Same category
+			Request: crosspostRequest(`{"category": "music"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{original, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"category","value":"music","msg":"must differ from the category of the original"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //No original
+			Request: crosspostRequest(`{"category": "funny"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, mgo.ErrNotFound},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Original of the crosspost was deleted
+			Request: crosspostRequest(`{"category": "news"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockCategoryRepo.EXPECT().GetByName("news"),
+				mockAutomod.EXPECT().Check(submission("news", "Lorem", "")),
+				mockPostsRepo.EXPECT().Crosspost(testUser, crosspost, "news", "", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{crosspost, nil},
+				{testCategory, nil},
+				{nil, nil},
+				{nil, posts.ErrOriginalDeleted},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte("Original post was deleted\n"),
+				Code: http.StatusGone,
+			},
+		},
+		{ //NSFW original is hidden from the user
+			Request: crosspostRequest(`{"category": "funny"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockPreferences.EXPECT().Get(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&nsfwOriginal, nil},
+				{&user.Preferences{}, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //NSFW and spoiler marks are copied, flair is checked
+			Request: crosspostRequest(`{"category": "funny", "flair": "Video"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockPreferences.EXPECT().Get(int64(1)),
+				mockCategoryRepo.EXPECT().GetByName("funny"),
+				mockAutomod.EXPECT().Check(submission("funny", "Lorem", "Something")),
+				mockPostsRepo.EXPECT().Crosspost(testUser, &nsfwOriginal, "funny", "", posts.Flags{Flair: "Video", NSFW: true, Spoiler: true}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&nsfwOriginal, nil},
+				{&user.Preferences{ShowNSFW: true}, nil},
+				{&funnyCategory, nil},
+				{nil, nil},
+				{crosspost, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"funny","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebbfe733c04c12b9bf85d1b","score":1,"title":"Lorem","type":"crosspost","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"crosspost":{"id":"5ebaf9ee3c04c17c56f51244","title":"Lorem","author":{"username":"rvasily","id":"1"},"category":"music","deleted":false}}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unknown flair
+			Request: crosspostRequest(`{"category": "funny", "flair": "memes"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockCategoryRepo.EXPECT().GetByName("funny"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{original, nil},
+				{&funnyCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"flair","value":"memes","msg":"unknown flair"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Removed by a rule
+			Request: crosspostRequest(`{"category": "funny", "title": "Buy now"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(original.ID),
+				mockCategoryRepo.EXPECT().GetByName("funny"),
+				mockAutomod.EXPECT().Check(submission("funny", "Buy now", "Something")),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{original, nil},
+				{&funnyCategory, nil},
+				{&automod.Decision{Rule: "spam", Action: automod.ActionRemove, Reason: "No spam"}, nil},
+			},
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"Removed by AutoModerator","reason":"No spam"}` + "\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Bad JSON
+			Request:     crosspostRequest(`{"category": `),
+			HandlerFunc: postsTestHandler.Crosspost,
+			ExpectResult: Result{
+				Body: []byte("Bad JSON\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type DomainsRepositoryInterface interface {
+	Set(*posts.DomainRule) error
+	Delete(string) (bool, error)
+	GetAll() ([]*posts.DomainRule, error)
+	Match(string) (*posts.DomainRule, error)
+}
+
+// DomainsHandler lets the site admins keep the rules for link domains.
+type DomainsHandler struct {
+	Logger      *zap.SugaredLogger
+	DomainsRepo DomainsRepositoryInterface
+	Admins      map[string]bool // usernames of the site admins
+}
+
+type DomainRuleRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// admin answers with an error and returns nil if the session user isn't an
+// admin.
+func (h *DomainsHandler) admin(w http.ResponseWriter, r *http.Request) *session.Session {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return nil
+	}
+	if !h.Admins[sess.User.Username] {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("%v isn't an admin", sess.User.Username)
+		return nil
+	}
+	return sess
+}
+
+func (h *DomainsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if h.admin(w, r) == nil {
+		return
+	}
+	rules, err := h.DomainsRepo.GetAll()
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(rules)
+	w.Write(resp)
+	h.Logger.Infof("Domain rules")
+}
+
+// Set blocks, flags or allows the domain in the URL and its subdomains.
+func (h *DomainsHandler) Set(w http.ResponseWriter, r *http.Request) {
+	sess := h.admin(w, r)
+	if sess == nil {
+		return
+	}
+	ruleRequest := new(DomainRuleRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, ruleRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	domain := mux.Vars(r)["DOMAIN"]
+	validationErrors := posts.ValidateDomainRule(domain, ruleRequest.Action, ruleRequest.Reason)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad domain rule: %v", validationErrors)
+		return
+	}
+	rule := &posts.DomainRule{
+		Domain: domain,
+		Action: ruleRequest.Action,
+		Reason: ruleRequest.Reason,
+		Admin:  sess.User,
+	}
+	err = h.DomainsRepo.Set(rule)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(rule)
+	w.Write(resp)
+	h.Logger.Infof("%v set %v for %v", sess.User.Username, rule.Action, domain)
+}
+
+func (h *DomainsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	sess := h.admin(w, r)
+	if sess == nil {
+		return
+	}
+	domain := mux.Vars(r)["DOMAIN"]
+	ok, err := h.DomainsRepo.Delete(domain)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v deleted the rule of %v", sess.User.Username, domain)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v had no rule", domain)
+	}
+}
+
+// checkDomain answers with an error and returns false if the link goes to a
+// blocked domain. For a flagged domain it returns the reason to report the
+// post with.
+func (h *PostsHandler) checkDomain(w http.ResponseWriter, link string) (string, bool) {
+	if h.DomainsRepo == nil {
+		return "", true
+	}
+	domain := posts.Domain(link)
+	rule, err := h.DomainsRepo.Match(domain)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return "", false
+	}
+	if rule == nil || rule.Action == posts.DomainAllow {
+		return "", true
+	}
+	msg := "links to " + domain
+	if rule.Action == posts.DomainBlock {
+		msg += " are not allowed"
+	} else {
+		msg += " are flagged"
+	}
+	if rule.Reason != "" {
+		msg += ": " + rule.Reason
+	}
+	if rule.Action == posts.DomainBlock {
+		writeFieldError(w, "url", link, msg)
+		h.Logger.Infof("Link to blocked %v", domain)
+		return "", false
+	}
+	return msg, true
+}
+
+// ListByDomain shows the link posts to the domain in the URL.
+func (h *PostsHandler) ListByDomain(w http.ResponseWriter, r *http.Request) {
+	domain := posts.NormalizeDomain(mux.Vars(r)["DOMAIN"])
+	domainPosts, err := h.PostsRepo.GetByDomain(domain)
+	if err != nil {
+		http.Error(w, `DB error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Bad domain: %v", err)
+		return
+	}
+	domainPosts = h.visiblePosts(r, domainPosts)
+	marks := h.userMarks(r, domainPosts)
+	domainPosts = withoutHidden(r, domainPosts, marks)
+
+	postsResponse := make([]*PostResponse, 0)
+
+	for _, post := range domainPosts {
+		postResponse, err := PostToPostResponse(post, h.CommentRepo)
+		if err != nil {
+			http.Error(w, `DB error`, http.StatusInternalServerError)
+			h.Logger.Errorf("Post Transform error: %v", err)
+			return
+		}
+		postsResponse = append(postsResponse, postResponse)
+	}
+	markSaved(r, postsResponse, h.SavedRepo, h.Logger.Errorf)
+	applyMarks(postsResponse, marks)
+
+	resp, _ := json.Marshal(postsResponse)
+	w.Write(resp)
+	h.Logger.Infof("List domain %v", domain)
+}
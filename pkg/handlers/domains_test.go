@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/automod"
+	"reddit/pkg/moderation"
+	posts "reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestDomains(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockReportsRepo := NewMockReportsRepositoryInterface(ctrl)
+	mockDomainsRepo := NewMockDomainsRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		ReportsRepo:  mockReportsRepo,
+		DomainsRepo:  mockDomainsRepo,
+	}
+	domainsTestHandler := &DomainsHandler{
+		Logger:      zapLogger.Sugar(),
+		DomainsRepo: mockDomainsRepo,
+		Admins:      map[string]bool{"igor": true},
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	linkPost := &posts.Post{
+		Author:     testUser,
+		Category:   "music",
+		CommentsID: []bson.ObjectId{},
+		Created:    "2020-05-12T22:33:02+03:00",
+		ID:         postID,
+		Title:      "News",
+		Type:       "link",
+		Link:       "https://news.example.com/1",
+		Domain:     "news.example.com",
+		Votes:      []posts.Vote{},
+	}
+	postRequest := func(link string) *http.Request {
+		reqBody, _ := json.Marshal(NewPostRequest{
+			Category: "music",
+			Title:    "News",
+			Type:     "link",
+			Link:     link,
+		})
+		return requestWithSession(httptest.NewRequest("POST", "/api/posts", bytes.NewReader(reqBody)))
+	}
+	adminRequest := func(method string, u *user.User, domain, body string) *http.Request {
+		r := httptest.NewRequest(method, "/api/admin/domains/{DOMAIN}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"DOMAIN": domain})
+	}
+	linkResponse := `{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"url":"https://news.example.com/1","domain":"news.example.com","title":"News","type":"link","upvotePercentage":0,"views":0,"votes":[]}`
+
+	testCases := []TestCase{
+		{ //Link to a blocked domain
+			Request: postRequest("https://www.Spam.com/buy"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockDomainsRepo.EXPECT().Match("spam.com"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{&posts.DomainRule{Domain: "spam.com", Action: posts.DomainBlock, Reason: "Spam"}, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"url","value":"https://www.Spam.com/buy","msg":"links to spam.com are not allowed: Spam"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Link to a flagged domain is reported
+			Request: postRequest("https://news.example.com/1"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockDomainsRepo.EXPECT().Match("news.example.com"),
+				mockPostsRepo.EXPECT().GetByLink("news.example.com/1"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "News", "link", "", "https://news.example.com/1", posts.Flags{}, gomock.Any()),
+				mockReportsRepo.EXPECT().Add(&moderation.Report{
+					Kind:     posts.ItemPost,
+					ItemID:   postID,
+					PostID:   postID,
+					Category: "music",
+					Reporter: automod.Moderator,
+					Reason:   "links to news.example.com are flagged",
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{&posts.DomainRule{Domain: "example.com", Action: posts.DomainFlag}, nil},
+				{[]*posts.Post{}, nil},
+				{linkPost, nil},
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(linkResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Link to an allowed domain
+			Request: postRequest("https://news.example.com/1"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockDomainsRepo.EXPECT().Match("news.example.com"),
+				mockPostsRepo.EXPECT().GetByLink("news.example.com/1"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "News", "link", "", "https://news.example.com/1", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{&posts.DomainRule{Domain: "news.example.com", Action: posts.DomainAllow}, nil},
+				{[]*posts.Post{}, nil},
+				{linkPost, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(linkResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Posts of a domain
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/domain/{DOMAIN}", nil),
+				map[string]string{"DOMAIN": "News.Example.com"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByDomain("news.example.com"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{linkPost}, nil},
+			},
+			HandlerFunc: postsTestHandler.ListByDomain,
+			ExpectResult: Result{
+				Body: []byte(`[` + linkResponse + `]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Domain rule by a user
+			Request:        adminRequest("PUT", testUser, "spam.com", `{"action": "block"}`),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    domainsTestHandler.Set,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Bad domain rule
+			Request:        adminRequest("PUT", testModerator, "www.spam.com", `{"action": "ban"}`),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    domainsTestHandler.Set,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"domain","value":"www.spam.com","msg":"must be a lowercase host name without www."},{"location":"body","param":"action","value":"ban","msg":"must be one of: block, flag, allow"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Domain rule SUCCESS
+			Request: adminRequest("PUT", testModerator, "spam.com", `{"action": "block", "reason": "Spam"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockDomainsRepo.EXPECT().Set(&posts.DomainRule{
+					Domain: "spam.com",
+					Action: posts.DomainBlock,
+					Reason: "Spam",
+					Admin:  testModerator,
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil},
+			},
+			HandlerFunc: domainsTestHandler.Set,
+			ExpectResult: Result{
+				Body: []byte(`{"domain":"spam.com","action":"block","reason":"Spam","admin":{"username":"igor","id":"2"},"created":""}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Delete a missing rule
+			Request: adminRequest("DELETE", testModerator, "spam.com", ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockDomainsRepo.EXPECT().Delete("spam.com"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{false, nil},
+			},
+			HandlerFunc: domainsTestHandler.Delete,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
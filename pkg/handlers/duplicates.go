@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+)
+
+// DuplicateResponse lists the posts of the same link in the category, the
+// user can submit again with "resubmit": true.
+type DuplicateResponse struct {
+	Message string          `json:"message"`
+	Posts   []*PostResponse `json:"posts"`
+}
+
+// DiscussionResponse is a short view of another post of the same link.
+type DiscussionResponse struct {
+	ID       string     `json:"id"`
+	Category string     `json:"category"`
+	Title    string     `json:"title"`
+	Author   *user.User `json:"author"`
+	Score    int        `json:"score"`
+	Comments int        `json:"comments"`
+	Created  string     `json:"created"`
+}
+
+// checkDuplicate answers 409 with the posts of the link which are already in
+// the category and returns false.
+func (h *PostsHandler) checkDuplicate(w http.ResponseWriter, categoryName, link string) bool {
+	normalized := posts.NormalizeLink(link)
+	if normalized == "" {
+		return true
+	}
+	samePosts, err := h.PostsRepo.GetByLink(normalized)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return false
+	}
+	duplicates := make([]*PostResponse, 0)
+	for _, post := range samePosts {
+		if post.Category != categoryName || post.Pending {
+			continue
+		}
+		postResponse, err := PostToPostResponse(post, h.CommentRepo)
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("Post Transform error: %v", err)
+			return false
+		}
+		duplicates = append(duplicates, postResponse)
+	}
+	if len(duplicates) == 0 {
+		return true
+	}
+	ans, _ := json.Marshal(&DuplicateResponse{
+		Message: "This link was already submitted",
+		Posts:   duplicates,
+	})
+	http.Error(w, string(ans), http.StatusConflict)
+	h.Logger.Infof("Duplicate link %v in %v", normalized, categoryName)
+	return false
+}
+
+// otherDiscussions lists the other posts of the same link. They are only an
+// extra to the post, so errors are logged.
+func (h *PostsHandler) otherDiscussions(post *posts.Post) []*DiscussionResponse {
+	if post.NormalizedLink == "" {
+		return nil
+	}
+	samePosts, err := h.PostsRepo.GetByLink(post.NormalizedLink)
+	if err != nil {
+		h.Logger.Errorf("Can't get other discussions: %v", err)
+		return nil
+	}
+	var discussions []*DiscussionResponse
+	for _, other := range samePosts {
+		if other.ID == post.ID || other.Pending {
+			continue
+		}
+		discussions = append(discussions, &DiscussionResponse{
+			ID:       other.ID.Hex(),
+			Category: other.Category,
+			Title:    other.Title,
+			Author:   other.Author,
+			Score:    other.Score,
+			Comments: len(other.CommentsID),
+			Created:  other.Created,
+		})
+	}
+	return discussions
+}
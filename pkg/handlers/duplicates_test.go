@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestDuplicateLinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+	}
+	newLinkPost := func(id, category string) *posts.Post {
+		return &posts.Post{
+			Author:         testUser,
+			Category:       category,
+			CommentsID:     []bson.ObjectId{},
+			Created:        "2020-05-12T22:33:02+03:00",
+			ID:             bson.ObjectIdHex(id),
+			Title:          "Go",
+			Type:           "link",
+			Link:           "https://golang.org/",
+			NormalizedLink: "golang.org",
+			Votes:          []posts.Vote{},
+		}
+	}
+	musicPost := newLinkPost("5ebaf9ee3c04c17c56f51244", "music")
+	funnyPost := newLinkPost("5ebaf9ee3c04c17c56f51245", "funny")
+	postRequest := func(resubmit bool) *http.Request {
+		reqBody, _ := json.Marshal(NewPostRequest{
+			Category: "music",
+			Title:    "Go",
+			Type:     "link",
+			Link:     "http://www.golang.org?utm_source=feed",
+			Resubmit: resubmit,
+		})
+		return requestWithSession(httptest.NewRequest("POST", "/api/posts", bytes.NewReader(reqBody)))
+	}
+	musicResponse := `{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"url":"https://golang.org/","title":"Go","type":"link","upvotePercentage":0,"views":0,"votes":[]`
+
+	testCases := []TestCase{
+		{ //Link already in the category
+			Request: postRequest(false),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("golang.org"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{musicPost, funnyPost}, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"message":"This link was already submitted","posts":[` + musicResponse + `}]}` + "\n"),
+				Code: http.StatusConflict,
+			},
+		},
+		{ //Link in another category only
+			Request: postRequest(false),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().GetByLink("golang.org"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Go", "link", "", "http://www.golang.org?utm_source=feed", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{[]*posts.Post{funnyPost}, nil},
+				{musicPost, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(musicResponse + `}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Resubmit
+			Request: postRequest(true),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Go", "link", "", "http://www.golang.org?utm_source=feed", posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{musicPost, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(musicResponse + `}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Other discussions of the link
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/post/{ID}", nil),
+				map[string]string{"ID": musicPost.ID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(musicPost.ID),
+				mockPostsRepo.EXPECT().GetByLink("golang.org"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{musicPost, nil},
+				{[]*posts.Post{musicPost, funnyPost}, nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte(musicResponse + `,"otherDiscussions":[{"id":"5ebaf9ee3c04c17c56f51245","category":"funny","title":"Go","author":{"username":"rvasily","id":"1"},"score":0,"comments":0,"created":"2020-05-12T22:33:02+03:00"}]}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
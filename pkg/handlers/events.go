@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"reddit/pkg/events"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// EventsOutboxInterface sends the domain events to the subscribers, e.g. the
+// notifications, the search index or the analytics. The events of the posts
+// are staged in the post by the same write as the change, Wake makes the
+// outbox pick them up, Collect moves them out of a post which is about to be
+// removed. A change which can't carry its event holds it in the outbox before
+// the change and releases or drops it after.
+type EventsOutboxInterface interface {
+	Wake()
+	Collect() error
+	Hold(*events.Event) error
+	Release(*events.Event) error
+	Drop(*events.Event) error
+}
+
+// announce stages an event of the type in the changed post, data makes the
+// data of the event of the post. It is nil without events.
+func (h *PostsHandler) announce(eventType string, data func(*posts.Post) (interface{}, error)) posts.Announce {
+	if h.Events == nil {
+		return nil
+	}
+	return func(post *posts.Post) ([]posts.StagedEvent, error) {
+		eventData, err := data(post)
+		if err != nil {
+			return nil, err
+		}
+		event, err := events.New(eventType, eventData)
+		if err != nil {
+			return nil, err
+		}
+		return []posts.StagedEvent{event.Staged()}, nil
+	}
+}
+
+// eventsStaged wakes the outbox after a change staged its events.
+func (h *PostsHandler) eventsStaged() {
+	if h.Events != nil {
+		h.Events.Wake()
+	}
+}
+
+// collectEvents moves the events staged in the posts into the outbox, e.g.
+// before a post is removed. It is nil without events.
+func (h *PostsHandler) collectEvents() func() error {
+	if h.Events == nil {
+		return nil
+	}
+	return h.Events.Collect
+}
+
+// postCreated announces a new post, a pending one once it is approved.
+func (h *PostsHandler) postCreated() posts.Announce {
+	return h.announce(events.PostCreated, func(post *posts.Post) (interface{}, error) {
+		return events.NewPostData(post), nil
+	})
+}
+
+func (h *PostsHandler) voteCast(voter *user.User, vote int) posts.Announce {
+	return h.announce(events.VoteCast, func(post *posts.Post) (interface{}, error) {
+		return &events.VoteData{
+			PostID: post.ID,
+			Author: post.Author,
+			Voter:  voter,
+			Vote:   vote,
+			Score:  post.Score,
+		}, nil
+	})
+}
+
+// commentAdded announces the comment, it is loaded for the event.
+func (h *PostsHandler) commentAdded(commentID bson.ObjectId) posts.Announce {
+	return h.announce(events.CommentAdded, func(post *posts.Post) (interface{}, error) {
+		comment, err := h.CommentRepo.GetByID(commentID)
+		if err != nil {
+			return nil, err
+		}
+		return &events.CommentData{Post: events.NewPostData(post), Comment: comment}, nil
+	})
+}
+
+func (h *PostsHandler) commentDeleted(commentID bson.ObjectId) posts.Announce {
+	return h.announce(events.CommentDeleted, func(post *posts.Post) (interface{}, error) {
+		return &events.DeletedData{PostID: post.ID, Category: post.Category, CommentID: commentID}, nil
+	})
+}
+
+// holdEvent saves the event of a change which can't carry it, e.g. a
+// removal, before the change. It returns nil without events or if the event
+// can't be saved, then the change must not be made.
+func holdEvent(outbox EventsOutboxInterface, eventType string, data interface{}) (*events.Event, error) {
+	if outbox == nil {
+		return nil, nil
+	}
+	event, err := events.New(eventType, data)
+	if err != nil {
+		return nil, err
+	}
+	return event, outbox.Hold(event)
+}
+
+// releaseEvent sends the held event if the change happened and drops it if
+// it didn't. An error of the change leaves the event to the confirmation of
+// the outbox, the change may have happened.
+func releaseEvent(outbox EventsOutboxInterface, event *events.Event, changed bool, changeErr error) error {
+	if event == nil || changeErr != nil {
+		return nil
+	}
+	if !changed {
+		return outbox.Drop(event)
+	}
+	return outbox.Release(event)
+}
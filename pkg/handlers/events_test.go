@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/events"
+	"reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// eventMatcher matches a domain event by its type and data, the id and the
+// time are new for every event.
+type eventMatcher struct {
+	eventType string
+	data      string
+}
+
+func domainEvent(eventType string, data interface{}) gomock.Matcher {
+	raw, _ := json.Marshal(data)
+	return &eventMatcher{eventType: eventType, data: string(raw)}
+}
+
+func (m *eventMatcher) Matches(x interface{}) bool {
+	event, ok := x.(*events.Event)
+	return ok && event.Type == m.eventType && string(event.Data) == m.data
+}
+
+func (m *eventMatcher) String() string {
+	return fmt.Sprintf("is %v event %v", m.eventType, m.data)
+}
+
+// announceMatcher matches the announce of a change of the post by the event
+// it stages.
+type announceMatcher struct {
+	post  *posts.Post
+	event *eventMatcher
+}
+
+func announces(post *posts.Post, eventType string, data interface{}) gomock.Matcher {
+	return &announceMatcher{post: post, event: domainEvent(eventType, data).(*eventMatcher)}
+}
+
+func (m *announceMatcher) Matches(x interface{}) bool {
+	announce, ok := x.(posts.Announce)
+	if !ok || announce == nil {
+		return false
+	}
+	post := *m.post
+	staged, err := announce(&post)
+	return err == nil && len(staged) == 1 &&
+		staged[0].Type == m.event.eventType && staged[0].Data == m.event.data
+}
+
+func (m *announceMatcher) String() string {
+	return fmt.Sprintf("stages %v event %v", m.event.eventType, m.event.data)
+}
+
+func TestDomainEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockUserRepo := NewMockUserRepositoryInterface(ctrl)
+	mockSessionManager := NewMockSessionManagerInterface(ctrl)
+	mockEvents := NewMockEventsOutboxInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Events:       mockEvents,
+	}
+	userTestHandler := &UserHandler{
+		Logger:   zapLogger.Sugar(),
+		UserRepo: mockUserRepo,
+		Sessions: mockSessionManager,
+		Events:   mockEvents,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	commentID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+	post := &posts.Post{
+		Author:   testUser,
+		Category: "music",
+		Created:  "2020-05-12T22:33:02+03:00",
+		ID:       postID,
+		Score:    1,
+		Title:    "News",
+		Type:     "text",
+		Text:     "Hello",
+		Votes:    []posts.Vote{},
+	}
+	postJSON := []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Hello","title":"News","type":"text","upvotePercentage":0,"views":0,"votes":[]}`)
+	postRequest := func(method, body string, vars map[string]string) *http.Request {
+		r := httptest.NewRequest(method, "/api/post/{POST_ID}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithSession(r), vars)
+	}
+	anna := &user.User{ID: 3, Username: "anna"}
+	deleted := &events.DeletedData{PostID: postID, Category: "music"}
+
+	testCases := []TestCase{
+		{ //A new post stages its event
+			Request: postRequest("POST", `{"category": "music", "title": "News", "type": "text", "text": "Hello"}`, nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "News", "text", "Hello", "", posts.Flags{},
+					announces(post, events.PostCreated, events.NewPostData(post))),
+				mockEvents.EXPECT().Wake(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{post, nil},
+				{},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: postJSON,
+				Code: http.StatusOK,
+			},
+		},
+		{ //Upvote
+			Request: postRequest("GET", "", map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().Upvote(testUser, postID, announces(post, events.VoteCast, &events.VoteData{
+					PostID: postID,
+					Author: testUser,
+					Voter:  testUser,
+					Vote:   1,
+					Score:  1,
+				})),
+				mockEvents.EXPECT().Wake(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{},
+			},
+			HandlerFunc: postsTestHandler.Upvote,
+			ExpectResult: Result{
+				Body: postJSON,
+				Code: http.StatusOK,
+			},
+		},
+		{ //Delete comment
+			Request: postRequest("DELETE", "", map[string]string{"POST_ID": postID.Hex(), "COMMENT_ID": commentID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockCommentsRepo.EXPECT().DelComment(commentID),
+				mockPostsRepo.EXPECT().DeleteComment(postID, commentID, announces(post, events.CommentDeleted, &events.DeletedData{
+					PostID:    postID,
+					Category:  "music",
+					CommentID: commentID,
+				})),
+				mockEvents.EXPECT().Wake(),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{true, nil},
+				{post, nil},
+				{},
+			},
+			HandlerFunc: postsTestHandler.DeleteComment,
+			ExpectResult: Result{
+				Body: postJSON,
+				Code: http.StatusOK,
+			},
+		},
+		{ //Delete post, the event is held before the post goes
+			Request: postRequest("DELETE", "", map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockEvents.EXPECT().Hold(domainEvent(events.PostDeleted, deleted)),
+				mockPostsRepo.EXPECT().Delete(postID, gomock.Any()),
+				mockEvents.EXPECT().Release(domainEvent(events.PostDeleted, deleted)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{nil},
+				{true, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Delete,
+			ExpectResult: Result{
+				Body: []byte("{\"message\": \"success\"}"),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Post deleted meanwhile, the event is dropped
+			Request: postRequest("DELETE", "", map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockEvents.EXPECT().Hold(domainEvent(events.PostDeleted, deleted)),
+				mockPostsRepo.EXPECT().Delete(postID, gomock.Any()),
+				mockEvents.EXPECT().Drop(domainEvent(events.PostDeleted, deleted)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{nil},
+				{false, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Delete,
+			ExpectResult: Result{
+				Body: []byte("{\"message\": \"failure\"}"),
+				Code: http.StatusOK,
+			},
+		},
+		{ //No delete without its event
+			Request: postRequest("DELETE", "", map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockEvents.EXPECT().Hold(domainEvent(events.PostDeleted, deleted)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{errors.New("outbox is down")},
+			},
+			HandlerFunc: postsTestHandler.Delete,
+			ExpectResult: Result{
+				Body: []byte("Delete error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Sign up, the event gets the id of the new user
+			Request: httptest.NewRequest("POST", "/api/register", bytes.NewReader([]byte(`{"username": "anna", "password": "lovelove"}`))),
+			ExpectMockFunc: []*gomock.Call{
+				mockEvents.EXPECT().Hold(domainEvent(events.UserRegistered, &events.UserData{User: &user.User{Username: "anna"}})),
+				mockUserRepo.EXPECT().Add("anna", "lovelove"),
+				mockEvents.EXPECT().Release(domainEvent(events.UserRegistered, &events.UserData{User: anna})),
+				mockSessionManager.EXPECT().Create(gomock.Any(), anna),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil},
+				{int64(3), nil},
+				{nil},
+				{int64(1), nil},
+			},
+			HandlerFunc: userTestHandler.SignUp,
+			ExpectResult: Result{
+				Body: []byte(""),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Taken username, the event is dropped
+			Request: httptest.NewRequest("POST", "/api/register", bytes.NewReader([]byte(`{"username": "anna", "password": "lovelove"}`))),
+			ExpectMockFunc: []*gomock.Call{
+				mockEvents.EXPECT().Hold(domainEvent(events.UserRegistered, &events.UserData{User: &user.User{Username: "anna"}})),
+				mockUserRepo.EXPECT().Add("anna", "lovelove"),
+				mockEvents.EXPECT().Drop(domainEvent(events.UserRegistered, &events.UserData{User: &user.User{Username: "anna"}})),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil},
+				{int64(0), user.ErrAlreadyExisting},
+				{nil},
+			},
+			HandlerFunc: userTestHandler.SignUp,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","msg":"already exists","param":"username","value":"anna"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/paging"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+)
+
+// Feed shows the hottest posts from the categories the user is subscribed to.
+// Anonymous users and users without subscriptions get the default categories.
+func (h *PostsHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	categories := category.DefaultCategories
+	sess, err := session.SessionFromContext(r.Context())
+	if err == nil {
+		subscriptions, err := h.SubscriptionRepo.GetByUser(sess.User.ID)
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("DB err: %v", err)
+			return
+		}
+		if len(subscriptions) != 0 {
+			categories = subscriptions
+		}
+	}
+
+	feedPosts, err := h.PostsRepo.GetByCategories(categories)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	feedPosts = h.visiblePosts(r, feedPosts)
+	posts.SortByHot(feedPosts)
+
+	page, limit := pageFromRequest(r)
+	from, to := paging.Bounds(page, limit, len(feedPosts))
+	feed := &PostsPage{
+		Posts: make([]*PostResponse, 0, to-from),
+		Page:  page,
+		Limit: limit,
+		Total: len(feedPosts),
+	}
+	for _, post := range feedPosts[from:to] {
+		postResponse, err := PostToPostResponse(post, h.CommentRepo)
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("Post Transform error: %v", err)
+			return
+		}
+		feed.Posts = append(feed.Posts, postResponse)
+	}
+	markSaved(r, feed.Posts, h.SavedRepo, h.Logger.Errorf)
+
+	resp, _ := json.Marshal(feed)
+	w.Write(resp)
+	h.Logger.Infof("Feed page %v", page)
+}
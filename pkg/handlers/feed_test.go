@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	"reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestFeed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockSubscriptionRepo := NewMockSubscriptionRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	postsTestHandler := &PostsHandler{
+		Logger:           logger,
+		PostsRepo:        mockPostsRepo,
+		CommentRepo:      mockCommentsRepo,
+		SubscriptionRepo: mockSubscriptionRepo,
+	}
+	oldPost := &posts.Post{
+		Author:     testUser,
+		Category:   "news",
+		CommentsID: nil,
+		Created:    "2020-05-01T22:33:02+03:00",
+		ID:         "^\xba\xf9\xee<\x04\xc1|V\xf5\x12F",
+		Score:      1,
+		Title:      "Old",
+		Type:       "text",
+		Votes:      []posts.Vote{},
+	}
+
+	testCases := []TestCase{
+		{ //Anonymous feed
+			Request: httptest.NewRequest("GET", "/api/feed", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByCategories(category.DefaultCategories),
+				mockCommentsRepo.EXPECT().GetByID(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{testPost1}, nil},
+				{testComment, nil},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte(`{"posts":[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[{"id":"5ebaf9f23c04c17c56f51245","author":{"username":"rvasily","id":"1"},"body":"something","created":"2020-05-12T22:33:02+03:00"}],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something 1","title":"Lorem","type":"text","upvotePercentage":100,"views":10,"votes":[{"user":"1","vote":1}]}],"page":1,"limit":25,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Subscribed feed, second page
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/feed?page=2&limit=1", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().GetByUser(testUser.ID),
+				mockPostsRepo.EXPECT().GetByCategories([]string{"music", "news"}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]string{"music", "news"}, nil},
+				{[]*posts.Post{oldPost, testPost1}, nil},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte(`{"posts":[{"author":{"username":"rvasily","id":"1"},"category":"news","comments":[],"created":"2020-05-01T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51246","score":1,"title":"Old","type":"text","upvotePercentage":0,"views":0,"votes":[]}],"page":2,"limit":1,"total":2}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //No subscriptions, page out of range
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/feed?page=3", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().GetByUser(testUser.ID),
+				mockPostsRepo.EXPECT().GetByCategories(category.DefaultCategories),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]string{}, nil},
+				{[]*posts.Post{testPost1}, nil},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte(`{"posts":[],"page":3,"limit":25,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Page too far away to have an offset
+			Request: httptest.NewRequest("GET", "/api/feed?page=9223372036854775807&limit=100", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByCategories(category.DefaultCategories),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{testPost1}, nil},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte(`{"posts":[],"page":9223372036854775807,"limit":100,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Subscription repo error
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/feed", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSubscriptionRepo.EXPECT().GetByUser(testUser.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Post repo error
+			Request: httptest.NewRequest("GET", "/api/feed", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByCategories(category.DefaultCategories),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: postsTestHandler.Feed,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FlagsRequest changes only the fields which are present.
+type FlagsRequest struct {
+	Flair   *string `json:"flair"`
+	NSFW    *bool   `json:"nsfw"`
+	Spoiler *bool   `json:"spoiler"`
+}
+
+// UpdateFlags sets flair, NSFW and spoiler marks of a post. The author of
+// the post and the moderator of its category can do it.
+func (h *PostsHandler) UpdateFlags(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+
+	flagsRequest := new(FlagsRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, flagsRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	cat, err := h.CategoryRepo.GetByName(post.Category)
+	if err != nil && err != category.ErrNoCategory {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	isAuthor := post.Author != nil && post.Author.ID == sess.User.ID
+	if !isAuthor && !isModerator(cat, sess.User, h.Admins) {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("%v can't change flags of post %v", sess.User.Username, postID)
+		return
+	}
+
+	if flagsRequest.Flair != nil {
+		if !h.checkFlair(w, cat, *flagsRequest.Flair) {
+			return
+		}
+		post.Flair = *flagsRequest.Flair
+	}
+	if flagsRequest.NSFW != nil {
+		post.NSFW = *flagsRequest.NSFW
+	}
+	if flagsRequest.Spoiler != nil {
+		post.Spoiler = *flagsRequest.Spoiler
+	}
+	err = h.PostsRepo.SetFlags(postID, post.Flair, post.NSFW, post.Spoiler)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+
+	postResponse, err := PostToPostResponse(post, h.CommentRepo)
+	if err != nil {
+		http.Error(w, `Internal error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Post Transform error: %v", err)
+		return
+	}
+	h.indexPost(post, postResponse.Comments)
+	resp, _ := json.Marshal(postResponse)
+	w.Write(resp)
+	h.Logger.Infof("Flags of post %v were updated", postID)
+}
+
+// checkFlair answers with an error and returns false if the category has no
+// such flair. An empty flair is always fine.
+func (h *PostsHandler) checkFlair(w http.ResponseWriter, cat *category.Category, flair string) bool {
+	if flair == "" || (cat != nil && cat.HasFlair(flair)) {
+		return true
+	}
+	writeFieldError(w, "flair", flair, "unknown flair")
+	if cat != nil {
+		h.Logger.Errorf("Unknown flair %v in %v", flair, cat.Name)
+	}
+	return false
+}
+
+// visiblePosts applies the ?flair= filter of listings, hides posts waiting
+// for approval and hides NSFW posts from anonymous users and users who
+// haven't opted in.
+func (h *PostsHandler) visiblePosts(r *http.Request, list []*posts.Post) []*posts.Post {
+	return filterPosts(r, list, h.Preferences, h.Logger.Errorf)
+}
+
+func filterPosts(
+	r *http.Request,
+	list []*posts.Post,
+	prefsRepo PreferencesRepositoryInterface,
+	logError func(string, ...interface{})) []*posts.Post {
+	flair := r.URL.Query().Get("flair")
+	hasNSFW := false
+	for _, post := range list {
+		if post.NSFW {
+			hasNSFW = true
+			break
+		}
+	}
+	show := false
+	if hasNSFW {
+		var err error
+		show, err = showNSFW(r, prefsRepo)
+		if err != nil {
+			// better to hide too much than to fail the whole listing
+			logError("Can't get preferences: %v", err)
+		}
+	}
+
+	visible := make([]*posts.Post, 0, len(list))
+	for _, post := range list {
+		if post.Pending {
+			continue
+		}
+		if flair != "" && post.Flair != flair {
+			continue
+		}
+		if post.NSFW && !show {
+			continue
+		}
+		visible = append(visible, post)
+	}
+	return visible
+}
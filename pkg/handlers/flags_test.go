@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	posts "reddit/pkg/posts"
+	"reddit/pkg/session"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testModerator = &user.User{
+	ID:       2,
+	Username: "igor",
+}
+
+var testFlairCategory = &category.Category{
+	Name:        "music",
+	Owner:       testModerator,
+	Description: "Music",
+	Rules:       []string{},
+	Created:     "2020-05-12T22:33:02+03:00",
+	Flairs:      []category.Flair{{Name: "discussion", Color: "#ff0000"}},
+}
+
+func newFlaggedPost(flair string, nsfw bool) *posts.Post {
+	return &posts.Post{
+		Author:           testUser,
+		Category:         "music",
+		CommentsID:       []bson.ObjectId{},
+		Created:          "2020-05-12T22:33:02+03:00",
+		ID:               "^\xba\xf9\xee<\x04\xc1|V\xf5\x12D",
+		Score:            1,
+		Text:             "Something",
+		Title:            "Lorem",
+		Type:             "text",
+		UpvotePercentage: 100,
+		Votes:            []posts.Vote{{UserID: 1, Rating: 1}},
+		Flair:            flair,
+		NSFW:             nsfw,
+	}
+}
+
+func requestWithUser(r *http.Request, u *user.User) *http.Request {
+	ctx := context.WithValue(r.Context(), session.SessionKey, &session.Session{ID: 1, User: u})
+	return r.WithContext(ctx)
+}
+
+func TestUpdateFlags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Admins:       map[string]bool{"admin": true},
+	}
+	flagsRequest := func(u *user.User, body string) *http.Request {
+		r := httptest.NewRequest("PATCH", "/api/post/{POST_ID}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"POST_ID": "5ebaf9ee3c04c17c56f51244"})
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	stranger := &user.User{ID: 3, Username: "stranger"}
+
+	testCases := []TestCase{
+		{ //Author marks the post NSFW
+			Request: flagsRequest(testUser, `{"nsfw": true}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetFlags(postID, "", true, false),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("", false), nil},
+				{testFlairCategory, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"nsfw":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Moderator sets flair and spoiler, NSFW stays
+			Request: flagsRequest(testModerator, `{"flair": "discussion", "spoiler": true}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetFlags(postID, "discussion", true, true),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("", true), nil},
+				{testFlairCategory, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"flair":"discussion","nsfw":true,"spoiler":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Somebody else
+			Request: flagsRequest(stranger, `{"nsfw": false}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("", true), nil},
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Admin moderates a default category, which has no owner
+			Request: flagsRequest(&user.User{ID: 4, Username: "admin"}, `{"nsfw": false}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetFlags(postID, "", false, false),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("", true), nil},
+				{&category.Category{Name: "music"}, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}]}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unknown flair
+			Request: flagsRequest(testUser, `{"flair": "memes"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("", false), nil},
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"flair","value":"memes","msg":"unknown flair"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Repo error
+			Request: flagsRequest(testUser, `{"flair": ""}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetFlags(postID, "", false, false),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newFlaggedPost("discussion", false), nil},
+				{testFlairCategory, nil},
+				{fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: postsTestHandler.UpdateFlags,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+	}
+	addRequest := func(body string) *http.Request {
+		return requestWithSession(httptest.NewRequest("POST", "/api/posts", bytes.NewReader([]byte(body))))
+	}
+	flagged := newFlaggedPost("discussion", true)
+	flagged.Spoiler = true
+
+	testCases := []TestCase{
+		{ //Post is NSFW from the start
+			Request: addRequest(`{"category": "music", "type": "text", "title": "Lorem", "text": "Something", "flair": "discussion", "nsfw": true, "spoiler": true}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().Add(testUser, "music", "Lorem", "text", "Something", "", posts.Flags{
+					Flair:   "discussion",
+					NSFW:    true,
+					Spoiler: true,
+				}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{flagged, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"flair":"discussion","nsfw":true,"spoiler":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unknown flair
+			Request: addRequest(`{"category": "music", "type": "text", "title": "Lorem", "text": "Something", "flair": "memes"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"flair","value":"memes","msg":"unknown flair"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestListingFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockPreferences := NewMockPreferencesRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:      zapLogger.Sugar(),
+		PostsRepo:   mockPostsRepo,
+		CommentRepo: mockCommentsRepo,
+		Preferences: mockPreferences,
+	}
+	listing := func() []*posts.Post {
+		safe := newFlaggedPost("discussion", false)
+		nsfw := newFlaggedPost("discussion", true)
+		nsfw.Title = "NSFW"
+		other := newFlaggedPost("news", false)
+		other.Title = "Other flair"
+		return []*posts.Post{safe, nsfw, other}
+	}
+	titles := func(r *http.Request) []string {
+		w := httptest.NewRecorder()
+		postsTestHandler.ListCategory(w, mux.SetURLVars(r, map[string]string{"CATEGORY": "music"}))
+		body, _ := ioutil.ReadAll(w.Result().Body)
+		result := []string{}
+		for _, title := range []string{"Lorem", "NSFW", "Other flair"} {
+			if bytes.Contains(body, []byte(`"title":"`+title+`"`)) {
+				result = append(result, title)
+			}
+		}
+		return result
+	}
+
+	//Anonymous users never see NSFW posts
+	mockPostsRepo.EXPECT().GetCategory("music").Return(listing(), nil)
+	assert.Equal(t, []string{"Lorem", "Other flair"}, titles(httptest.NewRequest("GET", "/api/posts/music", nil)))
+
+	//Users who haven't opted in
+	mockPostsRepo.EXPECT().GetCategory("music").Return(listing(), nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{}, nil)
+	assert.Equal(t, []string{"Lorem", "Other flair"}, titles(requestWithSession(httptest.NewRequest("GET", "/api/posts/music", nil))))
+
+	//Opted in user filters by flair
+	mockPostsRepo.EXPECT().GetCategory("music").Return(listing(), nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{ShowNSFW: true}, nil)
+	assert.Equal(t, []string{"Lorem", "NSFW"}, titles(requestWithSession(httptest.NewRequest("GET", "/api/posts/music?flair=discussion", nil))))
+
+	//Preferences are unavailable
+	mockPostsRepo.EXPECT().GetCategory("music").Return(listing(), nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(nil, fmt.Errorf("Internal error"))
+	assert.Equal(t, []string{"Other flair"}, titles(requestWithSession(httptest.NewRequest("GET", "/api/posts/music?flair=news", nil))))
+
+	//The post page of an NSFW post follows the same rule
+	single := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		postsTestHandler.ListByID(w, mux.SetURLVars(r, map[string]string{"ID": "5ebaf9ee3c04c17c56f51244"}))
+		return w.Result().StatusCode
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	mockPostsRepo.EXPECT().GetByID(postID).Return(newFlaggedPost("", true), nil)
+	assert.Equal(t, http.StatusNotFound, single(httptest.NewRequest("GET", "/api/post/5ebaf9ee3c04c17c56f51244", nil)))
+
+	mockPostsRepo.EXPECT().GetByID(postID).Return(newFlaggedPost("", true), nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{ShowNSFW: true}, nil)
+	assert.Equal(t, http.StatusOK, single(requestWithSession(httptest.NewRequest("GET", "/api/post/5ebaf9ee3c04c17c56f51244", nil))))
+}
+
+func TestSetFlairs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	categoryTestHandler := &CategoryHandler{
+		Logger:       zapLogger.Sugar(),
+		CategoryRepo: mockCategoryRepo,
+	}
+	flairsRequest := func(u *user.User, body string) *http.Request {
+		r := httptest.NewRequest("PUT", "/api/categories/{CATEGORY}/flairs", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"CATEGORY": "music"})
+	}
+	flairs := []category.Flair{{Name: "discussion", Color: "#ff0000"}}
+
+	testCases := []TestCase{
+		{ //SUCCESS
+			Request: flairsRequest(testModerator, `[{"name": "discussion", "color": "#ff0000"}]`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockCategoryRepo.EXPECT().SetFlairs("music", flairs),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&category.Category{Name: "music", Owner: testModerator, Rules: []string{}}, nil},
+				{nil},
+			},
+			HandlerFunc: categoryTestHandler.SetFlairs,
+			ExpectResult: Result{
+				Body: []byte(`{"name":"music","owner":{"username":"igor","id":"2"},"description":"","rules":[],"created":"","flairs":[{"name":"discussion","color":"#ff0000"}]}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Not a moderator
+			Request: flairsRequest(testUser, `[]`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: categoryTestHandler.SetFlairs,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Bad flairs
+			Request: flairsRequest(testModerator, `[{"name": "a", "color": "red"}, {"name": "a"}]`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: categoryTestHandler.SetFlairs,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"flairs[0].color","value":"red","msg":"must look like #a1b2c3"},{"location":"body","param":"flairs[1].name","value":"a","msg":"duplicates another flair"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPreferences := NewMockPreferencesRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	userTestHandler := &UserHandler{
+		Logger:          zapLogger.Sugar(),
+		PreferencesRepo: mockPreferences,
+	}
+
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{}, nil)
+	w := httptest.NewRecorder()
+	userTestHandler.Preferences(w, requestWithSession(httptest.NewRequest("GET", "/api/me/preferences", nil)))
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Equal(t, `{"showNSFW":false}`, string(body))
+
+	mockPreferences.EXPECT().Set(int64(1), &user.Preferences{ShowNSFW: true}).Return(nil)
+	w = httptest.NewRecorder()
+	userTestHandler.SetPreferences(w, requestWithSession(httptest.NewRequest("PUT", "/api/me/preferences",
+		bytes.NewReader([]byte(`{"showNSFW": true}`)))))
+	body, _ = ioutil.ReadAll(w.Result().Body)
+	assert.Equal(t, `{"showNSFW":true}`, string(body))
+
+	w = httptest.NewRecorder()
+	userTestHandler.Preferences(w, httptest.NewRequest("GET", "/api/me/preferences", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
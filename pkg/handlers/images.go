@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/automod"
+	"reddit/pkg/media"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+)
+
+const (
+	// form fields besides the file are tiny, the rest is the image itself
+	maxUploadBody      = posts.MaxImageSize + 1<<20
+	maxMultipartMemory = 1 << 20
+)
+
+// addImage creates an image post from a multipart form with category,
+// title and image fields and optional flair, nsfw and spoiler fields.
+func (h *PostsHandler) addImage(w http.ResponseWriter, r *http.Request, sess *session.Session) {
+	if h.Images == nil {
+		writeFieldError(w, "type", "image", "image posts are disabled")
+		h.Logger.Errorf("Image upload without image store")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBody)
+	err := r.ParseMultipartForm(maxMultipartMemory)
+	if err != nil {
+		http.Error(w, "Bad form", http.StatusBadRequest)
+		h.Logger.Errorf("Bad multipart form. Error: %v", err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	categoryName := r.FormValue("category")
+	title := r.FormValue("title")
+	var imageSize int64
+	file, header, err := r.FormFile("image")
+	if err == nil {
+		defer file.Close()
+		imageSize = header.Size
+	}
+	validationErrors := posts.ValidateImagePost(categoryName, title, imageSize)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad new image post: %v", validationErrors)
+		return
+	}
+	cat, ok := h.checkCategory(w, categoryName, sess.User)
+	if !ok {
+		return
+	}
+	flags := posts.Flags{
+		Flair:   r.FormValue("flair"),
+		NSFW:    r.FormValue("nsfw") == "true",
+		Spoiler: r.FormValue("spoiler") == "true",
+	}
+	if !h.checkFlair(w, cat, flags.Flair) {
+		return
+	}
+	decision, ok := h.automodCheck(w, &automod.Submission{
+		Kind:     posts.ItemPost,
+		Author:   sess.User,
+		Category: categoryName,
+		Title:    title,
+	})
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, posts.MaxImageSize+1))
+	if err != nil {
+		http.Error(w, "Bad form", http.StatusBadRequest)
+		h.Logger.Errorf("Can't read image. Error: %v", err)
+		return
+	}
+	image, err := h.Images.Save(data)
+	if media.IsBadImage(err) {
+		writeFieldError(w, "image", "", imageErrorMessage(err))
+		h.Logger.Errorf("Bad image: %v", err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Storage error", http.StatusInternalServerError)
+		h.Logger.Errorf("Can't save image. Error: %v", err)
+		return
+	}
+
+	announce := h.postCreated()
+	if needsApproval(decision) {
+		// announced once a moderator approves it
+		announce = nil
+	}
+	newPost, err := h.PostsRepo.AddImage(sess.User, categoryName, title, image, flags, announce)
+	if err != nil {
+		h.deleteImage(image)
+		http.Error(w, "BD error", http.StatusInternalServerError)
+		h.Logger.Errorf("Bad add image post. Error: %v", err)
+		return
+	}
+	h.applyAutomod(decision, posts.ItemPost, newPost.ID, newPost)
+	h.eventsStaged()
+
+	postResponse, err := PostToPostResponse(newPost, h.CommentRepo)
+	if err != nil {
+		http.Error(w, `BD error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Post Transform error: %v", err)
+		return
+	}
+	h.indexPost(newPost, postResponse.Comments)
+
+	answer, _ := json.Marshal(postResponse)
+	w.Write(answer)
+	h.Logger.Infof("New image post was created with ID: %v", newPost.ID)
+}
+
+func imageErrorMessage(err error) string {
+	switch err {
+	case media.ErrTooLarge:
+		return "is too large"
+	case media.ErrTooManyPixels:
+		return "has too many pixels"
+	case media.ErrUnsupportedType:
+		return "must be a JPEG, PNG or GIF image"
+	}
+	return "is not a valid image"
+}
+
+func (h *PostsHandler) deleteImage(image *posts.Image) {
+	err := h.Images.Delete(image)
+	if err != nil {
+		h.Logger.Errorf("Can't delete image %v: %v", image.Key, err)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/media"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testImage = &posts.Image{
+	URL:          "/media/5ebaf9ee3c04c17c56f51244.png",
+	Thumbnail:    "/media/5ebaf9ee3c04c17c56f51244_thumb.png",
+	Width:        640,
+	Height:       480,
+	ContentType:  "image/png",
+	Key:          "5ebaf9ee3c04c17c56f51244.png",
+	ThumbnailKey: "5ebaf9ee3c04c17c56f51244_thumb.png",
+}
+
+var testImagePost = &posts.Post{
+	Author:           testUser,
+	Category:         "music",
+	CommentsID:       []bson.ObjectId{},
+	Created:          "2020-05-12T22:33:02+03:00",
+	ID:               "^\xba\xf9\xee<\x04\xc1|V\xf5\x12D",
+	Score:            1,
+	Title:            "Lorem",
+	Type:             "image",
+	UpvotePercentage: 100,
+	Votes:            []posts.Vote{{UserID: 1, Rating: 1}},
+	Image:            testImage,
+}
+
+func imageRequest(category, title string, image []byte) *http.Request {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	form.WriteField("category", category)
+	form.WriteField("title", title)
+	if image != nil {
+		file, _ := form.CreateFormFile("image", "cat.png")
+		file.Write(image)
+	}
+	form.Close()
+	r := httptest.NewRequest("POST", "/api/posts", body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return requestWithSession(r)
+}
+
+func TestAddImage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockImages := NewMockImageStoreInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Images:       mockImages,
+	}
+	imageData := []byte("\x89PNG\r\n\x1a\n...")
+
+	testCases := []TestCase{
+		{ //Image post SUCCESS
+			Request: imageRequest("music", "Lorem", imageData),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockImages.EXPECT().Save(imageData),
+				mockPostsRepo.EXPECT().AddImage(testUser, "music", "Lorem", testImage, posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{testImage, nil},
+				{testImagePost, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"title":"Lorem","type":"image","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"image":{"url":"/media/5ebaf9ee3c04c17c56f51244.png","thumbnail":"/media/5ebaf9ee3c04c17c56f51244_thumb.png","width":640,"height":480,"contentType":"image/png"}}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //No file and no title
+			Request:     imageRequest("music", "", nil),
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"title","value":"","msg":"is required"},{"location":"body","param":"image","value":"","msg":"is required"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Not an image
+			Request: imageRequest("music", "Lorem", []byte("<html></html>")),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockImages.EXPECT().Save([]byte("<html></html>")),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{nil, media.ErrUnsupportedType},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"image","value":"","msg":"must be a JPEG, PNG or GIF image"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Storage error
+			Request: imageRequest("music", "Lorem", imageData),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockImages.EXPECT().Save(imageData),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{nil, fmt.Errorf("disk full")},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte("Storage error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //BD error. Stored files are removed
+			Request: imageRequest("music", "Lorem", imageData),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockImages.EXPECT().Save(imageData),
+				mockPostsRepo.EXPECT().AddImage(testUser, "music", "Lorem", testImage, posts.Flags{}, gomock.Any()),
+				mockImages.EXPECT().Delete(testImage),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{testImage, nil},
+				{nil, fmt.Errorf("Internal error")},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte("BD error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestDeleteImagePost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockImages := NewMockImageStoreInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:    zapLogger.Sugar(),
+		PostsRepo: mockPostsRepo,
+		Images:    mockImages,
+	}
+
+	mockPostsRepo.EXPECT().GetByID(testImagePost.ID).Return(testImagePost, nil)
+	mockPostsRepo.EXPECT().Delete(testImagePost.ID, gomock.Any()).Return(true, nil)
+	mockImages.EXPECT().Delete(testImage).Return(nil)
+
+	r := requestWithSession(httptest.NewRequest("DELETE", "/api/post/5ebaf9ee3c04c17c56f51244", nil))
+	r = mux.SetURLVars(r, map[string]string{"POST_ID": "5ebaf9ee3c04c17c56f51244"})
+	w := httptest.NewRecorder()
+	postsTestHandler.Delete(w, r)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Equal(t, "{\"message\": \"success\"}", string(body))
+}
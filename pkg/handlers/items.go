@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"reddit/pkg/posts"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// itemFromURL reads the post ID and the optional comment ID from the URL and
+// tells which of them the request is about.
+func (h *PostsHandler) itemFromURL(w http.ResponseWriter, r *http.Request) (string, bson.ObjectId, bson.ObjectId, bool) {
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return "", "", "", false
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+	kind, itemID := posts.ItemPost, postID
+	if commentHex, ok := arg["COMMENT_ID"]; ok {
+		if !bson.IsObjectIdHex(commentHex) {
+			http.Error(w, "Bad id", http.StatusBadRequest)
+			h.Logger.Errorf("Bad comment id")
+			return "", "", "", false
+		}
+		kind, itemID = posts.ItemComment, bson.ObjectIdHex(commentHex)
+	}
+	return kind, itemID, postID, true
+}
+
+// findItem loads the post and checks that the comment belongs to it. It
+// answers with an error and returns nil if there is no such item.
+func (h *PostsHandler) findItem(w http.ResponseWriter, kind string, itemID, postID bson.ObjectId) *posts.Post {
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	if kind == posts.ItemComment {
+		found := false
+		for _, commentID := range post.CommentsID {
+			if commentID == itemID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, "No comment", http.StatusNotFound)
+			return nil
+		}
+	}
+	return post
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/live"
+	"reddit/pkg/posts"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// LiveInterface pushes the changes to the clients which watch a post, a
+// category or the events of their user.
+type LiveInterface interface {
+	Subscribe(string) *live.Subscription
+	Publish(*live.Event, ...string)
+}
+
+const (
+	LiveMessage        = "message"
+	LiveComment        = "comment"
+	LiveScore          = "score"
+	LiveCommentDeleted = "comment_deleted"
+	LivePostDeleted    = "post_deleted"
+)
+
+// sseHeartbeat is how often an idle stream gets a comment line, so proxies
+// don't close it.
+var sseHeartbeat = 15 * time.Second
+
+type LiveCommentData struct {
+	Post    bson.ObjectId  `json:"post"`
+	Comment *posts.Comment `json:"comment"`
+}
+
+type LiveScoreData struct {
+	Post             bson.ObjectId `json:"post"`
+	Score            int           `json:"score"`
+	UpvotePercentage int           `json:"upvotePercentage"`
+}
+
+type LiveDeleteData struct {
+	Post    bson.ObjectId `json:"post"`
+	Comment bson.ObjectId `json:"comment,omitempty"`
+}
+
+// publish sends the event to the watchers of the post, of its category and
+// to the other topics. Pending posts are seen by nobody yet, NSFW posts only
+// by the category watchers who opted in to them.
+func (h *PostsHandler) publish(post *posts.Post, eventType string, data interface{}, topics ...string) {
+	if h.Live == nil || post == nil || post.Pending {
+		return
+	}
+	topics = append(topics, live.PostTopic(post.ID), live.NSFWCategoryTopic(post.Category))
+	if !post.NSFW {
+		topics = append(topics, live.CategoryTopic(post.Category))
+	}
+	h.Live.Publish(&live.Event{Type: eventType, Data: data}, topics...)
+}
+
+// publishScore tells the author about the votes on the post too.
+func (h *PostsHandler) publishScore(post *posts.Post) {
+	h.publish(post, LiveScore, &LiveScoreData{
+		Post:             post.ID,
+		Score:            post.Score,
+		UpvotePercentage: post.UpvotePercentage,
+	}, live.UserTopic(post.Author.ID))
+}
+
+// PostEvents streams the changes of the post as Server-Sent Events.
+func (h *PostsHandler) PostEvents(w http.ResponseWriter, r *http.Request) {
+	if !bson.IsObjectIdHex(mux.Vars(r)["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return
+	}
+	postID := bson.ObjectIdHex(mux.Vars(r)["POST_ID"])
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if h.hidden(r, post) {
+		http.Error(w, "No post", http.StatusNotFound)
+		return
+	}
+	h.stream(w, r, live.PostTopic(postID))
+}
+
+// CategoryEvents streams the changes of all the posts of the category, of
+// the NSFW ones only for the users who opted in.
+func (h *PostsHandler) CategoryEvents(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["CATEGORY"]
+	_, err := h.CategoryRepo.GetByName(name)
+	if err == category.ErrNoCategory {
+		http.Error(w, "No category", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	show, err := showNSFW(r, h.Preferences)
+	if err != nil {
+		// better to hide too much than to fail the stream
+		h.Logger.Errorf("Can't get preferences: %v", err)
+	}
+	if show {
+		h.stream(w, r, live.NSFWCategoryTopic(name))
+		return
+	}
+	h.stream(w, r, live.CategoryTopic(name))
+}
+
+// stream writes the events of the topic until the client goes away or is
+// evicted for reading too slowly. An evicted client reconnects by itself and
+// re-fetches what it missed.
+func (h *PostsHandler) stream(w http.ResponseWriter, r *http.Request, topic string) {
+	flusher, ok := w.(http.Flusher)
+	if h.Live == nil || !ok {
+		http.Error(w, "Streaming unsupported", http.StatusNotImplemented)
+		return
+	}
+	sub := h.Live.Subscribe(topic)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-sub.Events:
+			if !ok {
+				h.Logger.Infof("Slow client of %v evicted", topic)
+				return
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				h.Logger.Errorf("Bad live event: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
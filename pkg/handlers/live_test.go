@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	"reddit/pkg/live"
+	"reddit/pkg/middleware"
+	posts "reddit/pkg/posts"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// readEvent reads the lines of the next event of the stream, the heartbeats
+// are counted and skipped.
+func readEvent(t *testing.T, reader *bufio.Reader, heartbeats *int) []string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Can't read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(lines) != 0:
+			return lines
+		case line == ": ping":
+			*heartbeats++
+		case line != "":
+			lines = append(lines, line)
+		}
+	}
+}
+
+func waitSubscribers(t *testing.T, hub *live.Hub, topic string, n int) {
+	for i := 0; i < 100; i++ {
+		if hub.Subscribers(topic) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%v has %d subscribers, want %d", topic, hub.Subscribers(topic), n)
+}
+
+func TestLiveEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(heartbeat time.Duration) { sseHeartbeat = heartbeat }(sseHeartbeat)
+	sseHeartbeat = 20 * time.Millisecond
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Live:         hub,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	post := &posts.Post{
+		Author:     testUser,
+		Category:   "music",
+		CommentsID: []bson.ObjectId{},
+		ID:         postID,
+		Title:      "News",
+		Type:       "text",
+		Votes:      []posts.Vote{},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/post/{POST_ID}/events", postsTestHandler.PostEvents)
+	router.HandleFunc("/api/categories/{CATEGORY}/events", postsTestHandler.CategoryEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// streams of missing posts and categories
+	mockPostsRepo.EXPECT().GetByID(postID).Return(nil, mgo.ErrNotFound)
+	resp, err := http.Get(server.URL + "/api/post/" + postID.Hex() + "/events")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+	mockCategoryRepo.EXPECT().GetByName("nothing").Return(nil, category.ErrNoCategory)
+	resp, err = http.Get(server.URL + "/api/categories/nothing/events")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// one client watches the post, another one the category
+	mockPostsRepo.EXPECT().GetByID(postID).Return(post, nil)
+	postResp, err := http.Get(server.URL + "/api/post/" + postID.Hex() + "/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer postResp.Body.Close()
+	assert.Equal(t, "text/event-stream", postResp.Header.Get("Content-Type"))
+	mockCategoryRepo.EXPECT().GetByName("music").Return(testCategory, nil)
+	categoryResp, err := http.Get(server.URL + "/api/categories/music/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	postEvents := bufio.NewReader(postResp.Body)
+	categoryEvents := bufio.NewReader(categoryResp.Body)
+	heartbeats := 0
+	assert.Equal(t, []string{"retry: 3000"}, readEvent(t, postEvents, &heartbeats))
+	assert.Equal(t, []string{"retry: 3000"}, readEvent(t, categoryEvents, &heartbeats))
+
+	// a vote is pushed to both
+	votedPost := *post
+	votedPost.Score = 1
+	votedPost.UpvotePercentage = 100
+	mockPostsRepo.EXPECT().Upvote(testUser, postID, gomock.Any()).Return(&votedPost, nil)
+	upvoteRequest := httptest.NewRequest("GET", "/api/post/{POST_ID}/upvote", nil)
+	upvoteRequest = mux.SetURLVars(requestWithSession(upvoteRequest), map[string]string{"POST_ID": postID.Hex()})
+	postsTestHandler.Upvote(httptest.NewRecorder(), upvoteRequest)
+	scoreEvent := []string{
+		"id: 1",
+		"event: score",
+		`data: {"post":"5ebaf9ee3c04c17c56f51244","score":1,"upvotePercentage":100}`,
+	}
+	assert.Equal(t, scoreEvent, readEvent(t, postEvents, &heartbeats))
+	assert.Equal(t, scoreEvent, readEvent(t, categoryEvents, &heartbeats))
+
+	// the category client leaves and the post is deleted meanwhile
+	categoryResp.Body.Close()
+	waitSubscribers(t, hub, live.CategoryTopic("music"), 0)
+	mockPostsRepo.EXPECT().GetByID(postID).Return(post, nil)
+	mockPostsRepo.EXPECT().Delete(postID, gomock.Any()).Return(true, nil)
+	deleteRequest := httptest.NewRequest("DELETE", "/api/post/{POST_ID}", nil)
+	deleteRequest = mux.SetURLVars(requestWithSession(deleteRequest), map[string]string{"POST_ID": postID.Hex()})
+	postsTestHandler.Delete(httptest.NewRecorder(), deleteRequest)
+	assert.Equal(t, []string{
+		"id: 2",
+		"event: post_deleted",
+		`data: {"post":"5ebaf9ee3c04c17c56f51244"}`,
+	}, readEvent(t, postEvents, &heartbeats))
+
+	// idle streams get heartbeats
+	heartbeats = 0
+	for heartbeats == 0 {
+		line, err := postEvents.ReadString('\n')
+		if !assert.Nil(t, err) {
+			return
+		}
+		if line == ": ping\n" {
+			heartbeats++
+		}
+	}
+	postResp.Body.Close()
+	waitSubscribers(t, hub, live.PostTopic(postID), 0)
+}
+
+func TestLiveEventsNSFW(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockPreferences := NewMockPreferencesRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CategoryRepo: mockCategoryRepo,
+		Preferences:  mockPreferences,
+		Live:         hub,
+	}
+	nsfwPost := &posts.Post{
+		Author:   testUser,
+		Category: "music",
+		ID:       bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244"),
+		NSFW:     true,
+		Votes:    []posts.Vote{},
+	}
+	post := &posts.Post{
+		Author:   testUser,
+		Category: "music",
+		ID:       bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245"),
+		Votes:    []posts.Vote{},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/post/{POST_ID}/events", postsTestHandler.PostEvents)
+	router.HandleFunc("/api/categories/{CATEGORY}/events", postsTestHandler.CategoryEvents)
+	anonymous := httptest.NewServer(router)
+	defer anonymous.Close()
+	loggedIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, requestWithSession(r))
+	}))
+	defer loggedIn.Close()
+
+	// the NSFW post can't be watched without the opt-in
+	mockPostsRepo.EXPECT().GetByID(nsfwPost.ID).Return(nsfwPost, nil)
+	resp, err := http.Get(anonymous.URL + "/api/post/" + nsfwPost.ID.Hex() + "/events")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+	mockPostsRepo.EXPECT().GetByID(nsfwPost.ID).Return(nsfwPost, nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{}, nil)
+	resp, err = http.Get(loggedIn.URL + "/api/post/" + nsfwPost.ID.Hex() + "/events")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+	mockPostsRepo.EXPECT().GetByID(nsfwPost.ID).Return(nsfwPost, nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{ShowNSFW: true}, nil)
+	resp, err = http.Get(loggedIn.URL + "/api/post/" + nsfwPost.ID.Hex() + "/events")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// an anonymous client and an opted-in one watch the category
+	mockCategoryRepo.EXPECT().GetByName("music").Return(testCategory, nil)
+	anonymousResp, err := http.Get(anonymous.URL + "/api/categories/music/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer anonymousResp.Body.Close()
+	mockCategoryRepo.EXPECT().GetByName("music").Return(testCategory, nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{ShowNSFW: true}, nil)
+	optedInResp, err := http.Get(loggedIn.URL + "/api/categories/music/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer optedInResp.Body.Close()
+	anonymousEvents := bufio.NewReader(anonymousResp.Body)
+	optedInEvents := bufio.NewReader(optedInResp.Body)
+	heartbeats := 0
+	assert.Equal(t, []string{"retry: 3000"}, readEvent(t, anonymousEvents, &heartbeats))
+	assert.Equal(t, []string{"retry: 3000"}, readEvent(t, optedInEvents, &heartbeats))
+
+	// the vote on the NSFW post reaches only the opted-in client, the vote on
+	// the other post both
+	postsTestHandler.publishScore(nsfwPost)
+	postsTestHandler.publishScore(post)
+	assert.Equal(t, []string{
+		"id: 1",
+		"event: score",
+		`data: {"post":"5ebaf9ee3c04c17c56f51244","score":0,"upvotePercentage":0}`,
+	}, readEvent(t, optedInEvents, &heartbeats))
+	scoreEvent := []string{
+		"id: 2",
+		"event: score",
+		`data: {"post":"5ebaf9ee3c04c17c56f51245","score":0,"upvotePercentage":0}`,
+	}
+	assert.Equal(t, scoreEvent, readEvent(t, optedInEvents, &heartbeats))
+	assert.Equal(t, scoreEvent, readEvent(t, anonymousEvents, &heartbeats))
+}
+
+// TestLiveEventsAuth goes through the auth middleware, the stream of an
+// opted-in user gets the NSFW events only when the token is picked up from
+// the query, as EventSource can't send headers.
+func TestLiveEventsAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockPreferences := NewMockPreferencesRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		CategoryRepo: mockCategoryRepo,
+		Preferences:  mockPreferences,
+		Live:         hub,
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/categories/{CATEGORY}/events", postsTestHandler.CategoryEvents)
+	server := httptest.NewServer(middleware.Auth(session.NewSessionsMem(db), router, nil))
+	defer server.Close()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user":      map[string]string{"username": "rvasily", "id": "1"},
+		"sessionId": 7,
+	}).SignedString(session.ExampleTokenSecret)
+	if !assert.Nil(t, err) {
+		return
+	}
+	mock.
+		ExpectQuery("SELECT `user_id`, `exp_time` FROM sessions WHERE").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "exp_time"}).AddRow(1, time.Now().Add(time.Hour).Unix()))
+	mockCategoryRepo.EXPECT().GetByName("music").Return(testCategory, nil)
+	mockPreferences.EXPECT().Get(int64(1)).Return(&user.Preferences{ShowNSFW: true}, nil)
+
+	resp, err := http.Get(server.URL + "/api/categories/music/events?token=" + token)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	waitSubscribers(t, hub, live.NSFWCategoryTopic("music"), 1)
+	assert.Equal(t, 0, hub.Subscribers(live.CategoryTopic("music")))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MarksRepositoryInterface interface {
+	SetHidden(int64, bson.ObjectId, bool) error
+	SetSeen(int64, bson.ObjectId) error
+	GetByPosts(int64, []bson.ObjectId) (map[bson.ObjectId]*posts.PostMarks, error)
+	RemovePost(bson.ObjectId) error
+}
+
+func (h *PostsHandler) Hide(w http.ResponseWriter, r *http.Request) {
+	h.setHidden(w, r, true)
+}
+
+func (h *PostsHandler) Unhide(w http.ResponseWriter, r *http.Request) {
+	h.setHidden(w, r, false)
+}
+
+func (h *PostsHandler) setHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+	if hidden {
+		_, err = h.PostsRepo.GetByID(postID)
+		if err == mgo.ErrNotFound {
+			http.Error(w, "No post", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("DB err: %v", err)
+			return
+		}
+	}
+	err = h.MarksRepo.SetHidden(sess.User.ID, postID, hidden)
+	if err != nil {
+		http.Error(w, `Hide error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Hide error: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v set hidden %v for post %v", sess.User.Username, hidden, postID)
+}
+
+// userMarks returns the marks of the authenticated user for the posts, nil
+// for anonymous users. Marks are not critical for listings, so errors are
+// only logged.
+func (h *PostsHandler) userMarks(r *http.Request, list []*posts.Post) map[bson.ObjectId]*posts.PostMarks {
+	if h.MarksRepo == nil || len(list) == 0 {
+		return nil
+	}
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		return nil
+	}
+	postIDs := make([]bson.ObjectId, 0, len(list))
+	for _, post := range list {
+		postIDs = append(postIDs, post.ID)
+	}
+	marks, err := h.MarksRepo.GetByPosts(sess.User.ID, postIDs)
+	if err != nil {
+		h.Logger.Errorf("Can't get post marks: %v", err)
+		return nil
+	}
+	return marks
+}
+
+// withoutHidden drops the posts hidden by the user if the listing was asked
+// for with ?excludeHidden=true.
+func withoutHidden(r *http.Request, list []*posts.Post, marks map[bson.ObjectId]*posts.PostMarks) []*posts.Post {
+	exclude, _ := strconv.ParseBool(r.URL.Query().Get("excludeHidden"))
+	if !exclude || len(marks) == 0 {
+		return list
+	}
+	visible := make([]*posts.Post, 0, len(list))
+	for _, post := range list {
+		if mark, ok := marks[post.ID]; ok && mark.Hidden {
+			continue
+		}
+		visible = append(visible, post)
+	}
+	return visible
+}
+
+// applyMarks sets the hidden and seen flags of the responses.
+func applyMarks(responses []*PostResponse, marks map[bson.ObjectId]*posts.PostMarks) {
+	for _, response := range responses {
+		if mark, ok := marks[response.ID]; ok {
+			response.Hidden = mark.Hidden
+			response.Seen = mark.Seen != ""
+		}
+	}
+}
+
+// recordSeen remembers that the authenticated user opened the post.
+func (h *PostsHandler) recordSeen(r *http.Request, postID bson.ObjectId) {
+	if h.MarksRepo == nil {
+		return
+	}
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		return
+	}
+	err = h.MarksRepo.SetSeen(sess.User.ID, postID)
+	if err != nil {
+		h.Logger.Errorf("Can't record seen post %v: %v", postID, err)
+	}
+}
+
+// forgetMarks removes the marks of a deleted post.
+func (h *PostsHandler) forgetMarks(postID bson.ObjectId) {
+	if h.MarksRepo == nil {
+		return
+	}
+	err := h.MarksRepo.RemovePost(postID)
+	if err != nil {
+		h.Logger.Errorf("Can't remove marks of post %v: %v", postID, err)
+	}
+}
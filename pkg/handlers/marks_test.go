@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMarks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockMarksRepo := NewMockMarksRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:      zapLogger.Sugar(),
+		PostsRepo:   mockPostsRepo,
+		CommentRepo: mockCommentsRepo,
+		MarksRepo:   mockMarksRepo,
+	}
+	firstID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	secondID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+	newPost := func(id bson.ObjectId, title string) *posts.Post {
+		return &posts.Post{
+			Author:     testUser,
+			Category:   "music",
+			CommentsID: []bson.ObjectId{},
+			Created:    "2020-05-12T22:33:02+03:00",
+			ID:         id,
+			Title:      title,
+			Type:       "text",
+			Votes:      []posts.Vote{},
+		}
+	}
+	listing := func() []*posts.Post {
+		return []*posts.Post{newPost(firstID, "First"), newPost(secondID, "Second")}
+	}
+	marks := map[bson.ObjectId]*posts.PostMarks{
+		firstID:  {UserID: 1, PostID: firstID, Hidden: true},
+		secondID: {UserID: 1, PostID: secondID, Seen: "2020-05-13T10:00:00+03:00"},
+	}
+	hideRequest := func(method string) *http.Request {
+		r := httptest.NewRequest(method, "/api/post/{POST_ID}/hide", nil)
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"POST_ID": firstID.Hex()})
+	}
+	categoryRequest := func(url string) *http.Request {
+		return mux.SetURLVars(requestWithSession(httptest.NewRequest("GET", url, nil)), map[string]string{"CATEGORY": "music"})
+	}
+
+	testCases := []TestCase{
+		{ //Hide SUCCESS
+			Request: hideRequest("POST"),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(firstID),
+				mockMarksRepo.EXPECT().SetHidden(int64(1), firstID, true),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(firstID, "First"), nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Hide,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Hide no post
+			Request: hideRequest("POST"),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(firstID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, mgo.ErrNotFound},
+			},
+			HandlerFunc: postsTestHandler.Hide,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Unhide SUCCESS
+			Request: hideRequest("DELETE"),
+			ExpectMockFunc: []*gomock.Call{
+				mockMarksRepo.EXPECT().SetHidden(int64(1), firstID, false),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Unhide,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Listing marks hidden and seen posts
+			Request: categoryRequest("/api/posts/music"),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetCategory("music"),
+				mockMarksRepo.EXPECT().GetByPosts(int64(1), []bson.ObjectId{firstID, secondID}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{listing(), nil},
+				{marks, nil},
+			},
+			HandlerFunc: postsTestHandler.ListCategory,
+			ExpectResult: Result{
+				Body: []byte(`[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"First","type":"text","upvotePercentage":0,"views":0,"votes":[],"hidden":true},{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51245","score":0,"title":"Second","type":"text","upvotePercentage":0,"views":0,"votes":[],"seen":true}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Listing without hidden posts
+			Request: categoryRequest("/api/posts/music?excludeHidden=true"),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetCategory("music"),
+				mockMarksRepo.EXPECT().GetByPosts(int64(1), []bson.ObjectId{firstID, secondID}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{listing(), nil},
+				{marks, nil},
+			},
+			HandlerFunc: postsTestHandler.ListCategory,
+			ExpectResult: Result{
+				Body: []byte(`[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51245","score":0,"title":"Second","type":"text","upvotePercentage":0,"views":0,"votes":[],"seen":true}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Marks are unavailable, the listing is still shown
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/posts/?excludeHidden=true", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetAll(),
+				mockMarksRepo.EXPECT().GetByPosts(int64(1), []bson.ObjectId{firstID}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{newPost(firstID, "First")}, nil},
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: postsTestHandler.ListAll,
+			ExpectResult: Result{
+				Body: []byte(`[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"First","type":"text","upvotePercentage":0,"views":0,"votes":[]}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Opening a post records it as seen
+			Request: mux.SetURLVars(requestWithSession(httptest.NewRequest("GET", "/api/post/{ID}", nil)), map[string]string{"ID": secondID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(secondID),
+				mockMarksRepo.EXPECT().SetSeen(int64(1), secondID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(secondID, "Second"), nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ListByID,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51245","score":0,"title":"Second","type":"text","upvotePercentage":0,"views":0,"votes":[]}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
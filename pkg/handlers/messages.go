@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/live"
+	"reddit/pkg/messages"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MessagesRepositoryInterface interface {
+	NewThread(*user.User, *user.User, string, string) (*messages.Thread, error)
+	Reply(*messages.Thread, *user.User, string) (*messages.Message, error)
+	GetThread(bson.ObjectId) (*messages.Thread, error)
+	GetThreads(int64, int, int) ([]*messages.Thread, int, error)
+	GetMessages(bson.ObjectId, int, int) ([]*messages.Message, int, error)
+	MarkRead(bson.ObjectId, int64) (int, error)
+	MarkUnread(bson.ObjectId, int64) (bool, error)
+	Unread(int64) (int, error)
+}
+
+type BlocksRepositoryInterface interface {
+	Block(int64, *user.User) error
+	Unblock(int64, int64) (bool, error)
+	GetByUser(int64) ([]*messages.Block, error)
+	IsBlocked(int64, int64) (bool, error)
+}
+
+type MessagesHandler struct {
+	Logger       *zap.SugaredLogger
+	MessagesRepo MessagesRepositoryInterface
+	BlocksRepo   BlocksRepositoryInterface
+	UserRepo     UserRepositoryInterface
+	Live         LiveInterface
+}
+
+type NewMessageRequest struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type ReplyRequest struct {
+	Body string `json:"body"`
+}
+
+type InboxPage struct {
+	Threads []*messages.Thread `json:"threads"`
+	Unread  int                `json:"unread"`
+	Page    int                `json:"page"`
+	Limit   int                `json:"limit"`
+	Total   int                `json:"total"`
+}
+
+type ThreadPage struct {
+	Thread   *messages.Thread    `json:"thread"`
+	Messages []*messages.Message `json:"messages"`
+	Page     int                 `json:"page"`
+	Limit    int                 `json:"limit"`
+	Total    int                 `json:"total"`
+}
+
+func (h *MessagesHandler) session(w http.ResponseWriter, r *http.Request) *session.Session {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return nil
+	}
+	return sess
+}
+
+// Inbox lists the threads of the user, the last updated first.
+func (h *MessagesHandler) Inbox(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	page, limit, skip, ok := pageSkip(w, r, h.Logger)
+	if !ok {
+		return
+	}
+	threads, total, err := h.MessagesRepo.GetThreads(sess.User.ID, skip, limit)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	unread, err := h.MessagesRepo.Unread(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(&InboxPage{
+		Threads: threads,
+		Unread:  unread,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	})
+	w.Write(resp)
+	h.Logger.Infof("Inbox of %v", sess.User.Username)
+}
+
+// checkNotBlocked answers with an error and returns false if the recipient
+// has blocked the sender.
+func (h *MessagesHandler) checkNotBlocked(w http.ResponseWriter, from, to *user.User) bool {
+	blocked, err := h.BlocksRepo.IsBlocked(to.ID, from.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return false
+	}
+	if blocked {
+		http.Error(w, `You can't message this user`, http.StatusForbidden)
+		h.Logger.Infof("%v is blocked by %v", from.Username, to.Username)
+		return false
+	}
+	return true
+}
+
+// Send starts a new thread with the user in the request.
+func (h *MessagesHandler) Send(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	messageRequest := new(NewMessageRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, messageRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	validationErrors := messages.ValidateMessage(messageRequest.To, messageRequest.Subject, messageRequest.Body)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad message: %v", validationErrors)
+		return
+	}
+	to, err := h.UserRepo.GetByUsername(messageRequest.To)
+	if err == user.ErrNoUser {
+		writeFieldError(w, "to", messageRequest.To, "unknown user")
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if to.ID == sess.User.ID {
+		writeFieldError(w, "to", messageRequest.To, "can't message yourself")
+		return
+	}
+	if !h.checkNotBlocked(w, sess.User, to) {
+		return
+	}
+	thread, err := h.MessagesRepo.NewThread(sess.User, to, messageRequest.Subject, messageRequest.Body)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	h.publish(thread.Last)
+	resp, _ := json.Marshal(thread)
+	w.Write(resp)
+	h.Logger.Infof("%v wrote to %v", sess.User.Username, to.Username)
+}
+
+// thread finds the thread in the URL. Threads of other users are not found
+// either, so their IDs tell nothing.
+func (h *MessagesHandler) thread(w http.ResponseWriter, r *http.Request, u *user.User) *messages.Thread {
+	if !bson.IsObjectIdHex(mux.Vars(r)["THREAD_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad thread id")
+		return nil
+	}
+	threadID := bson.ObjectIdHex(mux.Vars(r)["THREAD_ID"])
+	thread, err := h.MessagesRepo.GetThread(threadID)
+	if err == mgo.ErrNotFound || err == nil && !thread.HasUser(u.ID) {
+		http.Error(w, "No thread", http.StatusNotFound)
+		h.Logger.Errorf("No thread %v for %v", threadID, u.Username)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	return thread
+}
+
+// Thread shows a page of the messages, the oldest first, and marks the
+// messages to the user as read.
+func (h *MessagesHandler) Thread(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	thread := h.thread(w, r, sess.User)
+	if thread == nil {
+		return
+	}
+	page, limit, skip, ok := pageSkip(w, r, h.Logger)
+	if !ok {
+		return
+	}
+	threadMessages, total, err := h.MessagesRepo.GetMessages(thread.ID, skip, limit)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	_, err = h.MessagesRepo.MarkRead(thread.ID, sess.User.ID)
+	if err != nil {
+		h.Logger.Errorf("Can't mark thread %v read: %v", thread.ID, err)
+	}
+	resp, _ := json.Marshal(&ThreadPage{
+		Thread:   thread,
+		Messages: threadMessages,
+		Page:     page,
+		Limit:    limit,
+		Total:    total,
+	})
+	w.Write(resp)
+	h.Logger.Infof("%v read thread %v", sess.User.Username, thread.ID)
+}
+
+func (h *MessagesHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	thread := h.thread(w, r, sess.User)
+	if thread == nil {
+		return
+	}
+	replyRequest := new(ReplyRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, replyRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	validationErrors := messages.ValidateReply(replyRequest.Body)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad reply: %v", validationErrors)
+		return
+	}
+	if !h.checkNotBlocked(w, sess.User, thread.Other(sess.User.ID)) {
+		return
+	}
+	message, err := h.MessagesRepo.Reply(thread, sess.User, replyRequest.Body)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	h.publish(message)
+	resp, _ := json.Marshal(message)
+	w.Write(resp)
+	h.Logger.Infof("%v replied in thread %v", sess.User.Username, thread.ID)
+}
+
+// publish pushes the message to the live connections of the recipient.
+func (h *MessagesHandler) publish(message *messages.Message) {
+	if h.Live == nil || message == nil {
+		return
+	}
+	h.Live.Publish(&live.Event{Type: LiveMessage, Data: message}, live.UserTopic(message.To.ID))
+}
+
+func (h *MessagesHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	thread := h.thread(w, r, sess.User)
+	if thread == nil {
+		return
+	}
+	_, err := h.MessagesRepo.MarkRead(thread.ID, sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v marked thread %v read", sess.User.Username, thread.ID)
+}
+
+func (h *MessagesHandler) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	thread := h.thread(w, r, sess.User)
+	if thread == nil {
+		return
+	}
+	ok, err := h.MessagesRepo.MarkUnread(thread.ID, sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v marked thread %v unread", sess.User.Username, thread.ID)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v got no messages in thread %v", sess.User.Username, thread.ID)
+	}
+}
+
+func (h *MessagesHandler) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	blocks, err := h.BlocksRepo.GetByUser(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(blocks)
+	w.Write(resp)
+	h.Logger.Infof("Blocks of %v", sess.User.Username)
+}
+
+// blockedUser finds the user in the URL, it answers with an error and returns
+// nil if there is no such user.
+func (h *MessagesHandler) blockedUser(w http.ResponseWriter, r *http.Request) *user.User {
+	login := mux.Vars(r)["USER_LOGIN"]
+	u, err := h.UserRepo.GetByUsername(login)
+	if err == user.ErrNoUser {
+		http.Error(w, `No user`, http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	return u
+}
+
+// Block keeps the user in the URL from messaging the session user.
+func (h *MessagesHandler) Block(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	u := h.blockedUser(w, r)
+	if u == nil {
+		return
+	}
+	if u.ID == sess.User.ID {
+		http.Error(w, `Can't block yourself`, http.StatusBadRequest)
+		return
+	}
+	err := h.BlocksRepo.Block(sess.User.ID, u)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v blocked %v", sess.User.Username, u.Username)
+}
+
+func (h *MessagesHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	sess := h.session(w, r)
+	if sess == nil {
+		return
+	}
+	u := h.blockedUser(w, r)
+	if u == nil {
+		return
+	}
+	ok, err := h.BlocksRepo.Unblock(sess.User.ID, u.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v unblocked %v", sess.User.Username, u.Username)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v wasn't blocked by %v", u.Username, sess.User.Username)
+	}
+}
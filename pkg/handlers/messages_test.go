@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/messages"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMessagesRepo := NewMockMessagesRepositoryInterface(ctrl)
+	mockBlocksRepo := NewMockBlocksRepositoryInterface(ctrl)
+	mockUserRepo := NewMockUserRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	messagesTestHandler := &MessagesHandler{
+		Logger:       zapLogger.Sugar(),
+		MessagesRepo: mockMessagesRepo,
+		BlocksRepo:   mockBlocksRepo,
+		UserRepo:     mockUserRepo,
+	}
+	threadID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	messageID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+	message := &messages.Message{
+		ID:       messageID,
+		ThreadID: threadID,
+		From:     testUser,
+		To:       testModerator,
+		Body:     "Hi",
+		Created:  "2020-05-12T22:33:02+03:00",
+	}
+	thread := &messages.Thread{
+		ID:      threadID,
+		Subject: "Hello",
+		Users:   []*user.User{testUser, testModerator},
+		UserIDs: []int64{1, 2},
+		Created: "2020-05-12T22:33:02+03:00",
+		Updated: "2020-05-12T22:33:02+03:00",
+		Last:    message,
+	}
+	thirdUser := &user.User{ID: 3, Username: "anna"}
+	threadRequest := func(method string, u *user.User, id, body string) *http.Request {
+		r := httptest.NewRequest(method, "/api/messages/{THREAD_ID}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"THREAD_ID": id})
+	}
+	blockRequest := func(method, login string) *http.Request {
+		r := httptest.NewRequest(method, "/api/me/blocks/{USER_LOGIN}", nil)
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"USER_LOGIN": login})
+	}
+	messageResponse := `{"id":"5ebaf9ee3c04c17c56f51245","thread":"5ebaf9ee3c04c17c56f51244","from":{"username":"rvasily","id":"1"},"to":{"username":"igor","id":"2"},"body":"Hi","created":"2020-05-12T22:33:02+03:00","read":false}`
+	threadResponse := `{"id":"5ebaf9ee3c04c17c56f51244","subject":"Hello","users":[{"username":"rvasily","id":"1"},{"username":"igor","id":"2"}],"created":"2020-05-12T22:33:02+03:00","updated":"2020-05-12T22:33:02+03:00","last":` + messageResponse + `,"unread":0}`
+
+	testCases := []TestCase{
+		{ //Message without a body
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/messages",
+				bytes.NewReader([]byte(`{"to": "igor", "subject": "Hello"}`)))),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    messagesTestHandler.Send,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"body","value":"","msg":"is required"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Message to an unknown user
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/messages",
+				bytes.NewReader([]byte(`{"to": "nobody", "subject": "Hello", "body": "Hi"}`)))),
+			ExpectMockFunc: []*gomock.Call{
+				mockUserRepo.EXPECT().GetByUsername("nobody"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, user.ErrNoUser},
+			},
+			HandlerFunc: messagesTestHandler.Send,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"to","value":"nobody","msg":"unknown user"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Message to a user who blocked the sender
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/messages",
+				bytes.NewReader([]byte(`{"to": "igor", "subject": "Hello", "body": "Hi"}`)))),
+			ExpectMockFunc: []*gomock.Call{
+				mockUserRepo.EXPECT().GetByUsername("igor"),
+				mockBlocksRepo.EXPECT().IsBlocked(int64(2), int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testModerator, nil},
+				{true, nil},
+			},
+			HandlerFunc: messagesTestHandler.Send,
+			ExpectResult: Result{
+				Body: []byte("You can't message this user\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Message SUCCESS
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/messages",
+				bytes.NewReader([]byte(`{"to": "igor", "subject": "Hello", "body": "Hi"}`)))),
+			ExpectMockFunc: []*gomock.Call{
+				mockUserRepo.EXPECT().GetByUsername("igor"),
+				mockBlocksRepo.EXPECT().IsBlocked(int64(2), int64(1)),
+				mockMessagesRepo.EXPECT().NewThread(testUser, testModerator, "Hello", "Hi"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testModerator, nil},
+				{false, nil},
+				{thread, nil},
+			},
+			HandlerFunc: messagesTestHandler.Send,
+			ExpectResult: Result{
+				Body: []byte(threadResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Inbox
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/messages", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThreads(int64(1), 0, 25),
+				mockMessagesRepo.EXPECT().Unread(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*messages.Thread{thread}, 1, nil},
+				{0, nil},
+			},
+			HandlerFunc: messagesTestHandler.Inbox,
+			ExpectResult: Result{
+				Body: []byte(`{"threads":[` + threadResponse + `],"unread":0,"page":1,"limit":25,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Thread of other users
+			Request: threadRequest("GET", thirdUser, threadID.Hex(), ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{thread, nil},
+			},
+			HandlerFunc: messagesTestHandler.Thread,
+			ExpectResult: Result{
+				Body: []byte("No thread\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Missing thread
+			Request: threadRequest("GET", testModerator, threadID.Hex(), ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, mgo.ErrNotFound},
+			},
+			HandlerFunc: messagesTestHandler.Thread,
+			ExpectResult: Result{
+				Body: []byte("No thread\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Reading a thread marks it read
+			Request: threadRequest("GET", testModerator, threadID.Hex(), ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+				mockMessagesRepo.EXPECT().GetMessages(threadID, 0, 25),
+				mockMessagesRepo.EXPECT().MarkRead(threadID, int64(2)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{thread, nil},
+				{[]*messages.Message{message}, 1, nil},
+				{1, nil},
+			},
+			HandlerFunc: messagesTestHandler.Thread,
+			ExpectResult: Result{
+				Body: []byte(`{"thread":` + threadResponse + `,"messages":[` + messageResponse + `],"page":1,"limit":25,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Reply to a user who blocked the sender
+			Request: threadRequest("POST", testModerator, threadID.Hex(), `{"body": "Hi"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+				mockBlocksRepo.EXPECT().IsBlocked(int64(1), int64(2)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{thread, nil},
+				{true, nil},
+			},
+			HandlerFunc: messagesTestHandler.Reply,
+			ExpectResult: Result{
+				Body: []byte("You can't message this user\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Reply SUCCESS
+			Request: threadRequest("POST", testUser, threadID.Hex(), `{"body": "Hi"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+				mockBlocksRepo.EXPECT().IsBlocked(int64(2), int64(1)),
+				mockMessagesRepo.EXPECT().Reply(thread, testUser, "Hi"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{thread, nil},
+				{false, nil},
+				{message, nil},
+			},
+			HandlerFunc: messagesTestHandler.Reply,
+			ExpectResult: Result{
+				Body: []byte(messageResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Mark unread a thread without messages to the user
+			Request: threadRequest("DELETE", testUser, threadID.Hex(), ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockMessagesRepo.EXPECT().GetThread(threadID),
+				mockMessagesRepo.EXPECT().MarkUnread(threadID, int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{thread, nil},
+				{false, nil},
+			},
+			HandlerFunc: messagesTestHandler.MarkUnread,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Block yourself
+			Request: blockRequest("POST", "rvasily"),
+			ExpectMockFunc: []*gomock.Call{
+				mockUserRepo.EXPECT().GetByUsername("rvasily"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testUser, nil},
+			},
+			HandlerFunc: messagesTestHandler.Block,
+			ExpectResult: Result{
+				Body: []byte("Can't block yourself\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Block SUCCESS
+			Request: blockRequest("POST", "igor"),
+			ExpectMockFunc: []*gomock.Call{
+				mockUserRepo.EXPECT().GetByUsername("igor"),
+				mockBlocksRepo.EXPECT().Block(int64(1), testModerator),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testModerator, nil},
+				{nil},
+			},
+			HandlerFunc: messagesTestHandler.Block,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
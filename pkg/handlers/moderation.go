@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/category"
+	"reddit/pkg/moderation"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// isModerator reports whether u may moderate the category: its owner and
+// the site admins can. The default categories have no owner, so only the
+// admins moderate them.
+func isModerator(cat *category.Category, u *user.User, admins map[string]bool) bool {
+	if u == nil {
+		return false
+	}
+	return admins[u.Username] || (cat != nil && cat.IsModerator(u))
+}
+
+// moderatedCategory checks that the user moderates the category in the URL.
+// It answers with an error and returns nils if not. The category and the
+// post handlers share it, so their answers are the same.
+func moderatedCategory(w http.ResponseWriter, r *http.Request, categories CategoryRepositoryInterface,
+	admins map[string]bool, logger *zap.SugaredLogger) (*category.Category, *session.Session) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		logger.Errorf("Bad auth. Error: %v", err)
+		return nil, nil
+	}
+	name := mux.Vars(r)["CATEGORY"]
+	cat, err := categories.GetByName(name)
+	if err == category.ErrNoCategory {
+		http.Error(w, `No category`, http.StatusNotFound)
+		logger.Errorf("No category: %v", name)
+		return nil, nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		logger.Errorf("DB err: %v", err)
+		return nil, nil
+	}
+	if !isModerator(cat, sess.User, admins) {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		logger.Errorf("%v isn't a moderator of %v", sess.User.Username, name)
+		return nil, nil
+	}
+	return cat, sess
+}
+
+// moderatedPost finds the post in the URL and checks that the user is the
+// moderator of its category. It answers with an error and returns nil if not.
+func (h *PostsHandler) moderatedPost(w http.ResponseWriter, r *http.Request) (*posts.Post, *user.User) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return nil, nil
+	}
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return nil, nil
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return nil, nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil, nil
+	}
+	cat, err := h.CategoryRepo.GetByName(post.Category)
+	if err != nil {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("Can't moderate post %v: %v", postID, err)
+		return nil, nil
+	}
+	if !isModerator(cat, sess.User, h.Admins) {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("%v isn't a moderator of %v", sess.User.Username, post.Category)
+		return nil, nil
+	}
+	return post, sess.User
+}
+
+func (h *PostsHandler) writeModeratedPost(w http.ResponseWriter, post *posts.Post) {
+	postResponse, err := PostToPostResponse(post, h.CommentRepo)
+	if err != nil {
+		http.Error(w, `Internal error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Post Transform error: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(postResponse)
+	w.Write(resp)
+}
+
+func (h *PostsHandler) Sticky(w http.ResponseWriter, r *http.Request) {
+	h.setSticky(w, r, true)
+}
+
+func (h *PostsHandler) Unsticky(w http.ResponseWriter, r *http.Request) {
+	h.setSticky(w, r, false)
+}
+
+func (h *PostsHandler) setSticky(w http.ResponseWriter, r *http.Request, sticky bool) {
+	post, moderator := h.moderatedPost(w, r)
+	if post == nil {
+		return
+	}
+	err := h.PostsRepo.SetSticky(post, sticky)
+	if err == posts.ErrTooManySticky {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	action := moderation.ActionSticky
+	if !sticky {
+		action = moderation.ActionUnsticky
+	}
+	h.logModAction(moderator, post.Category, action, posts.ItemPost, post.ID, post.ID, "")
+	h.writeModeratedPost(w, post)
+	h.Logger.Infof("Post %v sticky: %v", post.ID, sticky)
+}
+
+func (h *PostsHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	h.setLocked(w, r, true)
+}
+
+func (h *PostsHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	h.setLocked(w, r, false)
+}
+
+func (h *PostsHandler) setLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	post, moderator := h.moderatedPost(w, r)
+	if post == nil {
+		return
+	}
+	err := h.PostsRepo.SetLocked(post.ID, locked)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	post.Locked = locked
+	action := moderation.ActionLock
+	if !locked {
+		action = moderation.ActionUnlock
+	}
+	h.logModAction(moderator, post.Category, action, posts.ItemPost, post.ID, post.ID, "")
+	h.writeModeratedPost(w, post)
+	h.Logger.Infof("Post %v locked: %v", post.ID, locked)
+}
+
+// commentablePost finds the post to comment. It answers with an error and
+// returns nil if the post doesn't take new comments from the user.
+func (h *PostsHandler) commentablePost(w http.ResponseWriter, postID bson.ObjectId, u *user.User) *posts.Post {
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	if post.Archived {
+		http.Error(w, posts.ErrArchived.Error(), http.StatusForbidden)
+		return nil
+	}
+	if post.Locked {
+		http.Error(w, posts.ErrLocked.Error(), http.StatusForbidden)
+		return nil
+	}
+	if !h.checkNotBanned(w, post.Category, u) {
+		return nil
+	}
+	return post
+}
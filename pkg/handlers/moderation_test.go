@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	posts "reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestModeration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	newPost := func() *posts.Post {
+		return &posts.Post{
+			Author:     testUser,
+			Category:   "music",
+			CommentsID: []bson.ObjectId{},
+			Created:    "2020-05-12T22:33:02+03:00",
+			ID:         postID,
+			Title:      "Rules",
+			Type:       "text",
+			Votes:      []posts.Vote{},
+		}
+	}
+	moderationRequest := func(method, url string, u *user.User) *http.Request {
+		r := httptest.NewRequest(method, url, nil)
+		return mux.SetURLVars(requestWithUser(r, u), map[string]string{"POST_ID": postID.Hex()})
+	}
+
+	testCases := []TestCase{
+		{ //Sticky SUCCESS
+			Request: moderationRequest("POST", "/api/post/{POST_ID}/sticky", testModerator),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetSticky(gomock.Any(), true),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{testFlairCategory, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Sticky,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"Rules","type":"text","upvotePercentage":0,"views":0,"votes":[]}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Too many sticky posts
+			Request: moderationRequest("POST", "/api/post/{POST_ID}/sticky", testModerator),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetSticky(gomock.Any(), true),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{testFlairCategory, nil},
+				{posts.ErrTooManySticky},
+			},
+			HandlerFunc: postsTestHandler.Sticky,
+			ExpectResult: Result{
+				Body: []byte("Category already has 2 sticky posts\n"),
+				Code: http.StatusConflict,
+			},
+		},
+		{ //The author isn't a moderator
+			Request: moderationRequest("POST", "/api/post/{POST_ID}/lock", testUser),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.Lock,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Lock SUCCESS
+			Request: moderationRequest("POST", "/api/post/{POST_ID}/lock", testModerator),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().SetLocked(postID, true),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{testFlairCategory, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Lock,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"Rules","type":"text","upvotePercentage":0,"views":0,"votes":[],"locked":true}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Comment to a locked post
+			Request: mux.SetURLVars(requestWithSession(httptest.NewRequest("POST", "/api/post/{POST_ID}",
+				bytes.NewReader([]byte(`{"comment": "Hi"}`)))), map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&posts.Post{ID: postID, Locked: true}, nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte("Post is locked\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Comment to an archived post
+			Request: mux.SetURLVars(requestWithSession(httptest.NewRequest("POST", "/api/post/{POST_ID}",
+				bytes.NewReader([]byte(`{"comment": "Hi"}`)))), map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&posts.Post{ID: postID, Archived: true}, nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte("Post is archived\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Vote for an archived post
+			Request: mux.SetURLVars(requestWithSession(httptest.NewRequest("GET", "/api/post/{POST_ID}/upvote", nil)),
+				map[string]string{"POST_ID": postID.Hex()}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().Upvote(testUser, postID, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, posts.ErrArchived},
+			},
+			HandlerFunc: postsTestHandler.Upvote,
+			ExpectResult: Result{
+				Body: []byte("Post is archived\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Sticky posts go first in the category
+			Request: mux.SetURLVars(httptest.NewRequest("GET", "/api/posts/music", nil), map[string]string{"CATEGORY": "music"}),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetCategory("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{
+					{ID: "^\xba\xf9\xee<\x04\xc1|V\xf5\x12E", Title: "Plain", Type: "text", CommentsID: []bson.ObjectId{}},
+					{ID: postID, Title: "Rules", Type: "text", Sticky: true, CommentsID: []bson.ObjectId{}},
+				}, nil},
+			},
+			HandlerFunc: postsTestHandler.ListCategory,
+			ExpectResult: Result{
+				Body: []byte(`[{"author":null,"category":"","comments":[],"created":"","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"Rules","type":"text","upvotePercentage":0,"views":0,"votes":null,"sticky":true},{"author":null,"category":"","comments":[],"created":"","id":"5ebaf9ee3c04c17c56f51245","score":0,"title":"Plain","type":"text","upvotePercentage":0,"views":0,"votes":null}]`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
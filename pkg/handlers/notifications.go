@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/notifications"
+	"reddit/pkg/session"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type NotificationsRepositoryInterface interface {
+	GetByUser(int64, int, int) ([]*notifications.Notification, int, error)
+	Unread(int64) (int, error)
+	MarkRead(int64, bson.ObjectId) (bool, error)
+	MarkAllRead(int64) (int, error)
+}
+
+type NotificationsHandler struct {
+	Logger            *zap.SugaredLogger
+	NotificationsRepo NotificationsRepositoryInterface
+}
+
+type NotificationsPage struct {
+	Notifications []*notifications.Notification `json:"notifications"`
+	Unread        int                           `json:"unread"`
+	Page          int                           `json:"page"`
+	Limit         int                           `json:"limit"`
+	Total         int                           `json:"total"`
+}
+
+// List shows a page of the notifications of the user, the newest first.
+func (h *NotificationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	page, limit, skip, ok := pageSkip(w, r, h.Logger)
+	if !ok {
+		return
+	}
+	list, total, err := h.NotificationsRepo.GetByUser(sess.User.ID, skip, limit)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	unread, err := h.NotificationsRepo.Unread(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(&NotificationsPage{
+		Notifications: list,
+		Unread:        unread,
+		Page:          page,
+		Limit:         limit,
+		Total:         total,
+	})
+	w.Write(resp)
+	h.Logger.Infof("Notifications of %v", sess.User.Username)
+}
+
+// Unread answers only the number of the unread notifications, it is cheap
+// enough to poll.
+func (h *NotificationsHandler) Unread(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	unread, err := h.NotificationsRepo.Unread(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(map[string]int{"unread": unread})
+	w.Write(resp)
+}
+
+func (h *NotificationsHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	if !bson.IsObjectIdHex(mux.Vars(r)["NOTIFICATION_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad notification id")
+		return
+	}
+	id := bson.ObjectIdHex(mux.Vars(r)["NOTIFICATION_ID"])
+	ok, err := h.NotificationsRepo.MarkRead(sess.User.ID, id)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v read notification %v", sess.User.Username, id)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v has no notification %v", sess.User.Username, id)
+	}
+}
+
+func (h *NotificationsHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	_, err = h.NotificationsRepo.MarkAllRead(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v read all notifications", sess.User.Username)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/events"
+	"reddit/pkg/notifications"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestNotifications(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockNotificationsRepo := NewMockNotificationsRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	notificationsTestHandler := &NotificationsHandler{
+		Logger:            zapLogger.Sugar(),
+		NotificationsRepo: mockNotificationsRepo,
+	}
+	notificationID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51247")
+	notification := &notifications.Notification{
+		ID:        notificationID,
+		UserID:    1,
+		Kind:      notifications.KindPostComment,
+		Actor:     testModerator,
+		PostID:    bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244"),
+		CommentID: bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245"),
+		Category:  "music",
+		Title:     "News",
+		Body:      "Nice",
+		Created:   "2020-05-12T22:33:02+03:00",
+	}
+	readRequest := func(id string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/notifications/{NOTIFICATION_ID}/read", nil)
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"NOTIFICATION_ID": id})
+	}
+
+	testCases := []TestCase{
+		{ //Notifications
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/notifications?limit=10", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockNotificationsRepo.EXPECT().GetByUser(int64(1), 0, 10),
+				mockNotificationsRepo.EXPECT().Unread(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*notifications.Notification{notification}, 1, nil},
+				{1, nil},
+			},
+			HandlerFunc: notificationsTestHandler.List,
+			ExpectResult: Result{
+				Body: []byte(`{"notifications":[{"id":"5ebaf9ee3c04c17c56f51247","kind":"post_comment","actor":{"username":"igor","id":"2"},"post":"5ebaf9ee3c04c17c56f51244","comment":"5ebaf9ee3c04c17c56f51245","category":"music","title":"News","body":"Nice","created":"2020-05-12T22:33:02+03:00","read":false}],"unread":1,"page":1,"limit":10,"total":1}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unread count
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/notifications/unread", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockNotificationsRepo.EXPECT().Unread(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{3, nil},
+			},
+			HandlerFunc: notificationsTestHandler.Unread,
+			ExpectResult: Result{
+				Body: []byte(`{"unread":3}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Mark read with a bad id
+			Request:        readRequest("1"),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    notificationsTestHandler.MarkRead,
+			ExpectResult: Result{
+				Body: []byte("Bad id\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Mark read a notification of another user
+			Request: readRequest(notificationID.Hex()),
+			ExpectMockFunc: []*gomock.Call{
+				mockNotificationsRepo.EXPECT().MarkRead(int64(1), notificationID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{false, nil},
+			},
+			HandlerFunc: notificationsTestHandler.MarkRead,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Mark all read
+			Request: requestWithSession(httptest.NewRequest("POST", "/api/notifications/read", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockNotificationsRepo.EXPECT().MarkAllRead(int64(1)),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{2, nil},
+			},
+			HandlerFunc: notificationsTestHandler.MarkAllRead,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestCommentEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockEvents := NewMockEventsOutboxInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:      zapLogger.Sugar(),
+		PostsRepo:   mockPostsRepo,
+		CommentRepo: mockCommentsRepo,
+		Events:      mockEvents,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	parentID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+	commentID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51246")
+	parent := &posts.Comment{ID: parentID, Autor: testModerator, Body: "Nice", Created: "2020-05-12T22:33:02+03:00"}
+	reply := &posts.Comment{ID: commentID, Autor: testUser, Body: "Thanks", Created: "2020-05-12T22:33:02+03:00", Parent: parentID}
+	newPost := func(commentsID ...bson.ObjectId) *posts.Post {
+		return &posts.Post{
+			Author:     testUser,
+			Category:   "music",
+			CommentsID: commentsID,
+			Created:    "2020-05-12T22:33:02+03:00",
+			ID:         postID,
+			Title:      "News",
+			Type:       "text",
+			Text:       "Hello",
+			Votes:      []posts.Vote{},
+		}
+	}
+	commentRequest := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/post/{POST_ID}", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"POST_ID": postID.Hex()})
+	}
+
+	testCases := []TestCase{
+		{ //Reply to a comment of another post
+			Request: commentRequest(`{"comment": "Thanks", "parent": "5ebaf9ee3c04c17c56f51249"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(parentID), nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"parent","value":"5ebaf9ee3c04c17c56f51249","msg":"must be a comment of the post"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Reply is told to the events
+			Request: commentRequest(`{"comment": "Thanks", "parent": "5ebaf9ee3c04c17c56f51245"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCommentsRepo.EXPECT().NewComment(testUser, "Thanks", parentID),
+				// the comment is loaded for the staged event, the announce is
+				// run by the repo after the call matched
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+				mockPostsRepo.EXPECT().AddComment(postID, commentID, gomock.Any()).Do(func(_, _ bson.ObjectId, announce posts.Announce) {
+					assert.True(t, announces(newPost(parentID, commentID), events.CommentAdded, &events.CommentData{
+						Post:    events.NewPostData(newPost(parentID, commentID)),
+						Comment: reply,
+					}).Matches(announce))
+				}),
+				mockEvents.EXPECT().Wake(),
+				mockCommentsRepo.EXPECT().GetByID(parentID),
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(parentID), nil},
+				{commentID, nil},
+				{reply, nil},
+				{newPost(parentID, commentID), nil},
+				{},
+				{parent, nil},
+				{reply, nil},
+			},
+			HandlerFunc: postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte(`{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[{"id":"5ebaf9ee3c04c17c56f51245","author":{"username":"igor","id":"2"},"body":"Nice","created":"2020-05-12T22:33:02+03:00"},{"id":"5ebaf9ee3c04c17c56f51246","author":{"username":"rvasily","id":"1"},"body":"Thanks","created":"2020-05-12T22:33:02+03:00","parent":"5ebaf9ee3c04c17c56f51245"}],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"text":"Hello","title":"News","type":"text","upvotePercentage":0,"views":0,"votes":[]}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"reddit/pkg/paging"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit = 25
+	maxPageLimit     = 100
+)
+
+// PostsPage is a single page of a listing which is too long to be sent at once.
+type PostsPage struct {
+	Posts []*PostResponse `json:"posts"`
+	Page  int             `json:"page"`
+	Limit int             `json:"limit"`
+	Total int             `json:"total"`
+}
+
+// pageFromRequest reads ?page= (starting from 1) and ?limit= query params,
+// bad or missing values fall back to the first page of the default size.
+func pageFromRequest(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit
+}
+
+// pageSkip reads the page like pageFromRequest and returns how many items
+// to skip to get to it. A page too far away to skip to is answered with 400
+// and ok is false.
+func pageSkip(w http.ResponseWriter, r *http.Request, logger *zap.SugaredLogger) (page, limit, skip int, ok bool) {
+	page, limit = pageFromRequest(r)
+	skip, ok = paging.Offset(page, limit)
+	if !ok {
+		http.Error(w, `Bad page`, http.StatusBadRequest)
+		logger.Errorf("Bad page: %v", page)
+	}
+	return page, limit, skip, ok
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPageSkip(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+
+	w := httptest.NewRecorder()
+	page, limit, skip, ok := pageSkip(w, httptest.NewRequest("GET", "/api/messages?page=3&limit=10", nil), logger)
+	assert.True(t, ok)
+	assert.Equal(t, []int{3, 10, 20}, []int{page, limit, skip})
+
+	//Bad values fall back to the first page
+	page, limit, skip, ok = pageSkip(w, httptest.NewRequest("GET", "/api/messages?page=-1&limit=1000", nil), logger)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, maxPageLimit, 0}, []int{page, limit, skip})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//Too far away to skip to
+	_, _, _, ok = pageSkip(w, httptest.NewRequest("GET", "/api/messages?page=9223372036854775807&limit=100", nil), logger)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Bad page\n", w.Body.String())
+}
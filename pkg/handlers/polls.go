@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// PollResponse shows only tallies, who voted for what stays private.
+type PollResponse struct {
+	Options []posts.PollOption `json:"options"`
+	Total   int                `json:"total"`
+	Closes  string             `json:"closes,omitempty"`
+	Closed  bool               `json:"closed"`
+}
+
+type PollVoteRequest struct {
+	Option *int `json:"option"`
+}
+
+func NewPollResponse(poll *posts.Poll, now time.Time) *PollResponse {
+	return &PollResponse{
+		Options: poll.Tally(),
+		Total:   len(poll.Votes),
+		Closes:  poll.Closes,
+		Closed:  poll.Closed(now),
+	}
+}
+
+func (h *PostsHandler) VotePoll(w http.ResponseWriter, r *http.Request) {
+	h.pollVote(w, r, h.PostsRepo.VotePoll)
+}
+
+func (h *PostsHandler) ChangePollVote(w http.ResponseWriter, r *http.Request) {
+	h.pollVote(w, r, h.PostsRepo.ChangePollVote)
+}
+
+func (h *PostsHandler) pollVote(
+	w http.ResponseWriter,
+	r *http.Request,
+	vote func(*user.User, bson.ObjectId, int) (*posts.Post, error)) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	arg := mux.Vars(r)
+	if !bson.IsObjectIdHex(arg["POST_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad post id")
+		return
+	}
+	postID := bson.ObjectIdHex(arg["POST_ID"])
+
+	voteRequest := new(PollVoteRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, voteRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	if voteRequest.Option == nil {
+		writeFieldError(w, "option", "", "is required")
+		return
+	}
+
+	post, err := vote(sess.User, postID, *voteRequest.Option)
+	switch err {
+	case nil:
+	case posts.ErrNoOption:
+		writeFieldError(w, "option", strconv.Itoa(*voteRequest.Option), "no such option")
+		return
+	case posts.ErrNotPoll:
+		http.Error(w, "Post is not a poll", http.StatusBadRequest)
+		return
+	case posts.ErrArchived:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case posts.ErrPollClosed, posts.ErrAlreadyVoted, posts.ErrNotVoted:
+		http.Error(w, err.Error(), http.StatusConflict)
+		h.Logger.Infof("Poll vote rejected: %v", err)
+		return
+	default:
+		http.Error(w, `Bad poll vote`, http.StatusInternalServerError)
+		h.Logger.Errorf("Bad poll vote. Error: %v", err)
+		return
+	}
+
+	postResponse, err := PostToPostResponse(post, h.CommentRepo)
+	if err != nil {
+		http.Error(w, `Internal error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Post Transform error: %v", err)
+		return
+	}
+
+	resp, _ := json.Marshal(postResponse)
+	w.Write(resp)
+	h.Logger.Infof("Poll vote")
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var testPollPost = &posts.Post{
+	Author:           testUser,
+	Category:         "music",
+	CommentsID:       []bson.ObjectId{},
+	Created:          "2020-05-12T22:33:02+03:00",
+	ID:               "^\xba\xf9\xee<\x04\xc1|V\xf5\x12D",
+	Score:            1,
+	Title:            "Best band?",
+	Type:             "poll",
+	UpvotePercentage: 100,
+	Votes:            []posts.Vote{{UserID: 1, Rating: 1}},
+	Poll: &posts.Poll{
+		Options: []string{"Beatles", "Queen"},
+		Votes:   []posts.PollVote{{UserID: 1, Option: 1}, {UserID: 2, Option: 1}},
+	},
+}
+
+const testPollResponse = `{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"title":"Best band?","type":"poll","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"poll":{"options":[{"id":0,"text":"Beatles","votes":0},{"id":1,"text":"Queen","votes":2}],"total":2,"closed":false}}`
+
+func TestPolls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+	}
+	newPollRequest := func(options ...string) *http.Request {
+		reqBody, _ := json.Marshal(NewPostRequest{
+			Category: "music",
+			Title:    "Best band?",
+			Type:     "poll",
+			Options:  options,
+		})
+		return requestWithSession(httptest.NewRequest("POST", "/api/posts", bytes.NewReader(reqBody)))
+	}
+	voteRequest := func(method, body string) *http.Request {
+		r := httptest.NewRequest(method, "/api/post/{POST_ID}/poll", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithSession(r), map[string]string{"POST_ID": "5ebaf9ee3c04c17c56f51244"})
+	}
+
+	testCases := []TestCase{
+		{ //Create poll SUCCESS
+			Request: newPollRequest("Beatles", "Queen"),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockPostsRepo.EXPECT().AddPoll(testUser, "music", "Best band?", "", &posts.Poll{
+					Options: []string{"Beatles", "Queen"},
+				}, posts.Flags{}, gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testCategory, nil},
+				{testPollPost, nil},
+			},
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(testPollResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Create poll. Not enough options
+			Request:     newPollRequest("Beatles"),
+			HandlerFunc: postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"options","value":"","msg":"must have 2-10 options"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Vote SUCCESS
+			Request: voteRequest("POST", `{"option": 1}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().VotePoll(testUser, testPollPost.ID, 1),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testPollPost, nil},
+			},
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte(testPollResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Vote twice
+			Request: voteRequest("POST", `{"option": 0}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().VotePoll(testUser, testPollPost.ID, 0),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, posts.ErrAlreadyVoted},
+			},
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte("User already voted in this poll\n"),
+				Code: http.StatusConflict,
+			},
+		},
+		{ //Vote in closed poll
+			Request: voteRequest("PUT", `{"option": 0}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().ChangePollVote(testUser, testPollPost.ID, 0),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, posts.ErrPollClosed},
+			},
+			HandlerFunc: postsTestHandler.ChangePollVote,
+			ExpectResult: Result{
+				Body: []byte("Poll is closed\n"),
+				Code: http.StatusConflict,
+			},
+		},
+		{ //Change vote SUCCESS
+			Request: voteRequest("PUT", `{"option": 1}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().ChangePollVote(testUser, testPollPost.ID, 1),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testPollPost, nil},
+			},
+			HandlerFunc: postsTestHandler.ChangePollVote,
+			ExpectResult: Result{
+				Body: []byte(testPollResponse),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unknown option
+			Request: voteRequest("POST", `{"option": 7}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().VotePoll(testUser, testPollPost.ID, 7),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, posts.ErrNoOption},
+			},
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"option","value":"7","msg":"no such option"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //No option
+			Request:     voteRequest("POST", `{}`),
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"option","value":"","msg":"is required"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Not a poll
+			Request: voteRequest("POST", `{"option": 0}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().VotePoll(testUser, testPollPost.ID, 0),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, posts.ErrNotPoll},
+			},
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte("Post is not a poll\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Repo error
+			Request: voteRequest("POST", `{"option": 0}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().VotePoll(testUser, testPollPost.ID, 0),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: postsTestHandler.VotePoll,
+			ExpectResult: Result{
+				Body: []byte("Bad poll vote\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -65,6 +65,51 @@ func (mr *MockPostsRepositoryInterfaceMockRecorder) GetCategory(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).GetCategory), arg0)
 }
 
+// GetByDomain mocks base method
+func (m *MockPostsRepositoryInterface) GetByDomain(arg0 string) ([]*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByDomain", arg0)
+	ret0, _ := ret[0].([]*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByDomain indicates an expected call of GetByDomain
+func (mr *MockPostsRepositoryInterfaceMockRecorder) GetByDomain(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByDomain", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).GetByDomain), arg0)
+}
+
+// GetByLink mocks base method
+func (m *MockPostsRepositoryInterface) GetByLink(arg0 string) ([]*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByLink", arg0)
+	ret0, _ := ret[0].([]*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByLink indicates an expected call of GetByLink
+func (mr *MockPostsRepositoryInterfaceMockRecorder) GetByLink(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByLink", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).GetByLink), arg0)
+}
+
+// GetByCategories mocks base method
+func (m *MockPostsRepositoryInterface) GetByCategories(arg0 []string) ([]*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByCategories", arg0)
+	ret0, _ := ret[0].([]*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByCategories indicates an expected call of GetByCategories
+func (mr *MockPostsRepositoryInterfaceMockRecorder) GetByCategories(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByCategories", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).GetByCategories), arg0)
+}
+
 // GetByID mocks base method
 func (m *MockPostsRepositoryInterface) GetByID(arg0 bson.ObjectId) (*posts.Post, error) {
 	m.ctrl.T.Helper()
@@ -96,107 +141,224 @@ func (mr *MockPostsRepositoryInterfaceMockRecorder) GetByUserLogin(arg0 interfac
 }
 
 // Add mocks base method
-func (m *MockPostsRepositoryInterface) Add(arg0 *user.User, arg1, arg2, arg3, arg4, arg5 string) (*posts.Post, error) {
+func (m *MockPostsRepositoryInterface) Add(arg0 *user.User, arg1, arg2, arg3, arg4, arg5 string, arg6 posts.Flags, arg7 posts.Announce) (*posts.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret := m.ctrl.Call(m, "Add", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 	ret0, _ := ret[0].(*posts.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Add indicates an expected call of Add
-func (mr *MockPostsRepositoryInterfaceMockRecorder) Add(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+func (mr *MockPostsRepositoryInterfaceMockRecorder) Add(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Add), arg0, arg1, arg2, arg3, arg4, arg5)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Add), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 }
 
-// AddComment mocks base method
-func (m *MockPostsRepositoryInterface) AddComment(arg0, arg1 bson.ObjectId) (*posts.Post, error) {
+// AddImage mocks base method
+func (m *MockPostsRepositoryInterface) AddImage(arg0 *user.User, arg1, arg2 string, arg3 *posts.Image, arg4 posts.Flags, arg5 posts.Announce) (*posts.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddComment", arg0, arg1)
+	ret := m.ctrl.Call(m, "AddImage", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].(*posts.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddComment indicates an expected call of AddComment
-func (mr *MockPostsRepositoryInterfaceMockRecorder) AddComment(arg0, arg1 interface{}) *gomock.Call {
+// AddImage indicates an expected call of AddImage
+func (mr *MockPostsRepositoryInterfaceMockRecorder) AddImage(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImage", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).AddImage), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// AddPoll mocks base method
+func (m *MockPostsRepositoryInterface) AddPoll(arg0 *user.User, arg1, arg2, arg3 string, arg4 *posts.Poll, arg5 posts.Flags, arg6 posts.Announce) (*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPoll", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPoll indicates an expected call of AddPoll
+func (mr *MockPostsRepositoryInterfaceMockRecorder) AddPoll(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPoll", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).AddPoll), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// VotePoll mocks base method
+func (m *MockPostsRepositoryInterface) VotePoll(arg0 *user.User, arg1 bson.ObjectId, arg2 int) (*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VotePoll", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VotePoll indicates an expected call of VotePoll
+func (mr *MockPostsRepositoryInterfaceMockRecorder) VotePoll(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VotePoll", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).VotePoll), arg0, arg1, arg2)
+}
+
+// ChangePollVote mocks base method
+func (m *MockPostsRepositoryInterface) ChangePollVote(arg0 *user.User, arg1 bson.ObjectId, arg2 int) (*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePollVote", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePollVote indicates an expected call of ChangePollVote
+func (mr *MockPostsRepositoryInterfaceMockRecorder) ChangePollVote(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePollVote", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).ChangePollVote), arg0, arg1, arg2)
+}
+
+// Crosspost mocks base method
+func (m *MockPostsRepositoryInterface) Crosspost(arg0 *user.User, arg1 *posts.Post, arg2, arg3 string, arg4 posts.Flags, arg5 posts.Announce) (*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Crosspost", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Crosspost indicates an expected call of Crosspost
+func (mr *MockPostsRepositoryInterfaceMockRecorder) Crosspost(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Crosspost", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Crosspost), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// SetFlags mocks base method
+func (m *MockPostsRepositoryInterface) SetFlags(arg0 bson.ObjectId, arg1 string, arg2, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFlags", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFlags indicates an expected call of SetFlags
+func (mr *MockPostsRepositoryInterfaceMockRecorder) SetFlags(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFlags", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).SetFlags), arg0, arg1, arg2, arg3)
+}
+
+// SetSticky mocks base method
+func (m *MockPostsRepositoryInterface) SetSticky(arg0 *posts.Post, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSticky", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSticky indicates an expected call of SetSticky
+func (mr *MockPostsRepositoryInterfaceMockRecorder) SetSticky(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSticky", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).SetSticky), arg0, arg1)
+}
+
+// SetLocked mocks base method
+func (m *MockPostsRepositoryInterface) SetLocked(arg0 bson.ObjectId, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLocked", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLocked indicates an expected call of SetLocked
+func (mr *MockPostsRepositoryInterfaceMockRecorder) SetLocked(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddComment", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).AddComment), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLocked", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).SetLocked), arg0, arg1)
 }
 
-// UpViews mocks base method
-func (m *MockPostsRepositoryInterface) UpViews(arg0 bson.ObjectId) error {
+// SetPending mocks base method
+func (m *MockPostsRepositoryInterface) SetPending(arg0 *posts.Post, arg1 bool, arg2 posts.Announce) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpViews", arg0)
+	ret := m.ctrl.Call(m, "SetPending", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpViews indicates an expected call of UpViews
-func (mr *MockPostsRepositoryInterfaceMockRecorder) UpViews(arg0 interface{}) *gomock.Call {
+// SetPending indicates an expected call of SetPending
+func (mr *MockPostsRepositoryInterfaceMockRecorder) SetPending(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPending", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).SetPending), arg0, arg1, arg2)
+}
+
+// AddComment mocks base method
+func (m *MockPostsRepositoryInterface) AddComment(arg0, arg1 bson.ObjectId, arg2 posts.Announce) (*posts.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddComment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*posts.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddComment indicates an expected call of AddComment
+func (mr *MockPostsRepositoryInterfaceMockRecorder) AddComment(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpViews", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).UpViews), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddComment", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).AddComment), arg0, arg1, arg2)
 }
 
 // DeleteComment mocks base method
-func (m *MockPostsRepositoryInterface) DeleteComment(arg0, arg1 bson.ObjectId) (*posts.Post, error) {
+func (m *MockPostsRepositoryInterface) DeleteComment(arg0, arg1 bson.ObjectId, arg2 posts.Announce) (*posts.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteComment", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteComment", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*posts.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteComment indicates an expected call of DeleteComment
-func (mr *MockPostsRepositoryInterfaceMockRecorder) DeleteComment(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockPostsRepositoryInterfaceMockRecorder) DeleteComment(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).DeleteComment), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).DeleteComment), arg0, arg1, arg2)
 }
 
 // Upvote mocks base method
-func (m *MockPostsRepositoryInterface) Upvote(arg0 *user.User, arg1 bson.ObjectId) (*posts.Post, error) {
+func (m *MockPostsRepositoryInterface) Upvote(arg0 *user.User, arg1 bson.ObjectId, arg2 posts.Announce) (*posts.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Upvote", arg0, arg1)
+	ret := m.ctrl.Call(m, "Upvote", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*posts.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Upvote indicates an expected call of Upvote
-func (mr *MockPostsRepositoryInterfaceMockRecorder) Upvote(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockPostsRepositoryInterfaceMockRecorder) Upvote(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upvote", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Upvote), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upvote", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Upvote), arg0, arg1, arg2)
 }
 
 // Downvote mocks base method
-func (m *MockPostsRepositoryInterface) Downvote(arg0 *user.User, arg1 bson.ObjectId) (*posts.Post, error) {
+func (m *MockPostsRepositoryInterface) Downvote(arg0 *user.User, arg1 bson.ObjectId, arg2 posts.Announce) (*posts.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Downvote", arg0, arg1)
+	ret := m.ctrl.Call(m, "Downvote", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*posts.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Downvote indicates an expected call of Downvote
-func (mr *MockPostsRepositoryInterfaceMockRecorder) Downvote(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockPostsRepositoryInterfaceMockRecorder) Downvote(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Downvote", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Downvote), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Downvote", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Downvote), arg0, arg1, arg2)
 }
 
 // Delete mocks base method
-func (m *MockPostsRepositoryInterface) Delete(arg0 bson.ObjectId) (bool, error) {
+func (m *MockPostsRepositoryInterface) Delete(arg0 bson.ObjectId, arg1 func() error) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete
-func (mr *MockPostsRepositoryInterfaceMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockPostsRepositoryInterfaceMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostsRepositoryInterface)(nil).Delete), arg0, arg1)
 }
 
 // MockCommentsRepositoryInterface is a mock of CommentsRepositoryInterface interface
@@ -223,18 +385,18 @@ func (m *MockCommentsRepositoryInterface) EXPECT() *MockCommentsRepositoryInterf
 }
 
 // NewComment mocks base method
-func (m *MockCommentsRepositoryInterface) NewComment(arg0 *user.User, arg1 string) (bson.ObjectId, error) {
+func (m *MockCommentsRepositoryInterface) NewComment(arg0 *user.User, arg1 string, arg2 bson.ObjectId) (bson.ObjectId, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewComment", arg0, arg1)
+	ret := m.ctrl.Call(m, "NewComment", arg0, arg1, arg2)
 	ret0, _ := ret[0].(bson.ObjectId)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewComment indicates an expected call of NewComment
-func (mr *MockCommentsRepositoryInterfaceMockRecorder) NewComment(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCommentsRepositoryInterfaceMockRecorder) NewComment(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewComment", reflect.TypeOf((*MockCommentsRepositoryInterface)(nil).NewComment), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewComment", reflect.TypeOf((*MockCommentsRepositoryInterface)(nil).NewComment), arg0, arg1, arg2)
 }
 
 // GetByID mocks base method
@@ -266,3 +428,129 @@ func (mr *MockCommentsRepositoryInterfaceMockRecorder) DelComment(arg0 interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelComment", reflect.TypeOf((*MockCommentsRepositoryInterface)(nil).DelComment), arg0)
 }
+
+// MockUnfurlerInterface is a mock of UnfurlerInterface interface
+type MockUnfurlerInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnfurlerInterfaceMockRecorder
+}
+
+// MockUnfurlerInterfaceMockRecorder is the mock recorder for MockUnfurlerInterface
+type MockUnfurlerInterfaceMockRecorder struct {
+	mock *MockUnfurlerInterface
+}
+
+// NewMockUnfurlerInterface creates a new mock instance
+func NewMockUnfurlerInterface(ctrl *gomock.Controller) *MockUnfurlerInterface {
+	mock := &MockUnfurlerInterface{ctrl: ctrl}
+	mock.recorder = &MockUnfurlerInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUnfurlerInterface) EXPECT() *MockUnfurlerInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Enqueue mocks base method
+func (m *MockUnfurlerInterface) Enqueue(arg0 bson.ObjectId, arg1 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Enqueue indicates an expected call of Enqueue
+func (mr *MockUnfurlerInterfaceMockRecorder) Enqueue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockUnfurlerInterface)(nil).Enqueue), arg0, arg1)
+}
+
+// MockViewCounterInterface is a mock of ViewCounterInterface interface
+type MockViewCounterInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockViewCounterInterfaceMockRecorder
+}
+
+// MockViewCounterInterfaceMockRecorder is the mock recorder for MockViewCounterInterface
+type MockViewCounterInterfaceMockRecorder struct {
+	mock *MockViewCounterInterface
+}
+
+// NewMockViewCounterInterface creates a new mock instance
+func NewMockViewCounterInterface(ctrl *gomock.Controller) *MockViewCounterInterface {
+	mock := &MockViewCounterInterface{ctrl: ctrl}
+	mock.recorder = &MockViewCounterInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockViewCounterInterface) EXPECT() *MockViewCounterInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method
+func (m *MockViewCounterInterface) Count(arg0 bson.ObjectId, arg1 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Count indicates an expected call of Count
+func (mr *MockViewCounterInterfaceMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockViewCounterInterface)(nil).Count), arg0, arg1)
+}
+
+// MockImageStoreInterface is a mock of ImageStoreInterface interface
+type MockImageStoreInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockImageStoreInterfaceMockRecorder
+}
+
+// MockImageStoreInterfaceMockRecorder is the mock recorder for MockImageStoreInterface
+type MockImageStoreInterfaceMockRecorder struct {
+	mock *MockImageStoreInterface
+}
+
+// NewMockImageStoreInterface creates a new mock instance
+func NewMockImageStoreInterface(ctrl *gomock.Controller) *MockImageStoreInterface {
+	mock := &MockImageStoreInterface{ctrl: ctrl}
+	mock.recorder = &MockImageStoreInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockImageStoreInterface) EXPECT() *MockImageStoreInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Save mocks base method
+func (m *MockImageStoreInterface) Save(arg0 []byte) (*posts.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0)
+	ret0, _ := ret[0].(*posts.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Save indicates an expected call of Save
+func (mr *MockImageStoreInterfaceMockRecorder) Save(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockImageStoreInterface)(nil).Save), arg0)
+}
+
+// Delete mocks base method
+func (m *MockImageStoreInterface) Delete(arg0 *posts.Image) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockImageStoreInterfaceMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockImageStoreInterface)(nil).Delete), arg0)
+}
@@ -5,9 +5,16 @@ import (
 	"fmt"
 	"html/template"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"reddit/pkg/automod"
+	"reddit/pkg/category"
+	"reddit/pkg/clientip"
+	"reddit/pkg/events"
 	"reddit/pkg/session"
 	"reddit/pkg/user"
+	"reddit/pkg/views"
+	"time"
 
 	"reddit/pkg/posts"
 
@@ -19,51 +26,118 @@ import (
 type PostsRepositoryInterface interface {
 	GetAll() ([]*posts.Post, error)
 	GetCategory(string) ([]*posts.Post, error)
+	GetByDomain(string) ([]*posts.Post, error)
+	GetByLink(string) ([]*posts.Post, error)
+	GetByCategories([]string) ([]*posts.Post, error)
 	GetByID(bson.ObjectId) (*posts.Post, error)
 	GetByUserLogin(string) ([]*posts.Post, error)
-	Add(*user.User, string, string, string, string, string) (*posts.Post, error)
-	AddComment(bson.ObjectId, bson.ObjectId) (*posts.Post, error)
-	UpViews(bson.ObjectId) error
-	DeleteComment(bson.ObjectId, bson.ObjectId) (*posts.Post, error)
-	Upvote(*user.User, bson.ObjectId) (*posts.Post, error)
-	Downvote(*user.User, bson.ObjectId) (*posts.Post, error)
-	Delete(bson.ObjectId) (bool, error)
+	Add(*user.User, string, string, string, string, string, posts.Flags, posts.Announce) (*posts.Post, error)
+	AddImage(*user.User, string, string, *posts.Image, posts.Flags, posts.Announce) (*posts.Post, error)
+	AddPoll(*user.User, string, string, string, *posts.Poll, posts.Flags, posts.Announce) (*posts.Post, error)
+	VotePoll(*user.User, bson.ObjectId, int) (*posts.Post, error)
+	ChangePollVote(*user.User, bson.ObjectId, int) (*posts.Post, error)
+	Crosspost(*user.User, *posts.Post, string, string, posts.Flags, posts.Announce) (*posts.Post, error)
+	SetFlags(bson.ObjectId, string, bool, bool) error
+	SetSticky(*posts.Post, bool) error
+	SetLocked(bson.ObjectId, bool) error
+	SetPending(*posts.Post, bool, posts.Announce) error
+	AddComment(bson.ObjectId, bson.ObjectId, posts.Announce) (*posts.Post, error)
+	DeleteComment(bson.ObjectId, bson.ObjectId, posts.Announce) (*posts.Post, error)
+	Upvote(*user.User, bson.ObjectId, posts.Announce) (*posts.Post, error)
+	Downvote(*user.User, bson.ObjectId, posts.Announce) (*posts.Post, error)
+	Delete(bson.ObjectId, func() error) (bool, error)
 }
 type CommentsRepositoryInterface interface {
-	NewComment(*user.User, string) (bson.ObjectId, error)
+	NewComment(*user.User, string, bson.ObjectId) (bson.ObjectId, error)
 	GetByID(bson.ObjectId) (*posts.Comment, error)
 	DelComment(bson.ObjectId) (bool, error)
 }
 
+// UnfurlerInterface fetches link previews in background after the post is saved.
+type UnfurlerInterface interface {
+	Enqueue(bson.ObjectId, string) bool
+}
+
+// ViewCounterInterface counts a view of a post once per viewer in a while.
+type ViewCounterInterface interface {
+	Count(bson.ObjectId, string) bool
+}
+
+// ImageStoreInterface keeps the pictures of image posts.
+type ImageStoreInterface interface {
+	Save([]byte) (*posts.Image, error)
+	Delete(*posts.Image) error
+}
+
 type PostsHandler struct {
-	Tmpl        *template.Template
-	PostsRepo   PostsRepositoryInterface
-	CommentRepo CommentsRepositoryInterface
-	Logger      *zap.SugaredLogger
+	Tmpl             *template.Template
+	PostsRepo        PostsRepositoryInterface
+	CommentRepo      CommentsRepositoryInterface
+	CategoryRepo     CategoryRepositoryInterface
+	SubscriptionRepo SubscriptionRepositoryInterface
+	Searcher         SearcherInterface
+	Unfurler         UnfurlerInterface
+	Images           ImageStoreInterface
+	Preferences      PreferencesRepositoryInterface
+	SavedRepo        SavedRepositoryInterface
+	MarksRepo        MarksRepositoryInterface
+	Views            ViewCounterInterface
+	ReportsRepo      ReportsRepositoryInterface
+	ModLogRepo       ModLogRepositoryInterface
+	BansRepo         CategoryBansRepositoryInterface
+	Automod          AutomodInterface
+	DomainsRepo      DomainsRepositoryInterface
+	Events           EventsOutboxInterface
+	Live             LiveInterface
+	Admins           map[string]bool  // usernames of the site admins
+	Proxies          clientip.Proxies // trusted about X-Forwarded-For
+	Logger           *zap.SugaredLogger
 }
 
 type NewPostRequest struct {
-	Category string `json:"category"`
-	Text     string `json:"text,omitempty"`
-	Link     string `json:"url,omitempty"`
-	Title    string `json:"title"`
-	Type     string `json:"type"`
+	Category string   `json:"category"`
+	Text     string   `json:"text,omitempty"`
+	Link     string   `json:"url,omitempty"`
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options,omitempty"`
+	Closes   string   `json:"closes,omitempty"`
+	Resubmit bool     `json:"resubmit,omitempty"` // post a link already in the category
+	Flair    string   `json:"flair,omitempty"`
+	NSFW     bool     `json:"nsfw,omitempty"`
+	Spoiler  bool     `json:"spoiler,omitempty"`
 }
 
 type PostResponse struct {
-	Author           *user.User       `json:"author"`
-	Category         string           `json:"category"`
-	Comments         []*posts.Comment `json:"comments"`
-	Created          string           `json:"created"`
-	ID               bson.ObjectId    `json:"id,string"`
-	Score            int              `json:"score"`
-	Text             string           `json:"text,omitempty"`
-	Link             string           `json:"url,omitempty"`
-	Title            string           `json:"title"`
-	Type             string           `json:"type"`
-	UpvotePercentage int              `json:"upvotePercentage"`
-	Views            int              `json:"views"`
-	Votes            []posts.Vote     `json:"votes"`
+	Author           *user.User            `json:"author"`
+	Category         string                `json:"category"`
+	Comments         []*posts.Comment      `json:"comments"`
+	Created          string                `json:"created"`
+	ID               bson.ObjectId         `json:"id,string"`
+	Score            int                   `json:"score"`
+	Text             string                `json:"text,omitempty"`
+	Link             string                `json:"url,omitempty"`
+	Domain           string                `json:"domain,omitempty"`
+	Title            string                `json:"title"`
+	Type             string                `json:"type"`
+	UpvotePercentage int                   `json:"upvotePercentage"`
+	Views            int                   `json:"views"`
+	Votes            []posts.Vote          `json:"votes"`
+	Preview          *posts.LinkPreview    `json:"preview,omitempty"`
+	Image            *posts.Image          `json:"image,omitempty"`
+	Poll             *PollResponse         `json:"poll,omitempty"`
+	Crosspost        *posts.Crosspost      `json:"crosspost,omitempty"`
+	Flair            string                `json:"flair,omitempty"`
+	NSFW             bool                  `json:"nsfw,omitempty"`
+	Spoiler          bool                  `json:"spoiler,omitempty"`
+	Saved            bool                  `json:"saved,omitempty"`
+	Hidden           bool                  `json:"hidden,omitempty"`
+	Seen             bool                  `json:"seen,omitempty"`
+	Sticky           bool                  `json:"sticky,omitempty"`
+	Locked           bool                  `json:"locked,omitempty"`
+	Archived         bool                  `json:"archived,omitempty"`
+	Pending          bool                  `json:"pending,omitempty"`
+	OtherDiscussions []*DiscussionResponse `json:"otherDiscussions,omitempty"`
 }
 
 func PostToPostResponse(post *posts.Post, commentsRepo CommentsRepositoryInterface) (*PostResponse, error) {
@@ -84,11 +158,25 @@ func PostToPostResponse(post *posts.Post, commentsRepo CommentsRepositoryInterfa
 		Score:            post.Score,
 		Text:             post.Text,
 		Link:             post.Link,
+		Domain:           post.Domain,
 		Title:            post.Title,
 		Type:             post.Type,
 		UpvotePercentage: post.UpvotePercentage,
 		Views:            post.Views,
 		Votes:            post.Votes,
+		Preview:          post.Preview,
+		Image:            post.Image,
+		Crosspost:        post.Crosspost,
+		Flair:            post.Flair,
+		NSFW:             post.NSFW,
+		Spoiler:          post.Spoiler,
+		Sticky:           post.Sticky,
+		Locked:           post.Locked,
+		Archived:         post.Archived,
+		Pending:          post.Pending,
+	}
+	if post.Poll != nil {
+		postResponse.Poll = NewPollResponse(post.Poll, time.Now())
 	}
 	return postResponse, nil
 }
@@ -112,6 +200,9 @@ func (h *PostsHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("DB err: %v", err)
 		return
 	}
+	posts = h.visiblePosts(r, posts)
+	marks := h.userMarks(r, posts)
+	posts = withoutHidden(r, posts, marks)
 
 	postsResponse := make([]*PostResponse, 0)
 
@@ -124,6 +215,8 @@ func (h *PostsHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 		}
 		postsResponse = append(postsResponse, postResponse)
 	}
+	markSaved(r, postsResponse, h.SavedRepo, h.Logger.Errorf)
+	applyMarks(postsResponse, marks)
 
 	resp, _ := json.Marshal(postsResponse)
 	w.Write(resp)
@@ -133,16 +226,20 @@ func (h *PostsHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 func (h *PostsHandler) ListCategory(w http.ResponseWriter, r *http.Request) {
 	arg := mux.Vars(r)
 	cat, _ := arg["CATEGORY"]
-	posts, err := h.PostsRepo.GetCategory(cat)
+	categoryPosts, err := h.PostsRepo.GetCategory(cat)
 	if err != nil {
 		http.Error(w, `DB error`, http.StatusInternalServerError)
 		h.Logger.Errorf("Bad category: %v", err)
 		return
 	}
+	categoryPosts = h.visiblePosts(r, categoryPosts)
+	marks := h.userMarks(r, categoryPosts)
+	categoryPosts = withoutHidden(r, categoryPosts, marks)
+	posts.StickyFirst(categoryPosts)
 
 	postsResponse := make([]*PostResponse, 0)
 
-	for _, post := range posts {
+	for _, post := range categoryPosts {
 		postResponse, err := PostToPostResponse(post, h.CommentRepo)
 		if err != nil {
 			http.Error(w, `DB error`, http.StatusInternalServerError)
@@ -151,6 +248,8 @@ func (h *PostsHandler) ListCategory(w http.ResponseWriter, r *http.Request) {
 		}
 		postsResponse = append(postsResponse, postResponse)
 	}
+	markSaved(r, postsResponse, h.SavedRepo, h.Logger.Errorf)
+	applyMarks(postsResponse, marks)
 
 	resp, _ := json.Marshal(postsResponse)
 	w.Write(resp)
@@ -166,6 +265,7 @@ func (h *PostsHandler) ListByUserLogin(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Bad login: %v", err)
 		return
 	}
+	posts = h.visiblePosts(r, posts)
 
 	postsResponse := make([]*PostResponse, 0)
 
@@ -178,6 +278,7 @@ func (h *PostsHandler) ListByUserLogin(w http.ResponseWriter, r *http.Request) {
 		}
 		postsResponse = append(postsResponse, postResponse)
 	}
+	markSaved(r, postsResponse, h.SavedRepo, h.Logger.Errorf)
 
 	resp, _ := json.Marshal(postsResponse)
 	w.Write(resp)
@@ -198,24 +299,92 @@ func (h *PostsHandler) ListByID(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("DB err: %v", err)
 		return
 	}
-	err = h.PostsRepo.UpViews(postID)
-	if err != nil {
-		http.Error(w, `DB err`, http.StatusInternalServerError)
-		h.Logger.Errorf("Up view err: %v", err)
+	if h.hidden(r, post) {
+		http.Error(w, "No post", http.StatusNotFound)
 		return
 	}
+	// views are counted only by the counter, which adds them with $inc
+	if h.Views != nil {
+		h.countView(r, postID)
+	}
+	h.recordSeen(r, postID)
 	postResponse, err := PostToPostResponse(post, h.CommentRepo)
 	if err != nil {
 		http.Error(w, `DB err`, http.StatusInternalServerError)
 		h.Logger.Errorf("Post Transform error: %v", err)
 		return
 	}
+	markSaved(r, []*PostResponse{postResponse}, h.SavedRepo, h.Logger.Errorf)
+	postResponse.OtherDiscussions = h.otherDiscussions(post)
 
 	resp, _ := json.Marshal(postResponse)
 	w.Write(resp)
 	h.Logger.Infof("List posts by post id")
 }
 
+// hidden tells if the post is kept from the client by the same rules as the listings.
+func (h *PostsHandler) hidden(r *http.Request, post *posts.Post) bool {
+	if post.Pending && !h.canSeePending(r, post) {
+		return true
+	}
+	if !post.NSFW {
+		return false
+	}
+	show, err := showNSFW(r, h.Preferences)
+	if err != nil {
+		h.Logger.Errorf("Can't get preferences: %v", err)
+	}
+	return !show
+}
+
+// canSeePending reports whether the viewer may open a post waiting for
+// approval: only its author and the moderators can.
+func (h *PostsHandler) canSeePending(r *http.Request, post *posts.Post) bool {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		return false
+	}
+	if post.Author != nil && post.Author.ID == sess.User.ID {
+		return true
+	}
+	cat, err := h.CategoryRepo.GetByName(post.Category)
+	if err != nil && err != category.ErrNoCategory {
+		h.Logger.Errorf("DB err: %v", err)
+	}
+	return isModerator(cat, sess.User, h.Admins)
+}
+
+// countView counts the view unless the viewer has seen the post recently or
+// looks like a bot. The counter is flushed to DB in background.
+func (h *PostsHandler) countView(r *http.Request, postID bson.ObjectId) {
+	if views.IsBot(r.UserAgent()) {
+		return
+	}
+	var userID int64
+	sess, err := session.SessionFromContext(r.Context())
+	if err == nil {
+		userID = sess.User.ID
+	}
+	h.Views.Count(postID, views.Viewer(userID, h.Proxies.ClientIP(r)))
+}
+
+// checkCategory answers with an error and returns false if the user can't
+// add posts to the category.
+func (h *PostsHandler) checkCategory(w http.ResponseWriter, name string, u *user.User) (*category.Category, bool) {
+	cat, err := h.CategoryRepo.GetByName(name)
+	if err == category.ErrNoCategory {
+		writeFieldError(w, "category", name, "unknown category")
+		h.Logger.Errorf("Unknown category: %v", name)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "BD error", http.StatusInternalServerError)
+		h.Logger.Errorf("Bad get category. Error: %v", err)
+		return nil, false
+	}
+	return cat, h.checkNotBanned(w, name, u)
+}
+
 func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 	sess, err := session.SessionFromContext(r.Context())
 	if err != nil {
@@ -223,28 +392,111 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Bad auth. Error: %v", err)
 		return
 	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		h.addImage(w, r, sess)
+		return
+	}
 	newRequest := new(NewPostRequest)
-	body, errReadBody := ioutil.ReadAll(r.Body)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
 	err = json.Unmarshal(body, newRequest)
 	if errReadBody != nil || err != nil {
 		http.Error(w, "Internal error", http.StatusBadRequest)
 		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
 		return
 	}
-
-	newPost, err := h.PostsRepo.Add(
-		sess.User,
+	validationErrors := posts.ValidatePost(
 		newRequest.Category,
 		newRequest.Title,
 		newRequest.Type,
 		newRequest.Text,
 		newRequest.Link,
 	)
+	if newRequest.Type == "poll" {
+		validationErrors = append(validationErrors,
+			posts.ValidatePoll(newRequest.Options, newRequest.Closes, time.Now())...)
+	}
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad new post: %v", validationErrors)
+		return
+	}
+
+	cat, ok := h.checkCategory(w, newRequest.Category, sess.User)
+	if !ok {
+		return
+	}
+	flags := posts.Flags{
+		Flair:   newRequest.Flair,
+		NSFW:    newRequest.NSFW,
+		Spoiler: newRequest.Spoiler,
+	}
+	if !h.checkFlair(w, cat, flags.Flair) {
+		return
+	}
+	var domainFlag string
+	if newRequest.Type == "link" {
+		domainFlag, ok = h.checkDomain(w, newRequest.Link)
+		if !ok {
+			return
+		}
+		if !newRequest.Resubmit && !h.checkDuplicate(w, newRequest.Category, newRequest.Link) {
+			return
+		}
+	}
+	decision, ok := h.automodCheck(w, &automod.Submission{
+		Kind:     posts.ItemPost,
+		Author:   sess.User,
+		Category: newRequest.Category,
+		Title:    newRequest.Title,
+		Body:     newRequest.Text,
+		Link:     newRequest.Link,
+	})
+	if !ok {
+		return
+	}
+
+	announce := h.postCreated()
+	if needsApproval(decision) {
+		// announced once a moderator approves it
+		announce = nil
+	}
+	var newPost *posts.Post
+	if newRequest.Type == "poll" {
+		newPost, err = h.PostsRepo.AddPoll(
+			sess.User,
+			newRequest.Category,
+			newRequest.Title,
+			newRequest.Text,
+			&posts.Poll{
+				Options: newRequest.Options,
+				Closes:  newRequest.Closes,
+			},
+			flags,
+			announce,
+		)
+	} else {
+		newPost, err = h.PostsRepo.Add(
+			sess.User,
+			newRequest.Category,
+			newRequest.Title,
+			newRequest.Type,
+			newRequest.Text,
+			newRequest.Link,
+			flags,
+			announce,
+		)
+	}
 	if err != nil {
 		http.Error(w, "BD error", http.StatusInternalServerError)
 		h.Logger.Errorf("Bad add post. Error: %v, %v", err)
 		return
 	}
+	h.applyAutomod(decision, posts.ItemPost, newPost.ID, newPost)
+	if domainFlag != "" {
+		h.autoReport(posts.ItemPost, newPost.ID, newPost, domainFlag)
+	}
+	h.eventsStaged()
 
 	postResponse, err := PostToPostResponse(newPost, h.CommentRepo)
 	if err != nil {
@@ -252,6 +504,10 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Post Transform error: %v", err)
 		return
 	}
+	h.indexPost(newPost, postResponse.Comments)
+	if newPost.Type == "link" && h.Unfurler != nil {
+		h.Unfurler.Enqueue(newPost.ID, newPost.Link)
+	}
 
 	answer, _ := json.Marshal(postResponse)
 	w.Write(answer)
@@ -272,12 +528,17 @@ func (h *PostsHandler) Upvote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	postID := bson.ObjectIdHex(arg["POST_ID"])
-	post, err := h.PostsRepo.Upvote(sess.User, postID)
+	post, err := h.PostsRepo.Upvote(sess.User, postID, h.voteCast(sess.User, 1))
+	if err == posts.ErrArchived {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, `Bad upvote`, http.StatusInternalServerError)
 		h.Logger.Errorf("Bad upvote. Error: %v", err)
 		return
 	}
+	h.eventsStaged()
 
 	postResponse, err := PostToPostResponse(post, h.CommentRepo)
 	if err != nil {
@@ -286,6 +547,8 @@ func (h *PostsHandler) Upvote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishScore(post)
+
 	resp, _ := json.Marshal(postResponse)
 	w.Write(resp)
 	h.Logger.Infof("Upvote post")
@@ -305,12 +568,17 @@ func (h *PostsHandler) Downvote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	postID := bson.ObjectIdHex(arg["POST_ID"])
-	post, err := h.PostsRepo.Downvote(sess.User, postID)
+	post, err := h.PostsRepo.Downvote(sess.User, postID, h.voteCast(sess.User, -1))
+	if err == posts.ErrArchived {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, `Bad downvote`, http.StatusInternalServerError)
 		h.Logger.Errorf("Bad downvote. Error: %v", err)
 		return
 	}
+	h.eventsStaged()
 
 	postResponse, err := PostToPostResponse(post, h.CommentRepo)
 	if err != nil {
@@ -319,6 +587,8 @@ func (h *PostsHandler) Downvote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishScore(post)
+
 	resp, _ := json.Marshal(postResponse)
 	w.Write(resp)
 	h.Logger.Infof("Downvote post")
@@ -337,7 +607,7 @@ func (h *PostsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	postID := bson.ObjectIdHex(arg["POST_ID"])
-	ok, err := h.PostsRepo.Delete(postID)
+	ok, err := h.removePost(postID)
 	if err != nil {
 		http.Error(w, `Delete error`, http.StatusInternalServerError)
 		h.Logger.Errorf("Delete error: %v", err)
@@ -351,3 +621,37 @@ func (h *PostsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Infof("Delete post failure")
 	}
 }
+
+// removePost deletes the post together with everything which refers to it.
+func (h *PostsHandler) removePost(postID bson.ObjectId) (bool, error) {
+	var post *posts.Post
+	if h.Images != nil || h.Live != nil || h.Events != nil {
+		post, _ = h.PostsRepo.GetByID(postID)
+	}
+	deleted := &events.DeletedData{PostID: postID}
+	if post != nil {
+		deleted.Category = post.Category
+	}
+	// the removed post can't carry its event, it is held before
+	event, err := holdEvent(h.Events, events.PostDeleted, deleted)
+	if err != nil {
+		return false, err
+	}
+	ok, err := h.PostsRepo.Delete(postID, h.collectEvents())
+	releaseErr := releaseEvent(h.Events, event, ok, err)
+	if releaseErr != nil {
+		h.Logger.Errorf("Can't release %v: %v", events.PostDeleted, releaseErr)
+	}
+	if err != nil || !ok {
+		return ok, err
+	}
+	h.publish(post, LivePostDeleted, &LiveDeleteData{Post: postID})
+	h.unindexPost(postID)
+	h.forgetSaved(postID)
+	h.forgetMarks(postID)
+	h.closeReports(postID)
+	if h.Images != nil && post != nil && post.Image != nil {
+		h.deleteImage(post.Image)
+	}
+	return true, nil
+}
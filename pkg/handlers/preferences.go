@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+)
+
+type PreferencesRepositoryInterface interface {
+	Get(int64) (*user.Preferences, error)
+	Set(int64, *user.Preferences) error
+}
+
+func (h *UserHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	prefs, err := h.PreferencesRepo.Get(sess.User.ID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(prefs)
+	w.Write(resp)
+	h.Logger.Infof("Preferences of %v", sess.User.Username)
+}
+
+func (h *UserHandler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	prefs := new(user.Preferences)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, prefs)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	err = h.PreferencesRepo.Set(sess.User.ID, prefs)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(prefs)
+	w.Write(resp)
+	h.Logger.Infof("Preferences of %v were updated", sess.User.Username)
+}
+
+// showNSFW tells if the request comes from a user who opted in to NSFW posts.
+func showNSFW(r *http.Request, prefsRepo PreferencesRepositoryInterface) (bool, error) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil || prefsRepo == nil {
+		return false, nil
+	}
+	prefs, err := prefsRepo.Get(sess.User.ID)
+	if err != nil {
+		return false, err
+	}
+	return prefs.ShowNSFW, nil
+}
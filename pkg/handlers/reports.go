@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reddit/pkg/moderation"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type ReportsRepositoryInterface interface {
+	Add(*moderation.Report) (bool, error)
+	GetOpen(string) ([]*moderation.Report, error)
+	GetOpenByItem(bson.ObjectId) ([]*moderation.Report, error)
+	Resolve(bson.ObjectId, string) (int, error)
+}
+
+type ModLogRepositoryInterface interface {
+	Add(*moderation.LogEntry) error
+	GetByCategory(string, int, int) ([]*moderation.LogEntry, int, error)
+}
+
+type ReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ModActionRequest is a moderator decision about a reported item.
+type ModActionRequest struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	PostID string `json:"postId"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// QueueItemResponse is a reported item together with its content, which is
+// missing if the item was deleted.
+type QueueItemResponse struct {
+	*moderation.QueueItem
+	Post    *PostResponse  `json:"post,omitempty"`
+	Comment *posts.Comment `json:"comment,omitempty"`
+}
+
+type ModLogPage struct {
+	Entries []*moderation.LogEntry `json:"entries"`
+	Page    int                    `json:"page"`
+	Limit   int                    `json:"limit"`
+	Total   int                    `json:"total"`
+}
+
+// Report complains about a post or, if the URL has a comment ID, a comment.
+// Each user can report an item only once.
+func (h *PostsHandler) Report(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	kind, itemID, postID, ok := h.itemFromURL(w, r)
+	if !ok {
+		return
+	}
+	reportRequest := new(ReportRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err = json.Unmarshal(body, reportRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	validationErrors := moderation.ValidateReport(reportRequest.Reason)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad report: %v", validationErrors)
+		return
+	}
+	post := h.findItem(w, kind, itemID, postID)
+	if post == nil {
+		return
+	}
+
+	added, err := h.ReportsRepo.Add(&moderation.Report{
+		Kind:     kind,
+		ItemID:   itemID,
+		PostID:   postID,
+		Category: post.Category,
+		Reporter: sess.User,
+		Reason:   reportRequest.Reason,
+	})
+	if err != nil {
+		http.Error(w, `Report error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Report error: %v", err)
+		return
+	}
+	if !added {
+		http.Error(w, `Already reported`, http.StatusConflict)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v reported %v %v", sess.User.Username, kind, itemID)
+}
+
+// ModQueue lists the reported items of the category, the most reported first.
+func (h *PostsHandler) ModQueue(w http.ResponseWriter, r *http.Request) {
+	if _, sess := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger); sess == nil {
+		return
+	}
+	name := mux.Vars(r)["CATEGORY"]
+	reports, err := h.ReportsRepo.GetOpen(name)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+
+	queue := moderation.Queue(reports)
+	items := make([]*QueueItemResponse, 0, len(queue))
+	for _, queueItem := range queue {
+		item := &QueueItemResponse{QueueItem: queueItem}
+		if queueItem.Kind == posts.ItemComment {
+			item.Comment, err = h.CommentRepo.GetByID(queueItem.ItemID)
+		} else {
+			var post *posts.Post
+			post, err = h.PostsRepo.GetByID(queueItem.ItemID)
+			if err == nil {
+				item.Post, err = PostToPostResponse(post, h.CommentRepo)
+			}
+		}
+		if err != nil && err != mgo.ErrNotFound {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("DB err: %v", err)
+			return
+		}
+		items = append(items, item)
+	}
+
+	resp, _ := json.Marshal(items)
+	w.Write(resp)
+	h.Logger.Infof("Moderation queue of %v", name)
+}
+
+// ModAction approves, removes or dismisses the reported item from the URL.
+func (h *PostsHandler) ModAction(w http.ResponseWriter, r *http.Request) {
+	_, sess := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger)
+	if sess == nil {
+		return
+	}
+	name := mux.Vars(r)["CATEGORY"]
+	if !bson.IsObjectIdHex(mux.Vars(r)["ITEM_ID"]) {
+		http.Error(w, "Bad id", http.StatusBadRequest)
+		h.Logger.Errorf("Bad item id")
+		return
+	}
+	itemID := bson.ObjectIdHex(mux.Vars(r)["ITEM_ID"])
+
+	actionRequest := new(ModActionRequest)
+	body, errReadBody := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	err := json.Unmarshal(body, actionRequest)
+	if errReadBody != nil || err != nil {
+		http.Error(w, "Bad JSON", http.StatusBadRequest)
+		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
+		return
+	}
+	validationErrors := moderation.ValidateAction(actionRequest.Reason)
+	if len(validationErrors) != 0 {
+		writeFieldErrors(w, validationErrors)
+		h.Logger.Errorf("Bad reason: %v", validationErrors)
+		return
+	}
+	postID := itemID
+	if actionRequest.Type == posts.ItemComment {
+		if !bson.IsObjectIdHex(actionRequest.PostID) {
+			writeFieldError(w, "postId", actionRequest.PostID, "is required for comments")
+			return
+		}
+		postID = bson.ObjectIdHex(actionRequest.PostID)
+	} else if actionRequest.Type != posts.ItemPost {
+		writeFieldError(w, "type", actionRequest.Type, "must be one of: post, comment")
+		return
+	}
+
+	status, ok := actionStatus[actionRequest.Action]
+	if !ok {
+		writeFieldError(w, "action", actionRequest.Action, "must be one of: approve, remove, dismiss")
+		return
+	}
+	if !h.reportedItem(w, name, actionRequest.Type, itemID, postID) {
+		return
+	}
+	switch actionRequest.Action {
+	case moderation.ActionApprove:
+		if !h.approveItem(w, name, actionRequest.Type, itemID, postID) {
+			return
+		}
+	case moderation.ActionRemove:
+		if !h.removeItem(w, name, actionRequest.Type, itemID, postID) {
+			return
+		}
+	}
+
+	_, err = h.ReportsRepo.Resolve(itemID, status)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	h.logModAction(sess.User, name, actionRequest.Action, actionRequest.Type, itemID, postID, actionRequest.Reason)
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v: %v %v %v", sess.User.Username, actionRequest.Action, actionRequest.Type, itemID)
+}
+
+// actionStatus is the status of the reports closed by a moderator action.
+var actionStatus = map[string]string{
+	moderation.ActionApprove: moderation.StatusApproved,
+	moderation.ActionDismiss: moderation.StatusDismissed,
+	moderation.ActionRemove:  moderation.StatusRemoved,
+}
+
+// reportedItem checks that the item is reported in the category as a part
+// of the post, so a moderator can only act on the items of their category.
+// It answers with an error and returns false if not.
+func (h *PostsHandler) reportedItem(w http.ResponseWriter, categoryName, kind string, itemID, postID bson.ObjectId) bool {
+	reports, err := h.ReportsRepo.GetOpenByItem(itemID)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return false
+	}
+	var report *moderation.Report
+	for _, open := range reports {
+		if open.Kind == kind {
+			report = open
+			break
+		}
+	}
+	if report == nil {
+		http.Error(w, "No report", http.StatusNotFound)
+		return false
+	}
+	if report.PostID != postID || report.Category != categoryName {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("%v %v isn't reported in %v of %v", kind, itemID, postID, categoryName)
+		return false
+	}
+	return true
+}
+
+// categoryPost finds a post of the category. It answers with an error and
+// returns nil if there is no such post in the category.
+func (h *PostsHandler) categoryPost(w http.ResponseWriter, categoryName string, postID bson.ObjectId) *posts.Post {
+	post, err := h.PostsRepo.GetByID(postID)
+	if err == mgo.ErrNotFound {
+		http.Error(w, "No post", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return nil
+	}
+	if post.Category != categoryName {
+		http.Error(w, `Forbidden`, http.StatusForbidden)
+		h.Logger.Errorf("Post %v isn't in %v", postID, categoryName)
+		return nil
+	}
+	return post
+}
+
+// approveItem shows a post or a comment held by the automoderator. It answers
+// with an error and returns false if the item can't be approved.
+func (h *PostsHandler) approveItem(w http.ResponseWriter, categoryName, kind string, itemID, postID bson.ObjectId) bool {
+	post := h.categoryPost(w, categoryName, postID)
+	if post == nil {
+		return false
+	}
+	var err error
+	if kind == posts.ItemPost {
+		if !post.Pending {
+			return true
+		}
+		err = h.PostsRepo.SetPending(post, false, h.postCreated())
+	} else {
+		for _, commentID := range post.CommentsID {
+			if commentID == itemID {
+				return true
+			}
+		}
+		post, err = h.PostsRepo.AddComment(postID, itemID, h.commentAdded(itemID))
+	}
+	var postResponse *PostResponse
+	if err == nil {
+		postResponse, err = PostToPostResponse(post, h.CommentRepo)
+	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("Approve error: %v", err)
+		return false
+	}
+	h.indexPost(post, postResponse.Comments)
+	h.eventsStaged()
+	if kind == posts.ItemComment {
+		h.commentLive(post, postResponse.Comments, itemID)
+	}
+	return true
+}
+
+// removeItem deletes a post or a comment of the category. It answers with an
+// error and returns false if the item can't be removed.
+func (h *PostsHandler) removeItem(w http.ResponseWriter, categoryName, kind string, itemID, postID bson.ObjectId) bool {
+	post := h.categoryPost(w, categoryName, postID)
+	if post == nil {
+		return false
+	}
+
+	var err error
+	if kind == posts.ItemPost {
+		_, err = h.removePost(postID)
+	} else {
+		_, err = h.CommentRepo.DelComment(itemID)
+		if err == nil || err == mgo.ErrNotFound {
+			h.forgetSaved(itemID)
+			post, err = h.PostsRepo.DeleteComment(postID, itemID, h.commentDeleted(itemID))
+		}
+		var postResponse *PostResponse
+		if err == nil {
+			postResponse, err = PostToPostResponse(post, h.CommentRepo)
+		}
+		if err == nil {
+			h.eventsStaged()
+			h.indexPost(post, postResponse.Comments)
+			h.publish(post, LiveCommentDeleted, &LiveDeleteData{Post: postID, Comment: itemID})
+		}
+	}
+	if err != nil {
+		http.Error(w, `Delete error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Delete error: %v", err)
+		return false
+	}
+	return true
+}
+
+// ModLog shows the moderator actions in the category, newest first.
+func (h *PostsHandler) ModLog(w http.ResponseWriter, r *http.Request) {
+	if _, sess := moderatedCategory(w, r, h.CategoryRepo, h.Admins, h.Logger); sess == nil {
+		return
+	}
+	name := mux.Vars(r)["CATEGORY"]
+	page, limit, skip, ok := pageSkip(w, r, h.Logger)
+	if !ok {
+		return
+	}
+	entries, total, err := h.ModLogRepo.GetByCategory(name, skip, limit)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	resp, _ := json.Marshal(&ModLogPage{
+		Entries: entries,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	})
+	w.Write(resp)
+	h.Logger.Infof("Moderation log of %v, page %v", name, page)
+}
+
+// logModAction records the action in the moderation log. The action is done
+// already, so errors are only logged.
+func (h *PostsHandler) logModAction(moderator *user.User, categoryName, action, kind string, itemID, postID bson.ObjectId, reason string) {
+	if h.ModLogRepo == nil {
+		return
+	}
+	err := h.ModLogRepo.Add(&moderation.LogEntry{
+		Category:  categoryName,
+		Moderator: moderator,
+		Action:    action,
+		Kind:      kind,
+		ItemID:    itemID,
+		PostID:    postID,
+		Reason:    reason,
+	})
+	if err != nil {
+		h.Logger.Errorf("Can't log moderator action %v on %v: %v", action, itemID, err)
+	}
+}
+
+// closeReports resolves the open reports of a deleted post or comment.
+func (h *PostsHandler) closeReports(itemID bson.ObjectId) {
+	if h.ReportsRepo == nil {
+		return
+	}
+	_, err := h.ReportsRepo.Resolve(itemID, moderation.StatusRemoved)
+	if err != nil {
+		h.Logger.Errorf("Can't close reports of %v: %v", itemID, err)
+	}
+}
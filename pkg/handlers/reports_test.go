@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/category"
+	"reddit/pkg/moderation"
+	posts "reddit/pkg/posts"
+	user "reddit/pkg/user"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// openReports are the open reports of an item for the mocked ReportsRepo.
+func openReports(kind string, itemID, postID bson.ObjectId, category string) []*moderation.Report {
+	return []*moderation.Report{{
+		Kind:     kind,
+		ItemID:   itemID,
+		PostID:   postID,
+		Category: category,
+		Reporter: testUser,
+		Reason:   "spam",
+		Status:   moderation.StatusOpen,
+	}}
+}
+
+func TestReports(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockCategoryRepo := NewMockCategoryRepositoryInterface(ctrl)
+	mockReportsRepo := NewMockReportsRepositoryInterface(ctrl)
+	mockModLogRepo := NewMockModLogRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    mockPostsRepo,
+		CommentRepo:  mockCommentsRepo,
+		CategoryRepo: mockCategoryRepo,
+		ReportsRepo:  mockReportsRepo,
+		ModLogRepo:   mockModLogRepo,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	commentID := bson.ObjectIdHex("5ebaf9f33c04c17c56f51299")
+	newPost := func() *posts.Post {
+		return &posts.Post{
+			Author:     testUser,
+			Category:   "music",
+			CommentsID: []bson.ObjectId{},
+			Created:    "2020-05-12T22:33:02+03:00",
+			ID:         postID,
+			Title:      "Spam",
+			Type:       "text",
+			Votes:      []posts.Vote{},
+		}
+	}
+	reportRequest := func(vars map[string]string, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/post/{POST_ID}/report", bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithSession(r), vars)
+	}
+	categoryRequest := func(method, url string, u *user.User, vars map[string]string, body string) *http.Request {
+		r := httptest.NewRequest(method, url, bytes.NewReader([]byte(body)))
+		return mux.SetURLVars(requestWithUser(r, u), vars)
+	}
+	musicVars := map[string]string{"CATEGORY": "music"}
+	postItemVars := map[string]string{"CATEGORY": "music", "ITEM_ID": postID.Hex()}
+
+	testCases := []TestCase{
+		{ //Report SUCCESS
+			Request: reportRequest(map[string]string{"POST_ID": postID.Hex()}, `{"reason": "spam"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockReportsRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.Report,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Report twice
+			Request: reportRequest(map[string]string{"POST_ID": postID.Hex()}, `{"reason": "spam"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockReportsRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+				{false, nil},
+			},
+			HandlerFunc: postsTestHandler.Report,
+			ExpectResult: Result{
+				Body: []byte("Already reported\n"),
+				Code: http.StatusConflict,
+			},
+		},
+		{ //Report without a reason
+			Request:        reportRequest(map[string]string{"POST_ID": postID.Hex()}, `{"reason": " "}`),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    postsTestHandler.Report,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"reason","value":" ","msg":"is required"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Report a missing comment
+			Request: reportRequest(map[string]string{"POST_ID": postID.Hex(), "COMMENT_ID": commentID.Hex()}, `{"reason": "rude"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{newPost(), nil},
+			},
+			HandlerFunc: postsTestHandler.Report,
+			ExpectResult: Result{
+				Body: []byte("No comment\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Modqueue of a user
+			Request: categoryRequest("GET", "/api/categories/music/modqueue", testUser, musicVars, ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.ModQueue,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Modqueue of an unknown category
+			Request: categoryRequest("GET", "/api/categories/jazz/modqueue", testModerator, map[string]string{"CATEGORY": "jazz"}, ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("jazz"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, category.ErrNoCategory},
+			},
+			HandlerFunc: postsTestHandler.ModQueue,
+			ExpectResult: Result{
+				Body: []byte("No category\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Modqueue SUCCESS, the most reported item goes first
+			Request: categoryRequest("GET", "/api/categories/music/modqueue", testModerator, musicVars, ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpen("music"),
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{[]*moderation.Report{
+					{Kind: posts.ItemPost, ItemID: postID, PostID: postID, Reason: "spam", Created: "2020-05-12T22:40:00+03:00"},
+					{Kind: posts.ItemComment, ItemID: commentID, PostID: postID, Reason: "rude", Created: "2020-05-12T22:50:00+03:00"},
+					{Kind: posts.ItemComment, ItemID: commentID, PostID: postID, Reason: "insult", Created: "2020-05-12T22:45:00+03:00"},
+				}, nil},
+				{&posts.Comment{ID: commentID, Autor: testUser, Body: "Go away", Created: "2020-05-12T22:35:00+03:00"}, nil},
+				{newPost(), nil},
+			},
+			HandlerFunc: postsTestHandler.ModQueue,
+			ExpectResult: Result{
+				Body: []byte(`[{"type":"comment","id":"5ebaf9f33c04c17c56f51299","postId":"5ebaf9ee3c04c17c56f51244","count":2,"reasons":["rude","insult"],"reported":"2020-05-12T22:45:00+03:00","comment":{"id":"5ebaf9f33c04c17c56f51299","author":{"username":"rvasily","id":"1"},"body":"Go away","created":"2020-05-12T22:35:00+03:00"}},` +
+					`{"type":"post","id":"5ebaf9ee3c04c17c56f51244","postId":"5ebaf9ee3c04c17c56f51244","count":1,"reasons":["spam"],"reported":"2020-05-12T22:40:00+03:00","post":{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":0,"title":"Spam","type":"text","upvotePercentage":0,"views":0,"votes":[]}}]`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Dismiss reports
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "dismiss", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+				mockReportsRepo.EXPECT().Resolve(postID, moderation.StatusDismissed),
+				mockModLogRepo.EXPECT().Add(&moderation.LogEntry{
+					Category:  "music",
+					Moderator: testModerator,
+					Action:    moderation.ActionDismiss,
+					Kind:      posts.ItemPost,
+					ItemID:    postID,
+					PostID:    postID,
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemPost, postID, postID, "music"), nil},
+				{1, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Remove a reported post
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "remove", "type": "post", "reason": "spam"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockPostsRepo.EXPECT().Delete(postID, gomock.Any()),
+				mockReportsRepo.EXPECT().Resolve(postID, moderation.StatusRemoved),
+				mockReportsRepo.EXPECT().Resolve(postID, moderation.StatusRemoved),
+				mockModLogRepo.EXPECT().Add(&moderation.LogEntry{
+					Category:  "music",
+					Moderator: testModerator,
+					Action:    moderation.ActionRemove,
+					Kind:      posts.ItemPost,
+					ItemID:    postID,
+					PostID:    postID,
+					Reason:    "spam",
+				}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemPost, postID, postID, "music"), nil},
+				{newPost(), nil},
+				{true, nil},
+				{1, nil},
+				{0, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Remove a reported comment
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator,
+				map[string]string{"CATEGORY": "music", "ITEM_ID": commentID.Hex()},
+				`{"action": "remove", "type": "comment", "postId": "5ebaf9ee3c04c17c56f51244"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(commentID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCommentsRepo.EXPECT().DelComment(commentID),
+				mockPostsRepo.EXPECT().DeleteComment(postID, commentID, gomock.Any()),
+				mockReportsRepo.EXPECT().Resolve(commentID, moderation.StatusRemoved),
+				mockModLogRepo.EXPECT().Add(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemComment, commentID, postID, "music"), nil},
+				{newPost(), nil},
+				{true, nil},
+				{newPost(), nil},
+				{2, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Remove a post of another category
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "remove", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemPost, postID, postID, "news"), nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Remove a comment of another category through a post of this one
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator,
+				map[string]string{"CATEGORY": "music", "ITEM_ID": commentID.Hex()},
+				`{"action": "remove", "type": "comment", "postId": "5ebaf9ee3c04c17c56f51244"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(commentID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemComment, commentID, bson.ObjectIdHex("5ebaf9ee3c04c17c56f51300"), "news"), nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Approve a comment of another post into a post of this category
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator,
+				map[string]string{"CATEGORY": "music", "ITEM_ID": commentID.Hex()},
+				`{"action": "approve", "type": "comment", "postId": "5ebaf9ee3c04c17c56f51244"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(commentID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemComment, commentID, bson.ObjectIdHex("5ebaf9ee3c04c17c56f51300"), "music"), nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Dismiss reports of another category
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "dismiss", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{openReports(posts.ItemPost, postID, postID, "news"), nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte("Forbidden\n"),
+				Code: http.StatusForbidden,
+			},
+		},
+		{ //Item without open reports
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "dismiss", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockReportsRepo.EXPECT().GetOpenByItem(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{[]*moderation.Report{}, nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte("No report\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Unknown action
+			Request: categoryRequest("POST", "/api/categories/music/modqueue/{ITEM_ID}", testModerator, postItemVars, `{"action": "ban", "type": "post"}`),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+			},
+			HandlerFunc: postsTestHandler.ModAction,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"action","value":"ban","msg":"must be one of: approve, remove, dismiss"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Moderation log
+			Request: categoryRequest("GET", "/api/categories/music/modlog?page=2&limit=1", testModerator, musicVars, ""),
+			ExpectMockFunc: []*gomock.Call{
+				mockCategoryRepo.EXPECT().GetByName("music"),
+				mockModLogRepo.EXPECT().GetByCategory("music", 1, 1),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{testFlairCategory, nil},
+				{[]*moderation.LogEntry{{
+					ID:        bson.ObjectIdHex("5ebaf9f33c04c17c56f51300"),
+					Category:  "music",
+					Moderator: testModerator,
+					Action:    moderation.ActionApprove,
+					Kind:      posts.ItemPost,
+					ItemID:    postID,
+					PostID:    postID,
+					Created:   "2020-05-12T23:00:00+03:00",
+				}}, 2, nil},
+			},
+			HandlerFunc: postsTestHandler.ModLog,
+			ExpectResult: Result{
+				Body: []byte(`{"entries":[{"id":"5ebaf9f33c04c17c56f51300","category":"music","moderator":{"username":"igor","id":"2"},"action":"approve","type":"post","item":"5ebaf9ee3c04c17c56f51244","post":"5ebaf9ee3c04c17c56f51244","created":"2020-05-12T23:00:00+03:00"}],"page":2,"limit":1,"total":2}`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestCloseReportsOnDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockReportsRepo := NewMockReportsRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:      zapLogger.Sugar(),
+		PostsRepo:   mockPostsRepo,
+		ReportsRepo: mockReportsRepo,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+
+	mockPostsRepo.EXPECT().Delete(postID, gomock.Any()).Return(true, nil)
+	mockReportsRepo.EXPECT().Resolve(postID, moderation.StatusRemoved).Return(0, mgo.ErrNotFound)
+	ok, err := postsTestHandler.removePost(postID)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/session"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type SavedRepositoryInterface interface {
+	Save(int64, string, bson.ObjectId, bson.ObjectId) error
+	Unsave(int64, string, bson.ObjectId) (bool, error)
+	GetByUser(int64, string, int, int) ([]*posts.SavedItem, int, error)
+	SavedPosts(int64, []bson.ObjectId) (map[bson.ObjectId]bool, error)
+	RemoveItem(bson.ObjectId) error
+}
+
+// SavedResponse is a saved post or a saved comment together with its post ID.
+type SavedResponse struct {
+	Type    string         `json:"type"`
+	Saved   string         `json:"saved"`
+	Post    *PostResponse  `json:"post,omitempty"`
+	PostID  bson.ObjectId  `json:"postId,omitempty"`
+	Comment *posts.Comment `json:"comment,omitempty"`
+}
+
+type SavedPage struct {
+	Items []*SavedResponse `json:"items"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+	Total int              `json:"total"`
+}
+
+// savedItemFromRequest finds the post or the comment in the URL, it answers
+// with an error and returns false if there is no such item.
+func (h *PostsHandler) savedItemFromRequest(w http.ResponseWriter, r *http.Request) (string, bson.ObjectId, bson.ObjectId, bool) {
+	kind, itemID, postID, ok := h.itemFromURL(w, r)
+	if !ok {
+		return "", "", "", false
+	}
+	if r.Method == http.MethodDelete {
+		// the item may be gone already, unsaving it is still fine
+		return kind, itemID, postID, true
+	}
+	if h.findItem(w, kind, itemID, postID) == nil {
+		return "", "", "", false
+	}
+	return kind, itemID, postID, true
+}
+
+// Save bookmarks a post or, if the URL has a comment ID, a comment.
+func (h *PostsHandler) Save(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	kind, itemID, postID, ok := h.savedItemFromRequest(w, r)
+	if !ok {
+		return
+	}
+	err = h.SavedRepo.Save(sess.User.ID, kind, itemID, postID)
+	if err != nil {
+		http.Error(w, `Save error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Save error: %v", err)
+		return
+	}
+	w.Write([]byte("{\"message\": \"success\"}"))
+	h.Logger.Infof("%v saved %v %v", sess.User.Username, kind, itemID)
+}
+
+func (h *PostsHandler) Unsave(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	kind, itemID, _, ok := h.savedItemFromRequest(w, r)
+	if !ok {
+		return
+	}
+	ok, err = h.SavedRepo.Unsave(sess.User.ID, kind, itemID)
+	if err != nil {
+		http.Error(w, `Unsave error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Unsave error: %v", err)
+		return
+	}
+	if ok {
+		w.Write([]byte("{\"message\": \"success\"}"))
+		h.Logger.Infof("%v unsaved %v %v", sess.User.Username, kind, itemID)
+	} else {
+		w.Write([]byte("{\"message\": \"failure\"}"))
+		h.Logger.Infof("%v hasn't saved %v %v", sess.User.Username, kind, itemID)
+	}
+}
+
+// ListSaved shows the items saved by the user, newest first. ?type= narrows
+// the list to posts or comments.
+func (h *PostsHandler) ListSaved(w http.ResponseWriter, r *http.Request) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	kind := r.URL.Query().Get("type")
+	if kind != "" && kind != posts.ItemPost && kind != posts.ItemComment {
+		http.Error(w, `Bad type`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad saved type: %v", kind)
+		return
+	}
+	page, limit, skip, ok := pageSkip(w, r, h.Logger)
+	if !ok {
+		return
+	}
+	items, total, err := h.SavedRepo.GetByUser(sess.User.ID, kind, skip, limit)
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+
+	saved := &SavedPage{
+		Items: make([]*SavedResponse, 0, len(items)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+	for _, item := range items {
+		savedResponse := &SavedResponse{Type: item.Kind, Saved: item.Saved}
+		if item.Kind == posts.ItemComment {
+			savedResponse.PostID = item.PostID
+			savedResponse.Comment, err = h.CommentRepo.GetByID(item.ItemID)
+		} else {
+			var post *posts.Post
+			post, err = h.PostsRepo.GetByID(item.ItemID)
+			if err == nil {
+				savedResponse.Post, err = PostToPostResponse(post, h.CommentRepo)
+			}
+			if savedResponse.Post != nil {
+				savedResponse.Post.Saved = true
+			}
+		}
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("DB err: %v", err)
+			return
+		}
+		saved.Items = append(saved.Items, savedResponse)
+	}
+
+	resp, _ := json.Marshal(saved)
+	w.Write(resp)
+	h.Logger.Infof("Saved of %v, page %v", sess.User.Username, page)
+}
+
+// markSaved sets the saved flag of the posts for the authenticated user.
+// Saved flags are not critical for listings, so errors are only logged.
+func markSaved(r *http.Request, responses []*PostResponse, savedRepo SavedRepositoryInterface, logError func(string, ...interface{})) {
+	if savedRepo == nil || len(responses) == 0 {
+		return
+	}
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		return
+	}
+	postIDs := make([]bson.ObjectId, 0, len(responses))
+	for _, response := range responses {
+		postIDs = append(postIDs, response.ID)
+	}
+	saved, err := savedRepo.SavedPosts(sess.User.ID, postIDs)
+	if err != nil {
+		logError("Can't get saved posts: %v", err)
+		return
+	}
+	for _, response := range responses {
+		response.Saved = saved[response.ID]
+	}
+}
+
+// forgetSaved removes a deleted post or comment from the saved lists.
+func (h *PostsHandler) forgetSaved(itemID bson.ObjectId) {
+	if h.SavedRepo == nil {
+		return
+	}
+	err := h.SavedRepo.RemoveItem(itemID)
+	if err != nil {
+		h.Logger.Errorf("Can't remove saved item %v: %v", itemID, err)
+	}
+}
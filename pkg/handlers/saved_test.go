@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	posts "reddit/pkg/posts"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSaved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	mockSavedRepo := NewMockSavedRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:      zapLogger.Sugar(),
+		PostsRepo:   mockPostsRepo,
+		CommentRepo: mockCommentsRepo,
+		SavedRepo:   mockSavedRepo,
+	}
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	commentID := bson.ObjectIdHex("5ebaf9f33c04c17c56f51245")
+	comment := &posts.Comment{
+		ID:      commentID,
+		Autor:   testUser,
+		Body:    "Nice",
+		Created: "2020-05-12T22:33:07+03:00",
+	}
+	post := &posts.Post{
+		Author:           testUser,
+		Category:         "music",
+		CommentsID:       []bson.ObjectId{commentID},
+		Created:          "2020-05-12T22:33:02+03:00",
+		ID:               postID,
+		Score:            1,
+		Text:             "Something",
+		Title:            "Lorem",
+		Type:             "text",
+		UpvotePercentage: 100,
+		Votes:            []posts.Vote{{UserID: 1, Rating: 1}},
+	}
+	saveRequest := func(method, url string, vars map[string]string) *http.Request {
+		return mux.SetURLVars(requestWithSession(httptest.NewRequest(method, url, nil)), vars)
+	}
+	postVars := map[string]string{"POST_ID": postID.Hex()}
+	commentVars := map[string]string{"POST_ID": postID.Hex(), "COMMENT_ID": commentID.Hex()}
+	otherCommentVars := map[string]string{"POST_ID": postID.Hex(), "COMMENT_ID": "5ebaf9f33c04c17c56f51299"}
+
+	testCases := []TestCase{
+		{ //Save post SUCCESS
+			Request: saveRequest("POST", "/api/post/{POST_ID}/save", postVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockSavedRepo.EXPECT().Save(int64(1), posts.ItemPost, postID, postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Save,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Save comment SUCCESS
+			Request: saveRequest("POST", "/api/post/{POST_ID}/{COMMENT_ID}/save", commentVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockSavedRepo.EXPECT().Save(int64(1), posts.ItemComment, commentID, postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+				{nil},
+			},
+			HandlerFunc: postsTestHandler.Save,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Comment of another post
+			Request: saveRequest("POST", "/api/post/{POST_ID}/{COMMENT_ID}/save", otherCommentVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{post, nil},
+			},
+			HandlerFunc: postsTestHandler.Save,
+			ExpectResult: Result{
+				Body: []byte("No comment\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //No post
+			Request: saveRequest("POST", "/api/post/{POST_ID}/save", postVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByID(postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, mgo.ErrNotFound},
+			},
+			HandlerFunc: postsTestHandler.Save,
+			ExpectResult: Result{
+				Body: []byte("No post\n"),
+				Code: http.StatusNotFound,
+			},
+		},
+		{ //Unsave comment SUCCESS
+			Request: saveRequest("DELETE", "/api/post/{POST_ID}/{COMMENT_ID}/save", commentVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockSavedRepo.EXPECT().Unsave(int64(1), posts.ItemComment, commentID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{true, nil},
+			},
+			HandlerFunc: postsTestHandler.Unsave,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "success"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Unsave not saved post
+			Request: saveRequest("DELETE", "/api/post/{POST_ID}/save", postVars),
+			ExpectMockFunc: []*gomock.Call{
+				mockSavedRepo.EXPECT().Unsave(int64(1), posts.ItemPost, postID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{false, nil},
+			},
+			HandlerFunc: postsTestHandler.Unsave,
+			ExpectResult: Result{
+				Body: []byte(`{"message": "failure"}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //List saved, the deleted post is skipped
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/me/saved?page=2&limit=3", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockSavedRepo.EXPECT().GetByUser(int64(1), "", 3, 3),
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+				mockPostsRepo.EXPECT().GetByID(postID),
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+				mockPostsRepo.EXPECT().GetByID(bson.ObjectIdHex("5ebaf9f33c04c17c56f51299")),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.SavedItem{
+					{UserID: 1, Kind: posts.ItemComment, ItemID: commentID, PostID: postID, Saved: "2020-05-13T10:00:00+03:00"},
+					{UserID: 1, Kind: posts.ItemPost, ItemID: postID, PostID: postID, Saved: "2020-05-13T09:00:00+03:00"},
+					{UserID: 1, Kind: posts.ItemPost, ItemID: bson.ObjectIdHex("5ebaf9f33c04c17c56f51299"), Saved: "2020-05-13T08:00:00+03:00"},
+				}, 6, nil},
+				{comment, nil},
+				{post, nil},
+				{comment, nil},
+				{nil, mgo.ErrNotFound},
+			},
+			HandlerFunc: postsTestHandler.ListSaved,
+			ExpectResult: Result{
+				Body: []byte(`{"items":[{"type":"comment","saved":"2020-05-13T10:00:00+03:00","postId":"5ebaf9ee3c04c17c56f51244","comment":{"id":"5ebaf9f33c04c17c56f51245","author":{"username":"rvasily","id":"1"},"body":"Nice","created":"2020-05-12T22:33:07+03:00"}},{"type":"post","saved":"2020-05-13T09:00:00+03:00","post":{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[{"id":"5ebaf9f33c04c17c56f51245","author":{"username":"rvasily","id":"1"},"body":"Nice","created":"2020-05-12T22:33:07+03:00"}],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"saved":true}}],"page":2,"limit":3,"total":6}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Bad type
+			Request:     requestWithSession(httptest.NewRequest("GET", "/api/me/saved?type=video", nil)),
+			HandlerFunc: postsTestHandler.ListSaved,
+			ExpectResult: Result{
+				Body: []byte("Bad type\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Page too far away to skip to
+			Request:     requestWithSession(httptest.NewRequest("GET", "/api/me/saved?page=9223372036854775807&limit=100", nil)),
+			HandlerFunc: postsTestHandler.ListSaved,
+			ExpectResult: Result{
+				Body: []byte("Bad page\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Saved flag in listings
+			Request: requestWithSession(httptest.NewRequest("GET", "/api/user/rvasily", nil)),
+			ExpectMockFunc: []*gomock.Call{
+				mockPostsRepo.EXPECT().GetByUserLogin(""),
+				mockCommentsRepo.EXPECT().GetByID(commentID),
+				mockSavedRepo.EXPECT().SavedPosts(int64(1), []bson.ObjectId{postID}),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{[]*posts.Post{post}, nil},
+				{comment, nil},
+				{map[bson.ObjectId]bool{postID: true}, nil},
+			},
+			HandlerFunc: postsTestHandler.ListByUserLogin,
+			ExpectResult: Result{
+				Body: []byte(`[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[{"id":"5ebaf9f33c04c17c56f51245","author":{"username":"rvasily","id":"1"},"body":"Nice","created":"2020-05-12T22:33:07+03:00"}],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something","title":"Lorem","type":"text","upvotePercentage":100,"views":0,"votes":[{"user":"1","vote":1}],"saved":true}]`),
+				Code: http.StatusOK,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
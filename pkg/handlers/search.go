@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/posts"
+	"reddit/pkg/search"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type SearcherInterface interface {
+	Index(*search.Document) error
+	Remove(bson.ObjectId) error
+	Search(*search.Query) (*search.Result, error)
+}
+
+type SearchHandler struct {
+	Searcher    SearcherInterface
+	PostsRepo   PostsRepositoryInterface
+	CommentRepo CommentsRepositoryInterface
+	Preferences PreferencesRepositoryInterface
+	SavedRepo   SavedRepositoryInterface
+	Logger      *zap.SugaredLogger
+}
+
+// parseDate accepts both full RFC3339 timestamps and plain dates, a plain
+// "to" date covers the whole day.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return date, nil
+	}
+	date, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		date = date.Add(24*time.Hour - time.Nanosecond)
+	}
+	return date, nil
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	args := r.URL.Query()
+	from, errFrom := parseDate(args.Get("from"), false)
+	to, errTo := parseDate(args.Get("to"), true)
+	if errFrom != nil || errTo != nil {
+		http.Error(w, `Bad date`, http.StatusBadRequest)
+		h.Logger.Errorf("Bad date. Error: %v, %v", errFrom, errTo)
+		return
+	}
+	show, err := showNSFW(r, h.Preferences)
+	if err != nil {
+		// better to hide too much than to fail the search
+		h.Logger.Errorf("Can't get preferences: %v", err)
+	}
+	page, limit := pageFromRequest(r)
+	// the filters go to the searcher, so the pages and the total are right
+	query := &search.Query{
+		Text:     args.Get("q"),
+		Category: args.Get("category"),
+		Author:   args.Get("author"),
+		Type:     args.Get("type"),
+		Flair:    args.Get("flair"),
+		HideNSFW: !show,
+		From:     from,
+		To:       to,
+		Page:     page,
+		Limit:    limit,
+	}
+	result, err := h.Searcher.Search(query)
+	if err == search.ErrEmptyQuery {
+		http.Error(w, `Empty query`, http.StatusBadRequest)
+		h.Logger.Errorf("Empty search query: %q", query.Text)
+		return
+	}
+	if err != nil {
+		http.Error(w, `Search error`, http.StatusInternalServerError)
+		h.Logger.Errorf("Search error: %v", err)
+		return
+	}
+
+	found := &PostsPage{
+		Posts: make([]*PostResponse, 0, len(result.Hits)),
+		Page:  page,
+		Limit: limit,
+		Total: result.Total,
+	}
+	hitPosts := make([]*posts.Post, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		post, err := h.PostsRepo.GetByID(hit.PostID)
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("DB err: %v", err)
+			return
+		}
+		hitPosts = append(hitPosts, post)
+	}
+	for _, post := range hitPosts {
+		postResponse, err := PostToPostResponse(post, h.CommentRepo)
+		if err != nil {
+			http.Error(w, `DB err`, http.StatusInternalServerError)
+			h.Logger.Errorf("Post Transform error: %v", err)
+			return
+		}
+		found.Posts = append(found.Posts, postResponse)
+	}
+	markSaved(r, found.Posts, h.SavedRepo, h.Logger.Errorf)
+
+	resp, _ := json.Marshal(found)
+	w.Write(resp)
+	h.Logger.Infof("Search %q found %v posts", query.Text, result.Total)
+}
+
+// indexPost keeps the search index in step with the post. Posts waiting for
+// approval stay out of the search until a moderator approves them. Search is
+// not critical for posting, so index errors are only logged.
+func (h *PostsHandler) indexPost(post *posts.Post, comments []*posts.Comment) {
+	if h.Searcher == nil || post.Pending {
+		return
+	}
+	err := h.Searcher.Index(search.NewDocument(post, comments))
+	if err != nil {
+		h.Logger.Errorf("Search index error: %v", err)
+	}
+}
+
+func (h *PostsHandler) unindexPost(postID bson.ObjectId) {
+	if h.Searcher == nil {
+		return
+	}
+	err := h.Searcher.Remove(postID)
+	if err != nil {
+		h.Logger.Errorf("Search index error: %v", err)
+	}
+}
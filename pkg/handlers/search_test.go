@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/posts"
+	"reddit/pkg/search"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSearch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSearcher := NewMockSearcherInterface(ctrl)
+	mockPostsRepo := NewMockPostsRepositoryInterface(ctrl)
+	mockCommentsRepo := NewMockCommentsRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	searchTestHandler := &SearchHandler{
+		Logger:      logger,
+		Searcher:    mockSearcher,
+		PostsRepo:   mockPostsRepo,
+		CommentRepo: mockCommentsRepo,
+	}
+	deletedID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51299")
+
+	testCases := []TestCase{
+		{ //Search SUCCESS
+			Request: httptest.NewRequest("GET", "/api/search?q=lorem&category=music&author=rvasily&type=text&from=2020-05-01&to=2020-05-12&page=1&limit=10", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockSearcher.EXPECT().Search(&search.Query{
+					Text:     "lorem",
+					Category: "music",
+					Author:   "rvasily",
+					Type:     "text",
+					HideNSFW: true,
+					From:     time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+					To:       time.Date(2020, 5, 12, 23, 59, 59, 999999999, time.UTC),
+					Page:     1,
+					Limit:    10,
+				}),
+				mockPostsRepo.EXPECT().GetByID(testPost1.ID),
+				mockPostsRepo.EXPECT().GetByID(deletedID),
+				mockCommentsRepo.EXPECT().GetByID(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&search.Result{
+					Hits:  []search.Hit{{PostID: testPost1.ID, Score: 2}, {PostID: deletedID, Score: 1}},
+					Total: 2,
+				}, nil},
+				{testPost1, nil},
+				{nil, mgo.ErrNotFound},
+				{testComment, nil},
+			},
+			HandlerFunc: searchTestHandler.Search,
+			ExpectResult: Result{
+				Body: []byte(`{"posts":[{"author":{"username":"rvasily","id":"1"},"category":"music","comments":[{"id":"5ebaf9f23c04c17c56f51245","author":{"username":"rvasily","id":"1"},"body":"something","created":"2020-05-12T22:33:02+03:00"}],"created":"2020-05-12T22:33:02+03:00","id":"5ebaf9ee3c04c17c56f51244","score":1,"text":"Something 1","title":"Lorem","type":"text","upvotePercentage":100,"views":10,"votes":[{"user":"1","vote":1}]}],"page":1,"limit":10,"total":2}`),
+				Code: http.StatusOK,
+			},
+		},
+		{ //Search. Bad date
+			Request:        httptest.NewRequest("GET", "/api/search?q=lorem&from=yesterday", nil),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    searchTestHandler.Search,
+			ExpectResult: Result{
+				Body: []byte("Bad date\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Search. Empty query
+			Request: httptest.NewRequest("GET", "/api/search?q=", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockSearcher.EXPECT().Search(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, search.ErrEmptyQuery},
+			},
+			HandlerFunc: searchTestHandler.Search,
+			ExpectResult: Result{
+				Body: []byte("Empty query\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //Search. Searcher error
+			Request: httptest.NewRequest("GET", "/api/search?q=lorem", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockSearcher.EXPECT().Search(gomock.Any()),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: searchTestHandler.Search,
+			ExpectResult: Result{
+				Body: []byte("Search error\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+		{ //Search. Post repo error
+			Request: httptest.NewRequest("GET", "/api/search?q=lorem", nil),
+			ExpectMockFunc: []*gomock.Call{
+				mockSearcher.EXPECT().Search(gomock.Any()),
+				mockPostsRepo.EXPECT().GetByID(testPost1.ID),
+			},
+			ReturnMockFunc: [][]interface{}{
+				{&search.Result{Hits: []search.Hit{{PostID: testPost1.ID, Score: 1}}, Total: 1}, nil},
+				{nil, fmt.Errorf("Internal error")},
+			},
+			HandlerFunc: searchTestHandler.Search,
+			ExpectResult: Result{
+				Body: []byte("DB err\n"),
+				Code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
+
+func TestSearchIndexing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSearcher := NewMockSearcherInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:   zapLogger.Sugar(),
+		Searcher: mockSearcher,
+	}
+
+	mockSearcher.EXPECT().Index(search.NewDocument(testPost1, []*posts.Comment{testComment})).Return(nil)
+	postsTestHandler.indexPost(testPost1, []*posts.Comment{testComment})
+
+	mockSearcher.EXPECT().Remove(testPost1.ID).Return(fmt.Errorf("Internal error"))
+	postsTestHandler.unindexPost(testPost1.ID)
+
+	//Without searcher nothing happens
+	postsTestHandler.Searcher = nil
+	postsTestHandler.indexPost(testPost1, nil)
+	postsTestHandler.unindexPost(testPost1.ID)
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"reddit/pkg/events"
 	"reddit/pkg/session"
 	"reddit/pkg/user"
 
@@ -15,19 +16,26 @@ import (
 type SessionManagerInterface interface {
 	Check(*http.Request) (*session.Session, error)
 	Create(http.ResponseWriter, *user.User) (int64, error)
+	RevokeAll(int64) (int64, error)
+	Revoked(*session.Session) (bool, error)
 }
 
 type UserRepositoryInterface interface {
 	Authorize(string, string) (*user.User, error)
 	Add(string, string) (int64, error)
 	GetByID(int64) (*user.User, error)
+	GetByUsername(string) (*user.User, error)
 }
 
 type UserHandler struct {
-	Tmpl     *template.Template
-	Logger   *zap.SugaredLogger
-	UserRepo UserRepositoryInterface
-	Sessions SessionManagerInterface
+	Tmpl            *template.Template
+	Logger          *zap.SugaredLogger
+	UserRepo        UserRepositoryInterface
+	PreferencesRepo PreferencesRepositoryInterface
+	Sessions        SessionManagerInterface
+	BansRepo        BansRepositoryInterface
+	Admins          map[string]bool // usernames of the site admins
+	Events          EventsOutboxInterface
 }
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -54,6 +62,14 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Error: %v", err)
 		return
 	}
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("DB err: %v", err)
+		return
+	}
+	if !h.checkNotBanned(w, u) {
+		return
+	}
 	sessID, errSess := h.Sessions.Create(w, u)
 	if errSess == nil {
 		h.Logger.Infof("created session sessionID: %v", sessID)
@@ -72,7 +88,30 @@ func (h *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Errorf("Bad JSON. Error: %v, %v", errReadBody, err)
 		return
 	}
+	// the user lives in SQL and can't carry its event, it is held before
+	event, err := holdEvent(h.Events, events.UserRegistered, &events.UserData{User: &user.User{Username: dataRequest.Username}})
+	if err != nil {
+		http.Error(w, `DB err`, http.StatusInternalServerError)
+		h.Logger.Errorf("Can't hold %v: %v", events.UserRegistered, err)
+		return
+	}
 	userID, err := h.UserRepo.Add(dataRequest.Username, dataRequest.Password)
+	newUser := &user.User{
+		ID:       userID,
+		Username: dataRequest.Username,
+	}
+	if event != nil && err == nil {
+		// the held event is sent with the id of the new user
+		event.Data, _ = json.Marshal(&events.UserData{User: newUser})
+	}
+	addErr := err
+	if err == user.ErrAlreadyExisting {
+		addErr = nil
+	}
+	releaseErr := releaseEvent(h.Events, event, err == nil, addErr)
+	if releaseErr != nil {
+		h.Logger.Errorf("Can't release %v: %v", events.UserRegistered, releaseErr)
+	}
 	if err == user.ErrAlreadyExisting {
 		ans, _ := json.Marshal(map[string][]map[string]string{
 			"errors": {
@@ -87,10 +126,6 @@ func (h *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, string(ans), http.StatusUnprocessableEntity)
 		return
 	}
-	newUser := &user.User{
-		ID:       userID,
-		Username: dataRequest.Username,
-	}
 	sessID, errSess := h.Sessions.Create(w, newUser)
 	if errSess == nil {
 		h.Logger.Infof("created session sessionID: %v", sessID)
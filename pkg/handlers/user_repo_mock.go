@@ -65,6 +65,36 @@ func (mr *MockSessionManagerInterfaceMockRecorder) Create(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSessionManagerInterface)(nil).Create), arg0, arg1)
 }
 
+// RevokeAll mocks base method
+func (m *MockSessionManagerInterface) RevokeAll(arg0 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAll", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAll indicates an expected call of RevokeAll
+func (mr *MockSessionManagerInterfaceMockRecorder) RevokeAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAll", reflect.TypeOf((*MockSessionManagerInterface)(nil).RevokeAll), arg0)
+}
+
+// Revoked mocks base method
+func (m *MockSessionManagerInterface) Revoked(arg0 *session.Session) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoked", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoked indicates an expected call of Revoked
+func (mr *MockSessionManagerInterfaceMockRecorder) Revoked(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoked", reflect.TypeOf((*MockSessionManagerInterface)(nil).Revoked), arg0)
+}
+
 // MockUserRepositoryInterface is a mock of UserRepositoryInterface interface
 type MockUserRepositoryInterface struct {
 	ctrl     *gomock.Controller
@@ -132,3 +162,18 @@ func (mr *MockUserRepositoryInterfaceMockRecorder) GetByID(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetByID), arg0)
 }
+
+// GetByUsername mocks base method
+func (m *MockUserRepositoryInterface) GetByUsername(arg0 string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUsername", arg0)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUsername indicates an expected call of GetByUsername
+func (mr *MockUserRepositoryInterfaceMockRecorder) GetByUsername(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUsername", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetByUsername), arg0)
+}
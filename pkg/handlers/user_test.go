@@ -104,6 +104,22 @@ func TestAuthorize(t *testing.T) {
 	assert.Equal(t, body, []byte("bad pass\n"))
 	assert.Equal(t, code, 400)
 
+	//Error DB
+	testRequest, _ = json.Marshal(loginRequest)
+	r = httptest.NewRequest("POST", "/api/login", bytes.NewReader(testRequest))
+	w = httptest.NewRecorder()
+
+	mockRepo.EXPECT().Authorize(testUser.Username, password).Return(nil, fmt.Errorf("Internal error"))
+
+	userTestHandler.Login(w, r)
+	resp = w.Result()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	code = resp.StatusCode
+
+	assert.Equal(t, body, []byte("DB err\n"))
+	assert.Equal(t, code, 500)
+
 	//Error session
 	testRequest, _ = json.Marshal(loginRequest)
 	r = httptest.NewRequest("POST", "/api/login", bytes.NewReader(testRequest))
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/validation"
+)
+
+// maxRequestBody caps JSON request bodies, nothing we accept comes close to it.
+const maxRequestBody = 1 << 20
+
+// writeFieldErrors answers with the same 422 body the frontend already
+// understands from the sign up form.
+func writeFieldErrors(w http.ResponseWriter, errs validation.Errors) {
+	ans, _ := json.Marshal(map[string]validation.Errors{
+		"errors": errs,
+	})
+	http.Error(w, string(ans), http.StatusUnprocessableEntity)
+}
+
+func writeFieldError(w http.ResponseWriter, param, value, msg string) {
+	writeFieldErrors(w, validation.Errors{{
+		Location: "body",
+		Param:    param,
+		Value:    value,
+		Msg:      msg,
+	}})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	postsTestHandler := &PostsHandler{
+		Logger:       zapLogger.Sugar(),
+		PostsRepo:    NewMockPostsRepositoryInterface(ctrl),
+		CommentRepo:  NewMockCommentsRepositoryInterface(ctrl),
+		CategoryRepo: NewMockCategoryRepositoryInterface(ctrl),
+	}
+	jsonRequest := func(url string, reqBody interface{}) *http.Request {
+		bodyJSON, _ := json.Marshal(reqBody)
+		r := httptest.NewRequest("POST", url, bytes.NewReader(bodyJSON))
+		return mux.SetURLVars(requestWithSession(r), map[string]string{
+			"POST_ID": testPost1.ID.Hex(),
+		})
+	}
+
+	testCases := []TestCase{
+		{ //Add. Invalid post
+			Request: jsonRequest("/api/posts", NewPostRequest{
+				Category: "music",
+				Title:    "",
+				Type:     "link",
+				Link:     "localhost",
+			}),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"title","value":"","msg":"is required"},{"location":"body","param":"url","value":"localhost","msg":"must be a valid http or https URL"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+		{ //Add. Too big body
+			Request: jsonRequest("/api/posts", NewPostRequest{
+				Category: "music",
+				Title:    "Lorem",
+				Type:     "text",
+				Text:     strings.Repeat("a", maxRequestBody),
+			}),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    postsTestHandler.Add,
+			ExpectResult: Result{
+				Body: []byte("Internal error\n"),
+				Code: http.StatusBadRequest,
+			},
+		},
+		{ //AddComment. Empty comment
+			Request:        jsonRequest("/api/post/{POST_ID}", AddCommentRequest{Comment: "  "}),
+			ExpectMockFunc: []*gomock.Call{},
+			ReturnMockFunc: [][]interface{}{},
+			HandlerFunc:    postsTestHandler.AddComment,
+			ExpectResult: Result{
+				Body: []byte(`{"errors":[{"location":"body","param":"comment","value":"  ","msg":"is required"}]}` + "\n"),
+				Code: http.StatusUnprocessableEntity,
+			},
+		},
+	}
+
+	for iTestCase, testCase := range testCases {
+		w := httptest.NewRecorder()
+		for i, mockFunc := range testCase.ExpectMockFunc {
+			mockFunc.Return(testCase.ReturnMockFunc[i]...)
+		}
+		testCase.HandlerFunc(w, testCase.Request)
+
+		resp := w.Result()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		code := resp.StatusCode
+
+		if assert.Equal(t, body, testCase.ExpectResult.Body) &&
+			assert.Equal(t, code, testCase.ExpectResult.Code) {
+			fmt.Printf("CASE %d SUCCESS\n", iTestCase)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reddit/pkg/live"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+var (
+	wsPingPeriod = 30 * time.Second // how often the server pings
+	wsPongWait   = 60 * time.Second // how long a silent client is kept
+	wsWriteWait  = 10 * time.Second
+	// how often the session is checked again on a ping, so the sockets of
+	// banned users are closed soon after their sessions are revoked
+	wsSessionCheck = time.Minute
+)
+
+// WSHandler streams the events of the user over a WebSocket. Every
+// connection costs two goroutines and a small buffer in the hub, so a
+// process holds thousands of them.
+type WSHandler struct {
+	Logger   *zap.SugaredLogger
+	Sessions SessionManagerInterface
+	Live     LiveInterface
+}
+
+// WSMessage is a text frame sent to the client.
+type WSMessage struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// wsUpgrader leaves CheckOrigin empty, so the upgrade is refused when the
+// Origin of the browser isn't the host of the server: another site can't
+// open the socket with the token of a visitor.
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// wsMaxMessage limits the messages of clients, they only send control
+// frames and short messages.
+const wsMaxMessage = 4096
+
+// Serve authenticates with the JWT of the Authorization header, browsers
+// can't set headers on a WebSocket, so they pass it as ?token= instead. The
+// token is checked only on connect, a session which merely expires keeps its
+// socket. The session is checked again on the pings and the socket is closed
+// once the session is revoked, e.g. after a site ban.
+func (h *WSHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" && r.URL.Query().Get("token") != "" {
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+r.URL.Query().Get("token"))
+	}
+	sess, err := h.Sessions.Check(r)
+	if err != nil {
+		http.Error(w, `Bad auth`, http.StatusUnauthorized)
+		h.Logger.Errorf("Bad auth. Error: %v", err)
+		return
+	}
+	// a bad handshake or origin is answered by the upgrader
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.Logger.Errorf("Can't upgrade to websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+	sub := h.Live.Subscribe(live.UserTopic(sess.User.ID))
+	defer sub.Close()
+	h.Logger.Infof("Websocket of %v opened", sess.User.Username)
+
+	closed := make(chan struct{})
+	go h.read(conn, closed)
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	checked := time.Now()
+	for {
+		select {
+		case <-closed:
+			h.Logger.Infof("Websocket of %v closed", sess.User.Username)
+			return
+		case <-ping.C:
+			if time.Since(checked) >= wsSessionCheck {
+				revoked, err := h.Sessions.Revoked(sess)
+				if err != nil {
+					// checked again on the next ping
+					h.Logger.Errorf("Can't check the session of %v: %v", sess.User.Username, err)
+				} else if revoked {
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session ended"),
+						time.Now().Add(wsWriteWait))
+					h.Logger.Infof("Websocket of %v closed, the session was revoked", sess.User.Username)
+					return
+				} else {
+					checked = time.Now()
+				}
+			}
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case event, ok := <-sub.Events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"),
+					time.Now().Add(wsWriteWait))
+				h.Logger.Infof("Slow websocket of %v evicted", sess.User.Username)
+				return
+			}
+			message, _ := json.Marshal(&WSMessage{ID: event.ID, Type: event.Type, Data: event.Data})
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.TextMessage, message)
+		}
+		if err != nil {
+			h.Logger.Infof("Websocket of %v failed: %v", sess.User.Username, err)
+			return
+		}
+	}
+}
+
+// read waits for the pongs of the client, it closes closed when the client
+// goes away or stays silent too long. Pings and closes are answered by the
+// connection, messages of the client are ignored.
+func (h *WSHandler) read(conn *websocket.Conn, closed chan struct{}) {
+	defer close(closed)
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, _, err := conn.NextReader()
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reddit/pkg/live"
+	"reddit/pkg/messages"
+	"reddit/pkg/session"
+	user "reddit/pkg/user"
+	"strings"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// wsDial opens a websocket to the server the way a browser of the site does.
+func wsDial(server *httptest.Server, path, origin string) (*websocket.Conn, *http.Response, error) {
+	header := http.Header{}
+	header.Set("Origin", origin)
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, header)
+}
+
+// wsRead reads the next message, it fails the test if none comes in a second.
+func wsRead(t *testing.T, conn *websocket.Conn) (int, []byte) {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	kind, payload, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Can't read message: %v", err)
+	}
+	return kind, payload
+}
+
+func TestWebSocket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(ping time.Duration) { wsPingPeriod = ping }(wsPingPeriod)
+	wsPingPeriod = 50 * time.Millisecond
+
+	mockSessionManager := NewMockSessionManagerInterface(ctrl)
+	mockMessagesRepo := NewMockMessagesRepositoryInterface(ctrl)
+	mockBlocksRepo := NewMockBlocksRepositoryInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	wsTestHandler := &WSHandler{
+		Logger:   zapLogger.Sugar(),
+		Sessions: mockSessionManager,
+		Live:     hub,
+	}
+	messagesTestHandler := &MessagesHandler{
+		Logger:       zapLogger.Sugar(),
+		MessagesRepo: mockMessagesRepo,
+		BlocksRepo:   mockBlocksRepo,
+		Live:         hub,
+	}
+	server := httptest.NewServer(http.HandlerFunc(wsTestHandler.Serve))
+	defer server.Close()
+
+	// no token
+	mockSessionManager.EXPECT().Check(gomock.Any()).Return(nil, session.ErrNoAuth)
+	_, resp, err := wsDial(server, "/api/ws", server.URL)
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// another site can't open the socket
+	mockSessionManager.EXPECT().Check(gomock.Any()).Return(&session.Session{ID: 2, User: testModerator}, nil)
+	_, resp, err = wsDial(server, "/api/ws?token=abc", "http://evil.example.com")
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// the token of the query is checked as the header
+	mockSessionManager.EXPECT().Check(gomock.Any()).DoAndReturn(func(r *http.Request) (*session.Session, error) {
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		return &session.Session{ID: 2, User: testModerator}, nil
+	})
+	conn, resp, err := wsDial(server, "/api/ws?token=abc", server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	waitSubscribers(t, hub, live.UserTopic(2), 1)
+	pings := make(chan struct{}, 16)
+	conn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	pongs := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pongs <- data
+		return nil
+	})
+
+	// a reply of rvasily arrives to igor
+	threadID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	thread := &messages.Thread{
+		ID:      threadID,
+		Users:   []*user.User{testUser, testModerator},
+		UserIDs: []int64{1, 2},
+	}
+	message := &messages.Message{
+		ID:       bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245"),
+		ThreadID: threadID,
+		From:     testUser,
+		To:       testModerator,
+		Body:     "Hi",
+	}
+	mockMessagesRepo.EXPECT().GetThread(threadID).Return(thread, nil)
+	mockBlocksRepo.EXPECT().IsBlocked(int64(2), int64(1)).Return(false, nil)
+	mockMessagesRepo.EXPECT().Reply(thread, testUser, "Hi").Return(message, nil)
+	replyRequest := httptest.NewRequest("POST", "/api/messages/{THREAD_ID}", bytes.NewReader([]byte(`{"body": "Hi"}`)))
+	replyRequest = mux.SetURLVars(requestWithSession(replyRequest), map[string]string{"THREAD_ID": threadID.Hex()})
+	messagesTestHandler.Reply(httptest.NewRecorder(), replyRequest)
+
+	kind, payload := wsRead(t, conn)
+	assert.Equal(t, websocket.TextMessage, kind)
+	assert.Equal(t, `{"id":1,"type":"message","data":{"id":"5ebaf9ee3c04c17c56f51245","thread":"5ebaf9ee3c04c17c56f51244","from":{"username":"rvasily","id":"1"},"to":{"username":"igor","id":"2"},"body":"Hi","created":"","read":false}}`, string(payload))
+
+	// the server pings and answers pings, control frames come while reading
+	conn.WriteControl(websocket.PingMessage, []byte("hi"), time.Now().Add(time.Second))
+	go conn.ReadMessage()
+	select {
+	case data := <-pongs:
+		assert.Equal(t, "hi", data)
+	case <-time.After(time.Second):
+		t.Fatal("No pong")
+	}
+	select {
+	case <-pings:
+	case <-time.After(time.Second):
+		t.Fatal("No ping")
+	}
+
+	// the client closes
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	waitSubscribers(t, hub, live.UserTopic(2), 0)
+}
+
+func TestWebSocketSessionEnds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(ping, check time.Duration) {
+		wsPingPeriod = ping
+		wsSessionCheck = check
+	}(wsPingPeriod, wsSessionCheck)
+	wsPingPeriod = 20 * time.Millisecond
+	wsSessionCheck = 50 * time.Millisecond
+
+	mockSessionManager := NewMockSessionManagerInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	wsTestHandler := &WSHandler{
+		Logger:   zapLogger.Sugar(),
+		Sessions: mockSessionManager,
+		Live:     hub,
+	}
+	server := httptest.NewServer(http.HandlerFunc(wsTestHandler.Serve))
+	defer server.Close()
+
+	// the session passes on connect and on the first check, then it is
+	// revoked by a site ban
+	sess := &session.Session{ID: 2, User: testModerator}
+	mockSessionManager.EXPECT().Check(gomock.Any()).DoAndReturn(func(r *http.Request) (*session.Session, error) {
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		return sess, nil
+	})
+	gomock.InOrder(
+		mockSessionManager.EXPECT().Revoked(sess).Return(false, nil),
+		mockSessionManager.EXPECT().Revoked(sess).Return(true, nil),
+	)
+	conn, _, err := wsDial(server, "/api/ws?token=abc", server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	waitSubscribers(t, hub, live.UserTopic(2), 1)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*websocket.CloseError)
+	if assert.True(t, ok, "%v", err) {
+		assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+		assert.Equal(t, "session ended", closeErr.Text)
+	}
+	waitSubscribers(t, hub, live.UserTopic(2), 0)
+}
+
+func TestWebSocketSessionExpires(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(ping, check time.Duration) {
+		wsPingPeriod = ping
+		wsSessionCheck = check
+	}(wsPingPeriod, wsSessionCheck)
+	wsPingPeriod = 20 * time.Millisecond
+	wsSessionCheck = 20 * time.Millisecond
+
+	mockSessionManager := NewMockSessionManagerInterface(ctrl)
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+	hub := live.NewHub(8)
+	wsTestHandler := &WSHandler{
+		Logger:   zapLogger.Sugar(),
+		Sessions: mockSessionManager,
+		Live:     hub,
+	}
+	server := httptest.NewServer(http.HandlerFunc(wsTestHandler.Serve))
+	defer server.Close()
+
+	// the token passes only on connect, the session expires right after but
+	// isn't revoked, a failed check keeps the socket too
+	sess := &session.Session{ID: 2, User: testModerator}
+	mockSessionManager.EXPECT().Check(gomock.Any()).Return(sess, nil)
+	mockSessionManager.EXPECT().Revoked(sess).Return(false, errors.New("DB err"))
+	mockSessionManager.EXPECT().Revoked(sess).Return(false, nil).MinTimes(2)
+	conn, _, err := wsDial(server, "/api/ws?token=abc", server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	waitSubscribers(t, hub, live.UserTopic(2), 1)
+
+	// several session checks go by
+	time.Sleep(200 * time.Millisecond)
+	hub.Publish(&live.Event{Type: LiveMessage, Data: map[string]string{"text": "still here"}}, live.UserTopic(2))
+	kind, payload := wsRead(t, conn)
+	assert.Equal(t, websocket.TextMessage, kind)
+	assert.Contains(t, string(payload), `"text":"still here"`)
+
+	// the handler is done before the timings are restored
+	conn.Close()
+	waitSubscribers(t, hub, live.UserTopic(2), 0)
+}
@@ -0,0 +1,124 @@
+package live
+
+import (
+	"strconv"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Event is a change pushed to the subscribers of a topic. ID is set by the
+// hub and grows with every published event.
+type Event struct {
+	ID   uint64
+	Type string
+	Data interface{}
+}
+
+// Subscription receives the events of one topic. Events is closed when the
+// subscription is closed or the subscriber is too slow to keep up.
+type Subscription struct {
+	Events <-chan *Event
+	topic  string
+	events chan *Event
+	hub    *Hub
+}
+
+// Hub is an in-process pub/sub. Publishing never blocks: a subscriber whose
+// buffer is full is evicted, so one slow client can't hold up the others.
+type Hub struct {
+	mu     sync.Mutex
+	buffer int
+	lastID uint64
+	topics map[string]map[*Subscription]bool
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		buffer: buffer,
+		topics: map[string]map[*Subscription]bool{},
+	}
+}
+
+func PostTopic(postID bson.ObjectId) string {
+	return "post:" + postID.Hex()
+}
+
+func CategoryTopic(name string) string {
+	return "category:" + name
+}
+
+// NSFWCategoryTopic gets the events of all the posts of the category, NSFW
+// ones included, for the users who opted in to them.
+func NSFWCategoryTopic(name string) string {
+	return "category-nsfw:" + name
+}
+
+// UserTopic gets the events for the user: notifications, messages and votes
+// on their posts.
+func UserTopic(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+func (h *Hub) Subscribe(topic string) *Subscription {
+	events := make(chan *Event, h.buffer)
+	sub := &Subscription{
+		Events: events,
+		topic:  topic,
+		events: events,
+		hub:    h,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs, ok := h.topics[topic]
+	if !ok {
+		subs = map[*Subscription]bool{}
+		h.topics[topic] = subs
+	}
+	subs[sub] = true
+	return sub
+}
+
+// Publish sends the event to the subscribers of the topics.
+func (h *Hub) Publish(event *Event, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	event.ID = h.lastID
+	for _, topic := range topics {
+		for sub := range h.topics[topic] {
+			select {
+			case sub.events <- event:
+			default:
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+// Subscribers counts the subscribers of the topic.
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics[topic])
+}
+
+// remove must be called with the lock held.
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.topics[sub.topic]
+	if !subs[sub] {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.topics, sub.topic)
+	}
+	close(sub.events)
+}
+
+// Close unsubscribes, it is safe to call after an eviction.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
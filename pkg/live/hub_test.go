@@ -0,0 +1,50 @@
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub(2)
+	postTopic := PostTopic(bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244"))
+	assert.Equal(t, "post:5ebaf9ee3c04c17c56f51244", postTopic)
+	post := hub.Subscribe(postTopic)
+	category := hub.Subscribe(CategoryTopic("music"))
+	other := hub.Subscribe(CategoryTopic("news"))
+
+	hub.Publish(&Event{Type: "score", Data: 1}, postTopic, CategoryTopic("music"))
+	event := <-post.Events
+	assert.Equal(t, &Event{ID: 1, Type: "score", Data: 1}, event)
+	assert.Equal(t, event, <-category.Events)
+	assert.Len(t, other.Events, 0)
+
+	hub.Publish(&Event{Type: "score", Data: 2}, CategoryTopic("news"))
+	assert.Equal(t, uint64(2), (<-other.Events).ID)
+}
+
+func TestSlowSubscriberIsEvicted(t *testing.T) {
+	hub := NewHub(2)
+	slow := hub.Subscribe("topic")
+	fast := hub.Subscribe("topic")
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(&Event{Type: "score", Data: i}, "topic")
+		<-fast.Events
+	}
+	assert.Equal(t, 1, hub.Subscribers("topic"))
+	assert.Equal(t, 0, (<-slow.Events).Data)
+	assert.Equal(t, 1, (<-slow.Events).Data)
+	_, ok := <-slow.Events
+	assert.False(t, ok)
+
+	// closing after the eviction is fine
+	slow.Close()
+	fast.Close()
+	fast.Close()
+	assert.Equal(t, 0, hub.Subscribers("topic"))
+	_, ok = <-fast.Events
+	assert.False(t, ok)
+}
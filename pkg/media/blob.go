@@ -0,0 +1,110 @@
+package media
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BlobStore keeps uploaded files. Keys are always generated by the server,
+// user supplied file names never get here.
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+var ErrBadKey = errors.New("Bad blob key")
+
+var keyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+\.[a-z]+$`)
+
+// LocalStore is a BlobStore on the local filesystem, BaseURL is the route
+// its Handler is mounted on.
+type LocalStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStore(dir, baseURL string) (*LocalStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStore{
+		Dir:     dir,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// Put writes the file next to its destination first and renames it after,
+// so a half-written file is never served.
+func (s *LocalStore) Put(key string, data []byte) error {
+	if !keyRegexp.MatchString(key) {
+		return ErrBadKey
+	}
+	tmp, err := ioutil.TempFile(s.Dir, ".upload-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.Dir, key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) Delete(key string) error {
+	if !keyRegexp.MatchString(key) {
+		return ErrBadKey
+	}
+	err := os.Remove(filepath.Join(s.Dir, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+// Handler serves the stored files, it is meant to be mounted with
+// http.StripPrefix(BaseURL+"/", ...). A key is never reused for other
+// content, so files can be cached forever.
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if !keyRegexp.MatchString(key) {
+			http.NotFound(w, r)
+			return
+		}
+		file, err := os.Open(filepath.Join(s.Dir, key))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil || stat.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, key, stat.ModTime(), file)
+	})
+}
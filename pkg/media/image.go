@@ -0,0 +1,202 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"reddit/pkg/posts"
+)
+
+const (
+	MaxPixels     = 40 * 1000 * 1000
+	ThumbnailSize = 320
+	jpegQuality   = 90
+)
+
+var (
+	ErrTooLarge        = errors.New("Image file is too large")
+	ErrUnsupportedType = errors.New("Only JPEG, PNG and GIF images are supported")
+	ErrTooManyPixels   = errors.New("Image dimensions are too large")
+	ErrBadImage        = errors.New("Can't decode image")
+)
+
+// IsBadImage tells upload errors caused by the file itself from storage errors.
+func IsBadImage(err error) bool {
+	return err == ErrTooLarge ||
+		err == ErrUnsupportedType ||
+		err == ErrTooManyPixels ||
+		errors.Is(err, ErrBadImage)
+}
+
+var extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// Processed is an upload re-encoded from its decoded pixels, which drops
+// EXIF and any other metadata the original file carried.
+type Processed struct {
+	ContentType   string
+	Data          []byte
+	Width         int
+	Height        int
+	ThumbnailType string
+	Thumbnail     []byte
+}
+
+// Process checks an uploaded image and prepares it for storing. The type is
+// sniffed from the content, whatever the client claims is ignored.
+func Process(data []byte) (*Processed, error) {
+	if len(data) > posts.MaxImageSize {
+		return nil, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return nil, ErrUnsupportedType
+	}
+	// check dimensions before decoding, a tiny file can claim huge ones
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	processed := &Processed{ContentType: contentType}
+	var frame image.Image
+	buf := new(bytes.Buffer)
+	switch contentType {
+	case "image/gif":
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadImage, err)
+		}
+		if len(animation.Image)*config.Width*config.Height > MaxPixels {
+			return nil, ErrTooManyPixels
+		}
+		// DecodeAll keeps frames and timings only, comments and
+		// application extensions are dropped on the way
+		err = gif.EncodeAll(buf, animation)
+		if err != nil {
+			return nil, err
+		}
+		// the first frame may cover only a part of the canvas
+		first := animation.Image[0]
+		canvas := image.NewPaletted(image.Rect(0, 0, config.Width, config.Height), first.Palette)
+		draw.Draw(canvas, first.Bounds(), first, first.Bounds().Min, draw.Src)
+		frame = canvas
+	case "image/png":
+		frame, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadImage, err)
+		}
+		err = png.Encode(buf, frame)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		frame, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadImage, err)
+		}
+		frame = orient(frame, jpegOrientation(data))
+		err = jpeg.Encode(buf, frame, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+	}
+	processed.Data = buf.Bytes()
+	processed.Width = frame.Bounds().Dx()
+	processed.Height = frame.Bounds().Dy()
+
+	thumbW, thumbH := fit(processed.Width, processed.Height, ThumbnailSize)
+	thumbnail := resize(frame, thumbW, thumbH)
+	buf = new(bytes.Buffer)
+	if contentType == "image/jpeg" {
+		processed.ThumbnailType = "image/jpeg"
+		err = jpeg.Encode(buf, thumbnail, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		// keep transparency of PNG and GIF
+		processed.ThumbnailType = "image/png"
+		err = png.Encode(buf, thumbnail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	processed.Thumbnail = buf.Bytes()
+	return processed, nil
+}
+
+// fit scales w x h down to fit into a size x size box keeping the aspect ratio.
+func fit(w, h, size int) (int, int) {
+	if w <= size && h <= size {
+		return w, h
+	}
+	if w >= h {
+		h = h * size / w
+		w = size
+	} else {
+		w = w * size / h
+		h = size
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// resize scales src with a box filter, every destination pixel is the
+// average of the source pixels it covers.
+func resize(src image.Image, w, h int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*srcH/h
+		y1 := bounds.Min.Y + (y+1)*srcH/h
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*srcW/w
+			x1 := bounds.Min.X + (x+1)*srcW/w
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// RGBA is alpha-premultiplied, so the sums are too
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r * 0xffff / a >> 8),
+				G: uint8(g * 0xffff / a >> 8),
+				B: uint8(b * 0xffff / a >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,222 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reddit/pkg/posts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func twoColorImage(w, h int, left, right color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, left)
+			} else {
+				img.Set(x, y, right)
+			}
+		}
+	}
+	return img
+}
+
+// withExif inserts an APP1 segment with the given orientation right after SOI.
+func withExif(jpegData []byte, orientation uint16) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1}
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	result := append([]byte{}, jpegData[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegData[2:]...)
+}
+
+func TestProcessJPEG(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	buf := new(bytes.Buffer)
+	jpeg.Encode(buf, twoColorImage(40, 20, red, blue), nil)
+	data := withExif(buf.Bytes(), 6)
+	assert.Equal(t, 6, jpegOrientation(data))
+
+	processed, err := Process(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "image/jpeg", processed.ContentType)
+	assert.Equal(t, "image/jpeg", processed.ThumbnailType)
+	assert.False(t, bytes.Contains(processed.Data, []byte("Exif")))
+	assert.Equal(t, 1, jpegOrientation(processed.Data))
+
+	//Rotated 90 degrees clockwise, the left half is on top now
+	assert.Equal(t, 20, processed.Width)
+	assert.Equal(t, 40, processed.Height)
+	img, err := jpeg.Decode(bytes.NewReader(processed.Data))
+	assert.Nil(t, err)
+	r, _, b, _ := img.At(10, 5).RGBA()
+	assert.True(t, r > 0xc000 && b < 0x4000, "top must be red")
+	r, _, b, _ = img.At(10, 35).RGBA()
+	assert.True(t, b > 0xc000 && r < 0x4000, "bottom must be blue")
+}
+
+func TestProcessPNGThumbnail(t *testing.T) {
+	buf := new(bytes.Buffer)
+	png.Encode(buf, twoColorImage(800, 400, color.NRGBA{G: 255, A: 255}, color.NRGBA{}))
+
+	processed, err := Process(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", processed.ContentType)
+	assert.Equal(t, 800, processed.Width)
+	assert.Equal(t, 400, processed.Height)
+
+	thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 160), thumbnail.Bounds())
+	_, g, _, a := thumbnail.At(10, 10).RGBA()
+	assert.Equal(t, uint32(0xffff), g)
+	assert.Equal(t, uint32(0xffff), a)
+	_, _, _, a = thumbnail.At(300, 10).RGBA()
+	assert.Equal(t, uint32(0), a, "transparency must be kept")
+}
+
+func TestProcessGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 10, 10), palette),
+			image.NewPaletted(image.Rect(2, 2, 6, 6), palette),
+		},
+		Delay: []int{10, 10},
+	}
+	buf := new(bytes.Buffer)
+	gif.EncodeAll(buf, animation)
+
+	processed, err := Process(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "image/gif", processed.ContentType)
+	assert.Equal(t, "image/png", processed.ThumbnailType)
+	decoded, err := gif.DecodeAll(bytes.NewReader(processed.Data))
+	assert.Nil(t, err)
+	assert.Len(t, decoded.Image, 2)
+}
+
+func TestProcessRejects(t *testing.T) {
+	_, err := Process([]byte("<html>not an image</html>"))
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	_, err = Process(make([]byte, posts.MaxImageSize+1))
+	assert.Equal(t, ErrTooLarge, err)
+
+	buf := new(bytes.Buffer)
+	png.Encode(buf, image.NewGray(image.Rect(0, 0, 100, 100)))
+	data := buf.Bytes()
+
+	_, err = Process(data[:len(data)-20])
+	assert.True(t, IsBadImage(err), "truncated file")
+
+	//A tiny file claiming 10000x10000 pixels
+	binary.BigEndian.PutUint32(data[16:], 10000)
+	binary.BigEndian.PutUint32(data[20:], 10000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	_, err = Process(data)
+	assert.Equal(t, ErrTooManyPixels, err)
+	assert.True(t, IsBadImage(err))
+
+	assert.False(t, IsBadImage(os.ErrPermission))
+}
+
+func TestFit(t *testing.T) {
+	w, h := fit(100, 50, 320)
+	assert.Equal(t, []int{100, 50}, []int{w, h})
+	w, h = fit(640, 1280, 320)
+	assert.Equal(t, []int{160, 320}, []int{w, h})
+	w, h = fit(10000, 1, 320)
+	assert.Equal(t, []int{320, 1}, []int{w, h})
+}
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewLocalStore(filepath.Join(dir, "uploads"), "/media/")
+	assert.Nil(t, err)
+	assert.Equal(t, "/media/a.png", store.URL("a.png"))
+
+	assert.Nil(t, store.Put("a.png", []byte("data")))
+	assert.Equal(t, ErrBadKey, store.Put("../a.png", []byte("data")))
+	assert.Equal(t, ErrBadKey, store.Delete("/etc/passwd"))
+
+	server := httptest.NewServer(http.StripPrefix("/media/", store.Handler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/media/a.png")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "data", string(body))
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "public, max-age=31536000, immutable", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+
+	for _, path := range []string{"/media/b.png", "/media/", "/media/.upload-1"} {
+		resp, err = http.Get(server.URL + path)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+		assert.Empty(t, resp.Header.Get("Cache-Control"), path)
+	}
+
+	assert.Nil(t, store.Delete("a.png"))
+	assert.Nil(t, store.Delete("a.png"))
+	_, err = os.Stat(filepath.Join(dir, "uploads", "a.png"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "media")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store, _ := NewLocalStore(dir, "/media")
+	uploader := NewUploader(store)
+
+	buf := new(bytes.Buffer)
+	jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 640, 480)), nil)
+	uploaded, err := uploader.Save(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "/media/"+uploaded.Key, uploaded.URL)
+	assert.Equal(t, "/media/"+uploaded.ThumbnailKey, uploaded.Thumbnail)
+	assert.Equal(t, 640, uploaded.Width)
+	assert.Equal(t, "image/jpeg", uploaded.ContentType)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 2)
+
+	assert.Nil(t, uploader.Delete(uploaded))
+	files, _ = ioutil.ReadDir(dir)
+	assert.Len(t, files, 0)
+
+	_, err = uploader.Save([]byte("GIF89a broken"))
+	assert.True(t, IsBadImage(err))
+}
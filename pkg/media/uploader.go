@@ -0,0 +1,54 @@
+package media
+
+import (
+	"reddit/pkg/posts"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Uploader processes uploaded images and puts them with their thumbnails
+// into a BlobStore.
+type Uploader struct {
+	Store BlobStore
+}
+
+func NewUploader(store BlobStore) *Uploader {
+	return &Uploader{Store: store}
+}
+
+func (u *Uploader) Save(data []byte) (*posts.Image, error) {
+	processed, err := Process(data)
+	if err != nil {
+		return nil, err
+	}
+	name := bson.NewObjectId().Hex()
+	key := name + "." + extensions[processed.ContentType]
+	thumbnailKey := name + "_thumb." + extensions[processed.ThumbnailType]
+
+	err = u.Store.Put(key, processed.Data)
+	if err != nil {
+		return nil, err
+	}
+	err = u.Store.Put(thumbnailKey, processed.Thumbnail)
+	if err != nil {
+		u.Store.Delete(key)
+		return nil, err
+	}
+	return &posts.Image{
+		URL:          u.Store.URL(key),
+		Thumbnail:    u.Store.URL(thumbnailKey),
+		Width:        processed.Width,
+		Height:       processed.Height,
+		ContentType:  processed.ContentType,
+		Key:          key,
+		ThumbnailKey: thumbnailKey,
+	}, nil
+}
+
+func (u *Uploader) Delete(image *posts.Image) error {
+	err := u.Store.Delete(image.Key)
+	if err != nil {
+		return err
+	}
+	return u.Store.Delete(image.ThumbnailKey)
+}
@@ -0,0 +1,77 @@
+package messages
+
+import (
+	"log"
+	"reddit/pkg/user"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Block keeps the blocked user from messaging the user.
+type Block struct {
+	UserID  int64      `json:"-" bson:"user"`
+	Blocked *user.User `json:"user" bson:"blocked"`
+	Created string     `json:"created" bson:"created"`
+}
+
+type BlocksRepo struct {
+	DB *mgo.Collection
+}
+
+func NewBlocksRepo(collection *mgo.Collection) *BlocksRepo {
+	return &BlocksRepo{DB: collection}
+}
+
+func (repo *BlocksRepo) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"user", "blocked.id"},
+		Unique: true,
+	})
+}
+
+// Block blocks the user, blocking again keeps the original date.
+func (repo *BlocksRepo) Block(userID int64, blocked *user.User) error {
+	_, err := repo.DB.Upsert(
+		bson.M{"user": userID, "blocked.id": blocked.ID},
+		bson.M{"$setOnInsert": &Block{
+			UserID:  userID,
+			Blocked: blocked,
+			Created: time.Now().Format(time.RFC3339),
+		}},
+	)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *BlocksRepo) Unblock(userID, blockedID int64) (bool, error) {
+	err := repo.DB.Remove(bson.M{"user": userID, "blocked.id": blockedID})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *BlocksRepo) GetByUser(userID int64) ([]*Block, error) {
+	blocks := []*Block{}
+	err := repo.DB.Find(bson.M{"user": userID}).Sort("-created").All(&blocks)
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// IsBlocked tells if the user has blocked the other one.
+func (repo *BlocksRepo) IsBlocked(userID, blockedID int64) (bool, error) {
+	count, err := repo.DB.Find(bson.M{"user": userID, "blocked.id": blockedID}).Count()
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
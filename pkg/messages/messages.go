@@ -0,0 +1,232 @@
+package messages
+
+import (
+	"log"
+	"reddit/pkg/user"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Thread is a conversation of two users. Last is the newest message, Unread
+// is counted for the user who asks.
+type Thread struct {
+	ID      bson.ObjectId `json:"id" bson:"_id"`
+	Subject string        `json:"subject" bson:"subject"`
+	Users   []*user.User  `json:"users" bson:"users"`
+	UserIDs []int64       `json:"-" bson:"userIds"`
+	Created string        `json:"created" bson:"created"`
+	Updated string        `json:"updated" bson:"updated"`
+	Last    *Message      `json:"last" bson:"last"`
+	Unread  int           `json:"unread" bson:"-"`
+}
+
+type Message struct {
+	ID       bson.ObjectId `json:"id" bson:"_id"`
+	ThreadID bson.ObjectId `json:"thread" bson:"thread"`
+	From     *user.User    `json:"from" bson:"from"`
+	To       *user.User    `json:"to" bson:"to"`
+	Body     string        `json:"body" bson:"body"`
+	Created  string        `json:"created" bson:"created"`
+	Read     bool          `json:"read" bson:"read"`
+}
+
+// HasUser tells if the user takes part in the thread, only they can read it.
+func (t *Thread) HasUser(userID int64) bool {
+	for _, id := range t.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Other returns the other participant of the thread.
+func (t *Thread) Other(userID int64) *user.User {
+	for _, u := range t.Users {
+		if u.ID != userID {
+			return u
+		}
+	}
+	return nil
+}
+
+// Repo keeps the threads and their messages in two collections.
+type Repo struct {
+	Threads  *mgo.Collection
+	Messages *mgo.Collection
+}
+
+func NewRepo(threads, messages *mgo.Collection) *Repo {
+	return &Repo{Threads: threads, Messages: messages}
+}
+
+func (repo *Repo) EnsureIndexes() error {
+	err := repo.Threads.EnsureIndexKey("userIds", "-updated")
+	if err != nil {
+		return err
+	}
+	err = repo.Messages.EnsureIndexKey("thread", "_id")
+	if err != nil {
+		return err
+	}
+	return repo.Messages.EnsureIndexKey("to.id", "read", "thread")
+}
+
+// NewThread starts a thread with its first message.
+func (repo *Repo) NewThread(from, to *user.User, subject, body string) (*Thread, error) {
+	now := time.Now().Format(time.RFC3339)
+	thread := &Thread{
+		ID:      bson.NewObjectId(),
+		Subject: subject,
+		Users:   []*user.User{from, to},
+		UserIDs: []int64{from.ID, to.ID},
+		Created: now,
+		Updated: now,
+	}
+	message := newMessage(thread.ID, from, to, body, now)
+	err := repo.Messages.Insert(message)
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return nil, err
+	}
+	thread.Last = message
+	err = repo.Threads.Insert(thread)
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return nil, err
+	}
+	return thread, nil
+}
+
+func newMessage(threadID bson.ObjectId, from, to *user.User, body, now string) *Message {
+	return &Message{
+		ID:       bson.NewObjectId(),
+		ThreadID: threadID,
+		From:     from,
+		To:       to,
+		Body:     body,
+		Created:  now,
+	}
+}
+
+// Reply adds a message from the user to the other participant.
+func (repo *Repo) Reply(thread *Thread, from *user.User, body string) (*Message, error) {
+	now := time.Now().Format(time.RFC3339)
+	message := newMessage(thread.ID, from, thread.Other(from.ID), body, now)
+	err := repo.Messages.Insert(message)
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return nil, err
+	}
+	err = repo.Threads.UpdateId(thread.ID, bson.M{"$set": bson.M{"updated": now, "last": message}})
+	if err != nil {
+		log.Printf("Update error: %v", err)
+		return nil, err
+	}
+	thread.Updated = now
+	thread.Last = message
+	return message, nil
+}
+
+func (repo *Repo) GetThread(threadID bson.ObjectId) (*Thread, error) {
+	thread := &Thread{}
+	err := repo.Threads.FindId(threadID).One(thread)
+	if err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+// GetThreads returns a page of the threads of the user, the last updated
+// first, with their unread counts and the total number of threads.
+func (repo *Repo) GetThreads(userID int64, skip, limit int) ([]*Thread, int, error) {
+	query := bson.M{"userIds": userID}
+	total, err := repo.Threads.Find(query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	threads := []*Thread{}
+	err = repo.Threads.Find(query).Sort("-updated", "-_id").Skip(skip).Limit(limit).All(&threads)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(threads) == 0 {
+		return threads, total, nil
+	}
+	threadIDs := make([]bson.ObjectId, 0, len(threads))
+	for _, thread := range threads {
+		threadIDs = append(threadIDs, thread.ID)
+	}
+	var counts []struct {
+		ThreadID bson.ObjectId `bson:"_id"`
+		Count    int           `bson:"count"`
+	}
+	err = repo.Messages.Pipe([]bson.M{
+		{"$match": bson.M{"to.id": userID, "read": false, "thread": bson.M{"$in": threadIDs}}},
+		{"$group": bson.M{"_id": "$thread", "count": bson.M{"$sum": 1}}},
+	}).All(&counts)
+	if err != nil {
+		return nil, 0, err
+	}
+	unread := make(map[bson.ObjectId]int, len(counts))
+	for _, count := range counts {
+		unread[count.ThreadID] = count.Count
+	}
+	for _, thread := range threads {
+		thread.Unread = unread[thread.ID]
+	}
+	return threads, total, nil
+}
+
+// GetMessages returns a page of the messages of the thread, the oldest
+// first, and the total number of them.
+func (repo *Repo) GetMessages(threadID bson.ObjectId, skip, limit int) ([]*Message, int, error) {
+	query := bson.M{"thread": threadID}
+	total, err := repo.Messages.Find(query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	messages := []*Message{}
+	err = repo.Messages.Find(query).Sort("_id").Skip(skip).Limit(limit).All(&messages)
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// MarkRead marks the messages of the thread to the user as read.
+func (repo *Repo) MarkRead(threadID bson.ObjectId, userID int64) (int, error) {
+	info, err := repo.Messages.UpdateAll(
+		bson.M{"thread": threadID, "to.id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return 0, err
+	}
+	return info.Updated, nil
+}
+
+// MarkUnread marks the last message of the thread to the user as unread, it
+// returns false if the user got no messages in the thread.
+func (repo *Repo) MarkUnread(threadID bson.ObjectId, userID int64) (bool, error) {
+	last := &Message{}
+	err := repo.Messages.Find(bson.M{"thread": threadID, "to.id": userID}).Sort("-_id").One(last)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	err = repo.Messages.UpdateId(last.ID, bson.M{"$set": bson.M{"read": false}})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Unread counts all the unread messages of the user.
+func (repo *Repo) Unread(userID int64) (int, error) {
+	return repo.Messages.Find(bson.M{"to.id": userID, "read": false}).Count()
+}
@@ -0,0 +1,22 @@
+package messages
+
+import (
+	"reddit/pkg/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadUsers(t *testing.T) {
+	rvasily := &user.User{ID: 1, Username: "rvasily"}
+	igor := &user.User{ID: 2, Username: "igor"}
+	thread := &Thread{
+		Users:   []*user.User{rvasily, igor},
+		UserIDs: []int64{1, 2},
+	}
+	assert.True(t, thread.HasUser(1))
+	assert.True(t, thread.HasUser(2))
+	assert.False(t, thread.HasUser(3))
+	assert.Equal(t, igor, thread.Other(1))
+	assert.Equal(t, rvasily, thread.Other(2))
+}
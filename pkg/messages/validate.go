@@ -0,0 +1,28 @@
+package messages
+
+import (
+	"reddit/pkg/validation"
+	"strings"
+)
+
+const (
+	MaxSubjectLength = 100
+	MaxMessageLength = 10000
+)
+
+// ValidateMessage checks a message which starts a new thread.
+func ValidateMessage(to, subject, body string) validation.Errors {
+	errs := validation.Errors{}
+	if strings.TrimSpace(to) == "" {
+		errs.Add("to", to, "is required")
+	}
+	errs.Required("subject", subject, MaxSubjectLength)
+	errs.Required("body", body, MaxMessageLength)
+	return errs
+}
+
+func ValidateReply(body string) validation.Errors {
+	errs := validation.Errors{}
+	errs.Required("body", body, MaxMessageLength)
+	return errs
+}
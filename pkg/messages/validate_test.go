@@ -0,0 +1,22 @@
+package messages
+
+import (
+	"reddit/pkg/validation"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMessage(t *testing.T) {
+	assert.Empty(t, ValidateMessage("igor", "Hi", "How are you?"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "to", Value: "", Msg: "is required"},
+		{Location: "body", Param: "subject", Value: " ", Msg: "is required"},
+		{Location: "body", Param: "body", Value: "", Msg: "must be at most 10000 characters"},
+	}, ValidateMessage("", " ", strings.Repeat("a", MaxMessageLength+1)))
+	assert.Empty(t, ValidateReply("Fine"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "body", Value: "", Msg: "is required"},
+	}, ValidateReply(""))
+}
@@ -17,6 +17,38 @@ var authUrls []*regexp.Regexp = []*regexp.Regexp{
 	regexp.MustCompile(`^/api/post/.+/downvote$`),
 	regexp.MustCompile(`^/api/post/.+$`),
 	regexp.MustCompile(`^/api/post/.+/.+$`),
+	regexp.MustCompile(`^/api/categories$`),
+	regexp.MustCompile(`^/api/categories/.+/subscribe$`),
+	regexp.MustCompile(`^/api/me/.+$`),
+	regexp.MustCompile(`^/api/feed`),
+	regexp.MustCompile(`^/api/categories/.+/flairs$`),
+	regexp.MustCompile(`^/api/posts/`),
+	regexp.MustCompile(`^/api/user/.+$`),
+	regexp.MustCompile(`^/api/search`),
+	regexp.MustCompile(`^/api/categories/.+/modqueue`),
+	regexp.MustCompile(`^/api/categories/.+/modlog`),
+	regexp.MustCompile(`^/api/categories/.+/bans`),
+	regexp.MustCompile(`^/api/admin/.+$`),
+	regexp.MustCompile(`^/api/domain/.+$`),
+	regexp.MustCompile(`^/api/messages`),
+	regexp.MustCompile(`^/api/notifications`),
+	regexp.MustCompile(`^/api/categories/.+/events`),
+}
+
+// streamUrls are the Server-Sent Events streams. EventSource can't set the
+// Authorization header, so they take the token as ?token= too.
+var streamUrls []*regexp.Regexp = []*regexp.Regexp{
+	regexp.MustCompile(`^/api/categories/.+/events`),
+	regexp.MustCompile(`^/api/post/.+/events`),
+}
+
+func isStream(r *http.Request) bool {
+	for _, regexpPath := range streamUrls {
+		if regexpPath.MatchString(r.URL.Path) {
+			return true
+		}
+	}
+	return false
 }
 
 func Auth(sm *session.SessionsManager, next http.Handler, userRepo *user.UserRepo) http.Handler {
@@ -35,6 +67,10 @@ func Auth(sm *session.SessionsManager, next http.Handler, userRepo *user.UserRep
 			return
 		}
 
+		if r.Header.Get("Authorization") == "" && r.URL.Query().Get("token") != "" && isStream(r) {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+r.URL.Query().Get("token"))
+		}
 		sess, err := sm.Check(r)
 		if err != nil {
 			log.Println("no auth. Error:", err)
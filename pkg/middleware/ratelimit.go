@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"reddit/pkg/clientip"
+	"reddit/pkg/ratelimit"
+	"reddit/pkg/session"
+
+	"go.uber.org/zap"
+)
+
+// RateClass is a group of routes which share a limit.
+type RateClass struct {
+	Name   string
+	Method string
+	Path   *regexp.Regexp
+	Limit  ratelimit.Limit
+}
+
+// RateClasses are the limits of the routes which write, the first matching
+// class counts. Other routes aren't limited.
+var RateClasses = []*RateClass{
+	{"login", "POST", regexp.MustCompile(`^/api/(login|register)$`), ratelimit.Every(12*time.Second, 5)},
+	{"votes", "GET", regexp.MustCompile(`^/api/post/[^/]+/(upvote|downvote)$`), ratelimit.Every(time.Second, 30)},
+	{"votes", "POST", regexp.MustCompile(`^/api/post/[^/]+/poll$`), ratelimit.Every(time.Second, 30)},
+	{"votes", "PUT", regexp.MustCompile(`^/api/post/[^/]+/poll$`), ratelimit.Every(time.Second, 30)},
+	{"posts", "POST", regexp.MustCompile(`^/api/posts$`), ratelimit.Every(time.Minute, 5)},
+	{"posts", "POST", regexp.MustCompile(`^/api/post/[^/]+/crosspost$`), ratelimit.Every(time.Minute, 5)},
+	{"comments", "POST", regexp.MustCompile(`^/api/post/[^/]+$`), ratelimit.Every(6*time.Second, 10)},
+}
+
+func rateClass(r *http.Request) *RateClass {
+	for _, class := range RateClasses {
+		if r.Method == class.Method && class.Path.MatchString(r.URL.Path) {
+			return class
+		}
+	}
+	return nil
+}
+
+// RateLimit answers 429 to users and addresses which run out of their
+// limit. It goes after Auth, so logged in users are limited by their ID and
+// the rest by IP. Store errors let the requests through.
+func RateLimit(store ratelimit.Store, proxies clientip.Proxies, logger *zap.SugaredLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := rateClass(r)
+		if class == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := class.Name + ":ip:" + proxies.ClientIP(r)
+		if sess, err := session.SessionFromContext(r.Context()); err == nil {
+			key = class.Name + ":user:" + strconv.FormatInt(sess.User.ID, 10)
+		}
+		wait, err := store.Take(key, class.Limit)
+		if err != nil {
+			logger.Errorf("Rate limit error: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			logger.Infof("Rate limit of %v for %v", class.Name, key)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
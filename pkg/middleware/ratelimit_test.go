@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"reddit/pkg/clientip"
+	"reddit/pkg/ratelimit"
+	"reddit/pkg/session"
+	"reddit/pkg/user"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeStore answers every Take with wait and err and remembers what it was
+// asked.
+type fakeStore struct {
+	wait   time.Duration
+	err    error
+	keys   []string
+	limits []ratelimit.Limit
+}
+
+func (s *fakeStore) Take(key string, limit ratelimit.Limit) (time.Duration, error) {
+	s.keys = append(s.keys, key)
+	s.limits = append(s.limits, limit)
+	return s.wait, s.err
+}
+
+func serveRateLimited(store ratelimit.Store, proxies clientip.Proxies, r *http.Request) (*httptest.ResponseRecorder, bool) {
+	passed := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	})
+	w := httptest.NewRecorder()
+	RateLimit(store, proxies, zap.NewNop().Sugar(), next).ServeHTTP(w, r)
+	return w, passed
+}
+
+func withUser(r *http.Request, userID int64) *http.Request {
+	sess := &session.Session{ID: 1, User: &user.User{ID: userID, Username: "rvasily"}}
+	return r.WithContext(context.WithValue(r.Context(), session.SessionKey, sess))
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	proxies, _ := clientip.ParseProxies("10.0.0.0/8")
+
+	//Routes without a class aren't limited
+	store := &fakeStore{}
+	_, passed := serveRateLimited(store, proxies, httptest.NewRequest("GET", "/api/posts/", nil))
+	assert.True(t, passed)
+	assert.Empty(t, store.keys)
+
+	//Anonymous clients are limited by IP
+	r := httptest.NewRequest("POST", "/api/posts", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	_, passed = serveRateLimited(store, proxies, r)
+	assert.True(t, passed)
+
+	//The IP behind a trusted proxy
+	r = httptest.NewRequest("POST", "/api/login", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	serveRateLimited(store, proxies, r)
+
+	//Logged in users by their ID whatever their IP
+	r = withUser(httptest.NewRequest("POST", "/api/post/5ebaf9ee3c04c17c56f51244", nil), 1)
+	r.RemoteAddr = "192.0.2.1:1234"
+	serveRateLimited(store, proxies, r)
+
+	assert.Equal(t, []string{
+		"posts:ip:192.0.2.1",
+		"login:ip:198.51.100.7",
+		"comments:user:1",
+	}, store.keys)
+}
+
+func TestRateLimitFirstClassWins(t *testing.T) {
+	defer func(classes []*RateClass) { RateClasses = classes }(RateClasses)
+	first := ratelimit.Every(time.Second, 1)
+	RateClasses = []*RateClass{
+		{"first", "POST", regexp.MustCompile(`^/api/posts$`), first},
+		{"second", "POST", regexp.MustCompile(`^/api/.+$`), ratelimit.Every(time.Minute, 2)},
+	}
+
+	store := &fakeStore{}
+	serveRateLimited(store, nil, withUser(httptest.NewRequest("POST", "/api/posts", nil), 1))
+	assert.Equal(t, []string{"first:user:1"}, store.keys)
+	assert.Equal(t, []ratelimit.Limit{first}, store.limits)
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	testCases := []struct {
+		wait       time.Duration
+		retryAfter string
+	}{
+		{wait: 1500 * time.Millisecond, retryAfter: "2"},
+		{wait: 100 * time.Millisecond, retryAfter: "1"},
+		{wait: 12 * time.Second, retryAfter: "12"},
+	}
+	for _, testCase := range testCases {
+		store := &fakeStore{wait: testCase.wait}
+		w, passed := serveRateLimited(store, nil, withUser(httptest.NewRequest("POST", "/api/posts", nil), 1))
+		assert.False(t, passed)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, testCase.retryAfter, w.Header().Get("Retry-After"), "wait %v", testCase.wait)
+		assert.Equal(t, "Too many requests\n", w.Body.String())
+	}
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	store := &fakeStore{wait: time.Second, err: fmt.Errorf("connection refused")}
+	w, passed := serveRateLimited(store, nil, withUser(httptest.NewRequest("POST", "/api/posts", nil), 1))
+	assert.True(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"log"
+	"reddit/pkg/user"
+	"reddit/pkg/validation"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	ActionApprove  = "approve"
+	ActionRemove   = "remove"
+	ActionDismiss  = "dismiss"
+	ActionSticky   = "sticky"
+	ActionUnsticky = "unsticky"
+	ActionLock     = "lock"
+	ActionUnlock   = "unlock"
+)
+
+// LogEntry records an action of a moderator in the category.
+type LogEntry struct {
+	ID        bson.ObjectId `json:"id" bson:"_id"`
+	Category  string        `json:"category" bson:"category"`
+	Moderator *user.User    `json:"moderator" bson:"moderator"`
+	Action    string        `json:"action" bson:"action"`
+	Kind      string        `json:"type" bson:"kind"`
+	ItemID    bson.ObjectId `json:"item" bson:"item"`
+	PostID    bson.ObjectId `json:"post" bson:"post"`
+	Reason    string        `json:"reason,omitempty" bson:"reason,omitempty"`
+	Created   string        `json:"created" bson:"created"`
+}
+
+// ValidateAction checks the reason a moderator gives for an action, it is
+// optional and goes to the log, so it has the length limit of a report.
+func ValidateAction(reason string) validation.Errors {
+	errs := validation.Errors{}
+	errs.MaxLength("reason", reason, MaxReasonLength)
+	return errs
+}
+
+type LogRepo struct {
+	DB *mgo.Collection
+}
+
+func NewLogRepo(collection *mgo.Collection) *LogRepo {
+	return &LogRepo{DB: collection}
+}
+
+func (repo *LogRepo) EnsureIndexes() error {
+	return repo.DB.EnsureIndexKey("category", "-created")
+}
+
+func (repo *LogRepo) Add(entry *LogEntry) error {
+	entry.ID = bson.NewObjectId()
+	entry.Created = time.Now().Format(time.RFC3339)
+	err := repo.DB.Insert(entry)
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByCategory returns a page of the log, newest first, and the total
+// number of entries.
+func (repo *LogRepo) GetByCategory(category string, skip, limit int) ([]*LogEntry, int, error) {
+	query := bson.M{"category": category}
+	total, err := repo.DB.Find(query).Count()
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, 0, err
+	}
+	entries := []*LogEntry{}
+	err = repo.DB.Find(query).Sort("-created", "-_id").Skip(skip).Limit(limit).All(&entries)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,166 @@
+package moderation
+
+import (
+	"log"
+	"reddit/pkg/user"
+	"reddit/pkg/validation"
+	"sort"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	StatusOpen      = "open"
+	StatusApproved  = "approved"
+	StatusRemoved   = "removed"
+	StatusDismissed = "dismissed"
+)
+
+const MaxReasonLength = 500
+
+// Report is a complaint of a user about a post or a comment. PostID is the
+// post the comment belongs to, for reported posts it equals ItemID.
+type Report struct {
+	ID       bson.ObjectId `json:"id" bson:"_id"`
+	Kind     string        `json:"type" bson:"kind"`
+	ItemID   bson.ObjectId `json:"item" bson:"item"`
+	PostID   bson.ObjectId `json:"post" bson:"post"`
+	Category string        `json:"category" bson:"category"`
+	Reporter *user.User    `json:"reporter" bson:"reporter"`
+	Reason   string        `json:"reason" bson:"reason"`
+	Created  string        `json:"created" bson:"created"`
+	Status   string        `json:"status" bson:"status"`
+}
+
+// ValidateReport checks the reason of a report.
+func ValidateReport(reason string) validation.Errors {
+	errs := validation.Errors{}
+	errs.Required("reason", reason, MaxReasonLength)
+	return errs
+}
+
+// QueueItem is a reported post or comment with all its open reports.
+type QueueItem struct {
+	Kind     string        `json:"type"`
+	ItemID   bson.ObjectId `json:"id"`
+	PostID   bson.ObjectId `json:"postId"`
+	Count    int           `json:"count"`
+	Reasons  []string      `json:"reasons"`
+	Reported string        `json:"reported"` // the first report
+}
+
+type ReportsRepo struct {
+	DB *mgo.Collection
+}
+
+func NewReportsRepo(collection *mgo.Collection) *ReportsRepo {
+	return &ReportsRepo{DB: collection}
+}
+
+func (repo *ReportsRepo) EnsureIndexes() error {
+	err := repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"reporter.id", "kind", "item"},
+		Unique: true,
+	})
+	if err != nil {
+		return err
+	}
+	err = repo.DB.EnsureIndexKey("category", "status")
+	if err != nil {
+		return err
+	}
+	err = repo.DB.EnsureIndexKey("post")
+	if err != nil {
+		return err
+	}
+	return repo.DB.EnsureIndexKey("item")
+}
+
+// Add saves the report, it returns false if the user has already reported
+// the item.
+func (repo *ReportsRepo) Add(report *Report) (bool, error) {
+	report.ID = bson.NewObjectId()
+	report.Status = StatusOpen
+	report.Created = time.Now().Format(time.RFC3339)
+	info, err := repo.DB.Upsert(
+		bson.M{"reporter.id": report.Reporter.ID, "kind": report.Kind, "item": report.ItemID},
+		bson.M{"$setOnInsert": report},
+	)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return false, err
+	}
+	return info.UpsertedId != nil, nil
+}
+
+func (repo *ReportsRepo) GetOpen(category string) ([]*Report, error) {
+	reports := []*Report{}
+	err := repo.DB.Find(bson.M{"category": category, "status": StatusOpen}).All(&reports)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (repo *ReportsRepo) GetOpenByItem(itemID bson.ObjectId) ([]*Report, error) {
+	reports := []*Report{}
+	err := repo.DB.Find(bson.M{"item": itemID, "status": StatusOpen}).All(&reports)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Resolve closes the open reports of the item with the status. Resolving a
+// post also resolves the reports of its comments.
+func (repo *ReportsRepo) Resolve(itemID bson.ObjectId, status string) (int, error) {
+	info, err := repo.DB.UpdateAll(
+		bson.M{
+			"status": StatusOpen,
+			"$or":    []bson.M{{"item": itemID}, {"post": itemID}},
+		},
+		bson.M{"$set": bson.M{"status": status}},
+	)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return 0, err
+	}
+	return info.Updated, nil
+}
+
+// Queue groups the reports by the reported item, the most reported items go
+// first and the older ones win a tie.
+func Queue(reports []*Report) []*QueueItem {
+	items := make(map[bson.ObjectId]*QueueItem)
+	queue := []*QueueItem{}
+	for _, report := range reports {
+		item, ok := items[report.ItemID]
+		if !ok {
+			item = &QueueItem{
+				Kind:     report.Kind,
+				ItemID:   report.ItemID,
+				PostID:   report.PostID,
+				Reasons:  []string{},
+				Reported: report.Created,
+			}
+			items[report.ItemID] = item
+			queue = append(queue, item)
+		}
+		item.Count++
+		item.Reasons = append(item.Reasons, report.Reason)
+		if report.Created < item.Reported {
+			item.Reported = report.Created
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		if queue[i].Count != queue[j].Count {
+			return queue[i].Count > queue[j].Count
+		}
+		return queue[i].Reported < queue[j].Reported
+	})
+	return queue
+}
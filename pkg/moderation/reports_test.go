@@ -0,0 +1,49 @@
+package moderation
+
+import (
+	"reddit/pkg/validation"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestQueue(t *testing.T) {
+	first := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	second := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+	third := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51246")
+	reports := []*Report{
+		{Kind: "post", ItemID: first, PostID: first, Reason: "spam", Created: "2020-05-12T22:40:00+03:00"},
+		{Kind: "comment", ItemID: second, PostID: first, Reason: "rude", Created: "2020-05-12T22:50:00+03:00"},
+		{Kind: "post", ItemID: third, PostID: third, Reason: "off-topic", Created: "2020-05-12T22:30:00+03:00"},
+		{Kind: "comment", ItemID: second, PostID: first, Reason: "insult", Created: "2020-05-12T22:45:00+03:00"},
+	}
+
+	queue := Queue(reports)
+	assert.Equal(t, []*QueueItem{
+		{Kind: "comment", ItemID: second, PostID: first, Count: 2, Reasons: []string{"rude", "insult"}, Reported: "2020-05-12T22:45:00+03:00"},
+		{Kind: "post", ItemID: third, PostID: third, Count: 1, Reasons: []string{"off-topic"}, Reported: "2020-05-12T22:30:00+03:00"},
+		{Kind: "post", ItemID: first, PostID: first, Count: 1, Reasons: []string{"spam"}, Reported: "2020-05-12T22:40:00+03:00"},
+	}, queue)
+
+	assert.Equal(t, []*QueueItem{}, Queue(nil))
+}
+
+func TestValidateReport(t *testing.T) {
+	assert.Empty(t, ValidateReport("Spam"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "reason", Value: " ", Msg: "is required"},
+	}, ValidateReport(" "))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "reason", Value: "", Msg: "must be at most 500 characters"},
+	}, ValidateReport(strings.Repeat("a", MaxReasonLength+1)))
+}
+
+func TestValidateAction(t *testing.T) {
+	assert.Empty(t, ValidateAction(""))
+	assert.Empty(t, ValidateAction("Spam"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "reason", Value: "", Msg: "must be at most 500 characters"},
+	}, ValidateAction(strings.Repeat("a", MaxReasonLength+1)))
+}
@@ -0,0 +1,101 @@
+package notifications
+
+import (
+	"log"
+	"reddit/pkg/user"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	KindPostComment  = "post_comment"  // a comment to the post of the user
+	KindCommentReply = "comment_reply" // a reply to the comment of the user
+	KindMention      = "mention"       // u/username in a comment
+)
+
+// Notification tells the user about a comment. Body is the beginning of the
+// comment.
+type Notification struct {
+	ID        bson.ObjectId `json:"id" bson:"_id"`
+	UserID    int64         `json:"-" bson:"user"`
+	Kind      string        `json:"kind" bson:"kind"`
+	Actor     *user.User    `json:"actor" bson:"actor"`
+	PostID    bson.ObjectId `json:"post" bson:"post"`
+	CommentID bson.ObjectId `json:"comment" bson:"comment"`
+	Category  string        `json:"category" bson:"category"`
+	Title     string        `json:"title" bson:"title"`
+	Body      string        `json:"body" bson:"body"`
+	Created   string        `json:"created" bson:"created"`
+	Read      bool          `json:"read" bson:"read"`
+}
+
+type Repo struct {
+	DB *mgo.Collection
+}
+
+func NewRepo(collection *mgo.Collection) *Repo {
+	return &Repo{DB: collection}
+}
+
+func (repo *Repo) EnsureIndexes() error {
+	err := repo.DB.EnsureIndexKey("user", "-_id")
+	if err != nil {
+		return err
+	}
+	return repo.DB.EnsureIndexKey("user", "read")
+}
+
+func (repo *Repo) Add(notifications []*Notification) error {
+	docs := make([]interface{}, 0, len(notifications))
+	for _, n := range notifications {
+		docs = append(docs, n)
+	}
+	err := repo.DB.Insert(docs...)
+	if err != nil {
+		log.Printf("Insert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByUser returns a page of the notifications of the user, the newest
+// first, and the total number of them.
+func (repo *Repo) GetByUser(userID int64, skip, limit int) ([]*Notification, int, error) {
+	query := bson.M{"user": userID}
+	total, err := repo.DB.Find(query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	notifications := []*Notification{}
+	err = repo.DB.Find(query).Sort("-_id").Skip(skip).Limit(limit).All(&notifications)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (repo *Repo) Unread(userID int64) (int, error) {
+	return repo.DB.Find(bson.M{"user": userID, "read": false}).Count()
+}
+
+// MarkRead marks a notification of the user as read, it returns false if the
+// user has no such notification.
+func (repo *Repo) MarkRead(userID int64, id bson.ObjectId) (bool, error) {
+	err := repo.DB.Update(bson.M{"_id": id, "user": userID}, bson.M{"$set": bson.M{"read": true}})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *Repo) MarkAllRead(userID int64) (int, error) {
+	info, err := repo.DB.UpdateAll(bson.M{"user": userID, "read": false}, bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return 0, err
+	}
+	return info.Updated, nil
+}
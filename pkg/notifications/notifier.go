@@ -0,0 +1,170 @@
+package notifications
+
+import (
+	"fmt"
+	"reddit/pkg/events"
+	"reddit/pkg/live"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// LiveNotification is the type of the live events of new notifications.
+const LiveNotification = "notification"
+
+const (
+	MaxMentions   = 10  // only the first mentions of a comment are notified
+	ExcerptLength = 140 // runes of the comment kept in a notification
+)
+
+var mentionRe = regexp.MustCompile(`(?:^|[^A-Za-z0-9_/])u/([A-Za-z0-9_-]+)`)
+
+type Store interface {
+	Add([]*Notification) error
+}
+
+type UserFinder interface {
+	GetByUsername(string) (*user.User, error)
+}
+
+type CommentFinder interface {
+	GetByID(bson.ObjectId) (*posts.Comment, error)
+}
+
+// Publisher pushes the saved notifications to the live connections of the
+// users.
+type Publisher interface {
+	Publish(*live.Event, ...string)
+}
+
+// Notifier turns the comment.added events into notifications, commenting
+// never waits for them.
+type Notifier struct {
+	Store    Store
+	Users    UserFinder
+	Comments CommentFinder
+	Live     Publisher // optional
+	Logger   *zap.SugaredLogger
+	now      func() time.Time
+}
+
+func NewNotifier(store Store, users UserFinder, comments CommentFinder, logger *zap.SugaredLogger) *Notifier {
+	return &Notifier{
+		Store:    store,
+		Users:    users,
+		Comments: comments,
+		Logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// HandleEvent notifies about a comment.added domain event. It runs in the
+// worker of the event bus, so it notifies right away and a failure is
+// returned for a redelivery.
+func (n *Notifier) HandleEvent(e *events.Event) error {
+	data := &events.CommentData{}
+	err := e.Decode(data)
+	if err != nil {
+		return err
+	}
+	if data.Post == nil || data.Comment == nil || data.Comment.Autor == nil {
+		return fmt.Errorf("bad %v event %v", e.Type, e.ID)
+	}
+	return n.Notify(data.Post.Post(), data.Comment)
+}
+
+// Notify saves the notifications about the comment. The author of the parent
+// comment gets a reply, the author of the post a comment and the mentioned
+// users a mention. Every user is notified once and never about their own
+// comment.
+func (n *Notifier) Notify(post *posts.Post, comment *posts.Comment) error {
+	notified := map[int64]bool{comment.Autor.ID: true}
+	var notifications []*Notification
+	add := func(u *user.User, kind string) {
+		if u == nil || notified[u.ID] {
+			return
+		}
+		notified[u.ID] = true
+		notifications = append(notifications, n.notification(u.ID, kind, post, comment))
+	}
+
+	if comment.Parent != "" {
+		parent, err := n.Comments.GetByID(comment.Parent)
+		if err != nil {
+			return err
+		}
+		add(parent.Autor, KindCommentReply)
+	}
+	add(post.Author, KindPostComment)
+	for _, username := range Mentions(comment.Body) {
+		u, err := n.Users.GetByUsername(username)
+		if err == user.ErrNoUser {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		add(u, KindMention)
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+	err := n.Store.Add(notifications)
+	if err != nil {
+		return err
+	}
+	if n.Live != nil {
+		for _, notification := range notifications {
+			n.Live.Publish(&live.Event{Type: LiveNotification, Data: notification}, live.UserTopic(notification.UserID))
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) notification(userID int64, kind string, post *posts.Post, comment *posts.Comment) *Notification {
+	return &Notification{
+		ID:        bson.NewObjectId(),
+		UserID:    userID,
+		Kind:      kind,
+		Actor:     comment.Autor,
+		PostID:    post.ID,
+		CommentID: comment.ID,
+		Category:  post.Category,
+		Title:     post.Title,
+		Body:      excerpt(comment.Body),
+		Created:   n.now().Format(time.RFC3339),
+	}
+}
+
+// Mentions returns the distinct usernames mentioned as u/username, at most
+// MaxMentions of them.
+func Mentions(body string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionRe.FindAllStringSubmatch(body, -1) {
+		username := match[1]
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == MaxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+func excerpt(body string) string {
+	runes := []rune(strings.TrimSpace(body))
+	if len(runes) <= ExcerptLength {
+		return string(runes)
+	}
+	return string(runes[:ExcerptLength]) + "…"
+}
@@ -0,0 +1,157 @@
+package notifications
+
+import (
+	"reddit/pkg/events"
+	"reddit/pkg/live"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type testStore struct {
+	added []*Notification
+}
+
+func (s *testStore) Add(notifications []*Notification) error {
+	s.added = append(s.added, notifications...)
+	return nil
+}
+
+type testPublisher struct {
+	topics []string
+}
+
+func (p *testPublisher) Publish(event *live.Event, topics ...string) {
+	p.topics = append(p.topics, topics...)
+}
+
+type testUsers map[string]*user.User
+
+func (u testUsers) GetByUsername(username string) (*user.User, error) {
+	if found, ok := u[username]; ok {
+		return found, nil
+	}
+	return nil, user.ErrNoUser
+}
+
+type testComments map[bson.ObjectId]*posts.Comment
+
+func (c testComments) GetByID(id bson.ObjectId) (*posts.Comment, error) {
+	return c[id], nil
+}
+
+var (
+	rvasily = &user.User{ID: 1, Username: "rvasily"}
+	igor    = &user.User{ID: 2, Username: "igor"}
+	anna    = &user.User{ID: 3, Username: "anna"}
+)
+
+func TestMentions(t *testing.T) {
+	assert.Equal(t, []string{"igor", "anna"}, Mentions("u/igor and u/anna, ask u/igor again"))
+	assert.Equal(t, []string{"igor"}, Mentions("(u/igor) not /u/anna nor mu/anna"))
+	assert.Nil(t, Mentions("no mentions"))
+
+	many := ""
+	for i := 0; i < MaxMentions+5; i++ {
+		many += " u/user" + string(rune('a'+i))
+	}
+	assert.Len(t, Mentions(many), MaxMentions)
+}
+
+func TestNotify(t *testing.T) {
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	post := &posts.Post{
+		ID:       bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244"),
+		Author:   rvasily,
+		Category: "music",
+		Title:    "News",
+	}
+	parent := &posts.Comment{ID: bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245"), Autor: igor}
+	store := &testStore{}
+	publisher := &testPublisher{}
+	n := &Notifier{
+		Store:    store,
+		Live:     publisher,
+		Users:    testUsers{"rvasily": rvasily, "igor": igor, "anna": anna},
+		Comments: testComments{parent.ID: parent},
+		now:      func() time.Time { return now },
+	}
+
+	// a reply of anna to igor mentioning everybody
+	comment := &posts.Comment{
+		ID:     bson.ObjectIdHex("5ebaf9ee3c04c17c56f51246"),
+		Autor:  anna,
+		Body:   "u/igor u/rvasily u/anna u/nobody",
+		Parent: parent.ID,
+	}
+	assert.Nil(t, n.Notify(post, comment))
+	if assert.Len(t, store.added, 2) {
+		assert.Equal(t, int64(2), store.added[0].UserID)
+		assert.Equal(t, KindCommentReply, store.added[0].Kind)
+		assert.Equal(t, int64(1), store.added[1].UserID)
+		assert.Equal(t, KindPostComment, store.added[1].Kind)
+		assert.Equal(t, &Notification{
+			ID:        store.added[1].ID,
+			UserID:    1,
+			Kind:      KindPostComment,
+			Actor:     anna,
+			PostID:    post.ID,
+			CommentID: comment.ID,
+			Category:  "music",
+			Title:     "News",
+			Body:      "u/igor u/rvasily u/anna u/nobody",
+			Created:   "2020-05-12T22:33:02Z",
+		}, store.added[1])
+	}
+	assert.Equal(t, []string{"user:2", "user:1"}, publisher.topics)
+
+	// the author comments on their own post mentioning anna
+	store.added = nil
+	comment = &posts.Comment{ID: bson.NewObjectId(), Autor: rvasily, Body: "thanks u/anna"}
+	assert.Nil(t, n.Notify(post, comment))
+	if assert.Len(t, store.added, 1) {
+		assert.Equal(t, int64(3), store.added[0].UserID)
+		assert.Equal(t, KindMention, store.added[0].Kind)
+	}
+
+	// nobody to notify
+	store.added = nil
+	comment = &posts.Comment{ID: bson.NewObjectId(), Autor: rvasily, Body: "bump"}
+	assert.Nil(t, n.Notify(post, comment))
+	assert.Len(t, store.added, 0)
+}
+
+func TestHandleEvent(t *testing.T) {
+	store := &testStore{}
+	n := &Notifier{
+		Store:    store,
+		Users:    testUsers{},
+		Comments: testComments{},
+		now:      time.Now,
+	}
+	post := &posts.Post{ID: bson.NewObjectId(), Author: rvasily, Category: "music", Title: "News"}
+	comment := &posts.Comment{ID: bson.NewObjectId(), Autor: igor, Body: "Nice"}
+	event, _ := events.New(events.CommentAdded, &events.CommentData{Post: events.NewPostData(post), Comment: comment})
+	assert.Nil(t, n.HandleEvent(event))
+	if assert.Len(t, store.added, 1) {
+		assert.Equal(t, int64(1), store.added[0].UserID)
+		assert.Equal(t, post.ID, store.added[0].PostID)
+		assert.Equal(t, "News", store.added[0].Title)
+	}
+
+	event, _ = events.New(events.CommentAdded, nil)
+	assert.Error(t, n.HandleEvent(event))
+}
+
+func TestExcerpt(t *testing.T) {
+	long := ""
+	for i := 0; i < ExcerptLength+10; i++ {
+		long += "я"
+	}
+	assert.Equal(t, ExcerptLength+1, len([]rune(excerpt(long))))
+	assert.Equal(t, "short", excerpt("  short\n"))
+}
@@ -0,0 +1,37 @@
+// Package paging turns a page number and a page size into offsets that are
+// safe to slice with and to send to Mongo as a skip.
+package paging
+
+import "math"
+
+// MaxOffset is the largest number of items a page may skip. mgo sends the
+// skip as an int32, so anything past it would wrap around.
+const MaxOffset = math.MaxInt32
+
+// Offset returns how many items come before the page. ok is false when the
+// page or the limit is below 1 or the page is so far away that the offset
+// would go past MaxOffset.
+func Offset(page, limit int) (offset int, ok bool) {
+	if page < 1 || limit < 1 {
+		return 0, false
+	}
+	if page-1 > MaxOffset/limit {
+		return 0, false
+	}
+	return (page - 1) * limit, true
+}
+
+// Bounds returns the [from, to) slice bounds of the page in a list of total
+// items. Pages out of range, including ones too far to have an offset, are
+// empty.
+func Bounds(page, limit, total int) (int, int) {
+	from, ok := Offset(page, limit)
+	if !ok || from > total {
+		from = total
+	}
+	to := total
+	if limit < total-from {
+		to = from + limit
+	}
+	return from, to
+}
@@ -0,0 +1,48 @@
+package paging
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffset(t *testing.T) {
+	testCases := []struct {
+		page, limit int
+		offset      int
+		ok          bool
+	}{
+		{page: 1, limit: 25, offset: 0, ok: true},
+		{page: 3, limit: 10, offset: 20, ok: true},
+		{page: 0, limit: 10, ok: false},
+		{page: -5, limit: 10, ok: false},
+		{page: 1, limit: 0, ok: false},
+		{page: MaxOffset/100 + 1, limit: 100, offset: MaxOffset / 100 * 100, ok: true},
+		{page: MaxOffset/100 + 2, limit: 100, ok: false},
+		{page: math.MaxInt64, limit: 100, ok: false},
+	}
+	for _, testCase := range testCases {
+		offset, ok := Offset(testCase.page, testCase.limit)
+		assert.Equal(t, testCase.ok, ok, "page %v limit %v", testCase.page, testCase.limit)
+		assert.Equal(t, testCase.offset, offset, "page %v limit %v", testCase.page, testCase.limit)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	testCases := []struct {
+		page, limit, total int
+		from, to           int
+	}{
+		{page: 1, limit: 25, total: 3, from: 0, to: 3},
+		{page: 2, limit: 1, total: 2, from: 1, to: 2},
+		{page: 3, limit: 25, total: 1, from: 1, to: 1},
+		{page: 0, limit: 25, total: 5, from: 5, to: 5},
+		{page: math.MaxInt64, limit: 100, total: 5, from: 5, to: 5},
+	}
+	for _, testCase := range testCases {
+		from, to := Bounds(testCase.page, testCase.limit, testCase.total)
+		assert.Equal(t, testCase.from, from, "page %v", testCase.page)
+		assert.Equal(t, testCase.to, to, "page %v", testCase.page)
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// Comment is a comment of a post, Parent is the comment it replies to.
 type Comment struct {
 	ID      bson.ObjectId `json:"id,string" bson:"_id"`
 	Autor   *user.User    `json:"author" bson:"autor"`
 	Body    string        `json:"body" bson:"body"`
 	Created string        `json:"created" bson:"created"`
+	Parent  bson.ObjectId `json:"parent,omitempty" bson:"parent,omitempty"`
 }
 
 type CommentsRepo struct {
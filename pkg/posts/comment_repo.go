@@ -13,12 +13,14 @@ func NewCommentRepo(collection *mgo.Collection) *CommentsRepo {
 	return &CommentsRepo{DB: collection}
 }
 
-func (repo *CommentsRepo) NewComment(autor *user.User, body string) (bson.ObjectId, error) {
+// NewComment saves a comment, parent is empty for a top level comment.
+func (repo *CommentsRepo) NewComment(autor *user.User, body string, parent bson.ObjectId) (bson.ObjectId, error) {
 	newCommment := &Comment{
 		ID:      bson.NewObjectId(),
 		Autor:   autor,
 		Body:    body,
 		Created: time.Now().Format(time.RFC3339),
+		Parent:  parent,
 	}
 	repo.DB.Insert(&newCommment)
 	return newCommment.ID, nil
@@ -41,3 +43,12 @@ func (repo *CommentsRepo) DelComment(commentID bson.ObjectId) (bool, error) {
 	}
 	return true, nil
 }
+
+// CountByAuthorSince counts the comments of the user written since the time,
+// the creation time is read from the object IDs.
+func (repo *CommentsRepo) CountByAuthorSince(userID int64, since time.Time) (int, error) {
+	return repo.DB.Find(bson.M{
+		"autor.id": userID,
+		"_id":      bson.M{"$gte": bson.NewObjectIdWithTime(since)},
+	}).Count()
+}
@@ -0,0 +1,146 @@
+package posts
+
+import (
+	"log"
+	"net/url"
+	"reddit/pkg/user"
+	"reddit/pkg/validation"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Actions of the domain rules.
+const (
+	DomainBlock = "block" // link posts are rejected
+	DomainFlag  = "flag"  // link posts are reported to the moderators
+	DomainAllow = "allow" // overrides a rule of a parent domain
+)
+
+const MaxRuleReasonLength = 500
+
+// DomainActions are the actions an admin can set for a domain.
+var DomainActions = []string{DomainBlock, DomainFlag, DomainAllow}
+
+// DomainRule is set by an admin for a domain and its subdomains, the rule of
+// the longest matching domain wins.
+type DomainRule struct {
+	Domain  string     `json:"domain" bson:"_id"`
+	Action  string     `json:"action" bson:"action"`
+	Reason  string     `json:"reason,omitempty" bson:"reason,omitempty"`
+	Admin   *user.User `json:"admin" bson:"admin"`
+	Created string     `json:"created" bson:"created"`
+}
+
+func validDomainAction(action string) bool {
+	for _, allowed := range DomainActions {
+		if action == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDomainRule checks a domain rule set by an admin, the reason is
+// optional.
+func ValidateDomainRule(domain, action, reason string) validation.Errors {
+	errs := validation.Errors{}
+	if !ValidURL("http://"+domain) || Domain("http://"+domain) != domain || !strings.Contains(domain, ".") {
+		errs.Add("domain", domain, "must be a lowercase host name without www.")
+	}
+	if !validDomainAction(action) {
+		errs.Add("action", action, "must be one of: "+strings.Join(DomainActions, ", "))
+	}
+	errs.MaxLength("reason", reason, MaxRuleReasonLength)
+	return errs
+}
+
+// NormalizeDomain lowercases the host and drops the port and the www.
+// prefix, so every link to a site has the same domain.
+func NormalizeDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	host = strings.Trim(host, "[]")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// Domain is the normalized host of the link, empty for a bad link.
+func Domain(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return NormalizeDomain(u.Host)
+}
+
+// parentDomains lists the domain and the domains it is a subdomain of.
+func parentDomains(domain string) []string {
+	domains := []string{domain}
+	for i := strings.Index(domain, "."); i != -1; i = strings.Index(domain, ".") {
+		domain = domain[i+1:]
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+type DomainsRepo struct {
+	DB *mgo.Collection
+}
+
+func NewDomainsRepo(collection *mgo.Collection) *DomainsRepo {
+	return &DomainsRepo{DB: collection}
+}
+
+// Set adds or replaces the rule of the domain.
+func (repo *DomainsRepo) Set(rule *DomainRule) error {
+	rule.Created = time.Now().Format(time.RFC3339)
+	_, err := repo.DB.UpsertId(rule.Domain, rule)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *DomainsRepo) Delete(domain string) (bool, error) {
+	err := repo.DB.RemoveId(domain)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *DomainsRepo) GetAll() ([]*DomainRule, error) {
+	rules := []*DomainRule{}
+	err := repo.DB.Find(nil).Sort("_id").All(&rules)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Match finds the rule for the domain, nil if there is none.
+func (repo *DomainsRepo) Match(domain string) (*DomainRule, error) {
+	rules := []*DomainRule{}
+	err := repo.DB.Find(bson.M{"_id": bson.M{"$in": parentDomains(domain)}}).All(&rules)
+	if err != nil {
+		return nil, err
+	}
+	return mostSpecific(rules), nil
+}
+
+func mostSpecific(rules []*DomainRule) *DomainRule {
+	var best *DomainRule
+	for _, rule := range rules {
+		if best == nil || len(rule.Domain) > len(best.Domain) {
+			best = rule
+		}
+	}
+	return best
+}
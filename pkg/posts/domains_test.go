@@ -0,0 +1,42 @@
+package posts
+
+import (
+	"reddit/pkg/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomain(t *testing.T) {
+	cases := map[string]string{
+		"https://www.YouTube.com/watch?v=1": "youtube.com",
+		"http://news.example.com:8080/a":    "news.example.com",
+		"http://example.com./":              "example.com",
+		"http://[::1]:8080/":                "::1",
+		"not a link":                        "",
+		"::bad":                             "",
+	}
+	for link, domain := range cases {
+		assert.Equal(t, domain, Domain(link), link)
+	}
+}
+
+func TestMostSpecific(t *testing.T) {
+	assert.Equal(t, []string{"a.b.example.com", "b.example.com", "example.com", "com"}, parentDomains("a.b.example.com"))
+	assert.Nil(t, mostSpecific(nil))
+	allow := &DomainRule{Domain: "news.example.com", Action: DomainAllow}
+	block := &DomainRule{Domain: "example.com", Action: DomainBlock}
+	assert.Equal(t, allow, mostSpecific([]*DomainRule{block, allow}))
+}
+
+func TestValidateDomainRule(t *testing.T) {
+	assert.Empty(t, ValidateDomainRule("bit.ly", DomainBlock, "Link shortener"))
+	assert.Empty(t, ValidateDomainRule("news.example.com", DomainAllow, ""))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "domain", Value: "www.Example.com", Msg: "must be a lowercase host name without www."},
+		{Location: "body", Param: "action", Value: "ban", Msg: "must be one of: block, flag, allow"},
+	}, ValidateDomainRule("www.Example.com", "ban", ""))
+	for _, domain := range []string{"", "localhost", "example.com/path", "example.com:80", "bad host.com"} {
+		assert.Len(t, ValidateDomainRule(domain, DomainFlag, ""), 1, domain)
+	}
+}
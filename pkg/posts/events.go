@@ -0,0 +1,48 @@
+package posts
+
+import "gopkg.in/mgo.v2/bson"
+
+// StagedEvent is a domain event written into the post by the same write as
+// the change it is about, so the change and its event are saved or lost
+// together. The outbox relay moves the staged events out of the posts.
+type StagedEvent struct {
+	ID   string `bson:"id"`
+	Type string `bson:"type"`
+	Time string `bson:"time"`
+	Data string `bson:"data"`
+}
+
+// Announce makes the events of a change of the post. The repo calls it with
+// the changed post right before the write and doesn't write if it fails, a
+// nil Announce stages nothing.
+type Announce func(*Post) ([]StagedEvent, error)
+
+// stage adds the events of the change to the outbox of the post and returns
+// them.
+func (a Announce) stage(post *Post) ([]StagedEvent, error) {
+	if a == nil {
+		return nil, nil
+	}
+	staged, err := a(post)
+	if err != nil {
+		return nil, err
+	}
+	post.Outbox = append(post.Outbox, staged...)
+	return staged, nil
+}
+
+// pushStaged adds pushing the staged events to the update of the change, so
+// they are written by the same write and the outbox of the post is never
+// written back as a whole.
+func pushStaged(update bson.M, staged []StagedEvent) bson.M {
+	if len(staged) == 0 {
+		return update
+	}
+	push, ok := update["$push"].(bson.M)
+	if !ok {
+		push = bson.M{}
+		update["$push"] = push
+	}
+	push["outbox"] = bson.M{"$each": staged}
+	return update
+}
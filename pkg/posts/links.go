@@ -0,0 +1,48 @@
+package posts
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are dropped from links, they differ between shares of the
+// same page. Names ending with _ are prefixes.
+var trackingParams = []string{"utm_", "fbclid", "gclid", "yclid", "dclid", "mc_cid", "mc_eid", "igshid", "ref", "ref_src"}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range trackingParams {
+		if name == param || strings.HasSuffix(param, "_") && strings.HasPrefix(name, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLink makes the same key for the links to the same page: no
+// scheme, www., default port, fragment, trailing slash and tracking params,
+// the other params sorted. It is empty for a bad link.
+func NormalizeLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(u.Hostname(), ".")), "www.")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	query := u.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+	normalized := host + strings.TrimRight(u.EscapedPath(), "/")
+	if len(query) != 0 {
+		normalized += "?" + query.Encode()
+	}
+	return normalized
+}
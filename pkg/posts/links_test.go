@@ -0,0 +1,26 @@
+package posts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLink(t *testing.T) {
+	same := []string{
+		"https://www.example.com/news/1",
+		"http://example.com/news/1/",
+		"HTTPS://Example.COM:443/news/1#comments",
+		"https://example.com/news/1?utm_source=twitter&utm_medium=social",
+		"https://example.com/news/1?fbclid=abc",
+	}
+	for _, link := range same {
+		assert.Equal(t, "example.com/news/1", NormalizeLink(link), link)
+	}
+	assert.Equal(t, "example.com?a=1&b=2", NormalizeLink("https://example.com/?b=2&a=1&ref=home"))
+	assert.Equal(t, "example.com:8080/a", NormalizeLink("http://example.com:8080/a"))
+	assert.Equal(t, "[::1]/a", NormalizeLink("http://[::1]:80/a"))
+	//Paths are case sensitive
+	assert.NotEqual(t, NormalizeLink("https://example.com/A"), NormalizeLink("https://example.com/a"))
+	assert.Equal(t, "", NormalizeLink("not a link"))
+}
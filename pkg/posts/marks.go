@@ -0,0 +1,86 @@
+package posts
+
+import (
+	"log"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// PostMarks is what a single user did to a post: hid it from listings or
+// opened it. Seen is the time of the last view.
+type PostMarks struct {
+	UserID int64         `json:"user,string" bson:"user"`
+	PostID bson.ObjectId `json:"post" bson:"post"`
+	Hidden bool          `json:"hidden" bson:"hidden"`
+	Seen   string        `json:"seen,omitempty" bson:"seen,omitempty"`
+}
+
+type MarksRepo struct {
+	DB *mgo.Collection
+}
+
+func NewMarksRepo(collection *mgo.Collection) *MarksRepo {
+	return &MarksRepo{DB: collection}
+}
+
+func (repo *MarksRepo) EnsureIndexes() error {
+	err := repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"user", "post"},
+		Unique: true,
+	})
+	if err != nil {
+		return err
+	}
+	return repo.DB.EnsureIndexKey("post")
+}
+
+func (repo *MarksRepo) SetHidden(userID int64, postID bson.ObjectId, hidden bool) error {
+	_, err := repo.DB.Upsert(
+		bson.M{"user": userID, "post": postID},
+		bson.M{"$set": bson.M{"hidden": hidden}},
+	)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *MarksRepo) SetSeen(userID int64, postID bson.ObjectId) error {
+	_, err := repo.DB.Upsert(
+		bson.M{"user": userID, "post": postID},
+		bson.M{"$set": bson.M{"seen": time.Now().Format(time.RFC3339)}},
+	)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetByPosts returns the marks of the user for the posts, posts the user
+// never touched are missing from the map.
+func (repo *MarksRepo) GetByPosts(userID int64, postIDs []bson.ObjectId) (map[bson.ObjectId]*PostMarks, error) {
+	found := []*PostMarks{}
+	err := repo.DB.Find(bson.M{
+		"user": userID,
+		"post": bson.M{"$in": postIDs},
+	}).All(&found)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	marks := make(map[bson.ObjectId]*PostMarks, len(found))
+	for _, mark := range found {
+		marks[mark.PostID] = mark
+	}
+	return marks, nil
+}
+
+// RemovePost forgets the marks of a deleted post for every user.
+func (repo *MarksRepo) RemovePost(postID bson.ObjectId) error {
+	_, err := repo.DB.RemoveAll(bson.M{"post": postID})
+	return err
+}
@@ -0,0 +1,88 @@
+package posts
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotPoll      = errors.New("Post is not a poll")
+	ErrPollClosed   = errors.New("Poll is closed")
+	ErrNoOption     = errors.New("No such poll option")
+	ErrAlreadyVoted = errors.New("User already voted in this poll")
+	ErrNotVoted     = errors.New("User has not voted in this poll")
+)
+
+// Poll is attached to posts of the "poll" type. Closes is RFC3339 or empty
+// for polls which never close.
+type Poll struct {
+	Options []string   `bson:"options"`
+	Closes  string     `bson:"closes,omitempty"`
+	Votes   []PollVote `bson:"votes"`
+}
+
+// PollVote is the option a user picked, one per user like Vote.
+type PollVote struct {
+	UserID int64 `bson:"user"`
+	Option int   `bson:"option"`
+}
+
+// PollOption is a single option with its tally as shown to clients.
+type PollOption struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+func (p *Poll) Closed(now time.Time) bool {
+	if p.Closes == "" {
+		return false
+	}
+	closes, err := time.Parse(time.RFC3339, p.Closes)
+	if err != nil {
+		return false
+	}
+	return !now.Before(closes)
+}
+
+// Tally counts votes for every option, option ids are their indexes.
+func (p *Poll) Tally() []PollOption {
+	options := make([]PollOption, len(p.Options))
+	for i, text := range p.Options {
+		options[i] = PollOption{ID: i, Text: text}
+	}
+	for _, vote := range p.Votes {
+		if vote.Option >= 0 && vote.Option < len(options) {
+			options[vote.Option].Votes++
+		}
+	}
+	return options
+}
+
+// vote casts or, if change is set, changes the vote of userID.
+func (p *Poll) vote(userID int64, option int, change bool, now time.Time) error {
+	if p.Closed(now) {
+		return ErrPollClosed
+	}
+	if option < 0 || option >= len(p.Options) {
+		return ErrNoOption
+	}
+	for i := range p.Votes {
+		if p.Votes[i].UserID != userID {
+			continue
+		}
+		if !change {
+			return ErrAlreadyVoted
+		}
+		p.Votes[i].Option = option
+		return nil
+	}
+	if change {
+		return ErrNotVoted
+	}
+	p.Votes = append(p.Votes, PollVote{
+		UserID: userID,
+		Option: option,
+	})
+	return nil
+}
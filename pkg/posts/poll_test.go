@@ -0,0 +1,170 @@
+package posts
+
+import (
+	"fmt"
+	"reddit/pkg/user"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func newTestPoll(closes string, votes ...PollVote) *Post {
+	return &Post{
+		Author:     testPost1.Author,
+		Category:   "music",
+		CommentsID: []bson.ObjectId{},
+		ID:         testPost1.ID,
+		Title:      "Best band?",
+		Type:       "poll",
+		Votes:      []Vote{{UserID: 1, Rating: 1}},
+		Poll: &Poll{
+			Options: []string{"Beatles", "Queen", "ABBA"},
+			Closes:  closes,
+			Votes:   votes,
+		},
+	}
+}
+
+func TestPollTally(t *testing.T) {
+	poll := newTestPoll("", PollVote{UserID: 1, Option: 1}, PollVote{UserID: 2, Option: 1}, PollVote{UserID: 3, Option: 2}).Poll
+	assert.Equal(t, []PollOption{
+		{ID: 0, Text: "Beatles", Votes: 0},
+		{ID: 1, Text: "Queen", Votes: 2},
+		{ID: 2, Text: "ABBA", Votes: 1},
+	}, poll.Tally())
+
+	now := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	assert.False(t, poll.Closed(now))
+	poll.Closes = "2020-05-12T22:00:00Z"
+	assert.True(t, poll.Closed(now))
+	assert.False(t, poll.Closed(now.Add(-time.Second)))
+}
+
+func TestVotePoll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+	voter := &user.User{
+		ID:       8,
+		Username: "igor",
+	}
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	//Cast a vote
+	poll := newTestPoll(future)
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+	mockDB.EXPECT().Update(
+		bson.M{"_id": poll.ID, "poll.votes.user": bson.M{"$ne": int64(8)}},
+		bson.M{"$push": bson.M{"poll.votes": PollVote{UserID: 8, Option: 2}}},
+	).Return(nil)
+
+	responsePost, err := testRepo.VotePoll(voter, poll.ID, 2)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, []PollVote{{UserID: 8, Option: 2}}, responsePost.Poll.Votes)
+
+	//Vote twice
+	poll = newTestPoll(future, PollVote{UserID: 8, Option: 2})
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+
+	responsePost, err = testRepo.VotePoll(voter, poll.ID, 0)
+	assert.Empty(t, responsePost)
+	assert.Equal(t, ErrAlreadyVoted, err)
+
+	//Change the vote
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+	mockDB.EXPECT().Update(
+		bson.M{"_id": poll.ID, "poll.votes.user": int64(8)},
+		bson.M{"$set": bson.M{"poll.votes.$.option": 0}},
+	).Return(nil)
+
+	responsePost, err = testRepo.ChangePollVote(voter, poll.ID, 0)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, []PollVote{{UserID: 8, Option: 0}}, responsePost.Poll.Votes)
+
+	//Change without voting
+	poll = newTestPoll(future)
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+
+	_, err = testRepo.ChangePollVote(voter, poll.ID, 0)
+	assert.Equal(t, ErrNotVoted, err)
+
+	//A concurrent request voted first
+	poll = newTestPoll(future)
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+	mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mgo.ErrNotFound)
+
+	_, err = testRepo.VotePoll(voter, poll.ID, 1)
+	assert.Equal(t, ErrAlreadyVoted, err)
+
+	//The vote was removed meanwhile
+	poll = newTestPoll(future, PollVote{UserID: 8, Option: 2})
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+	mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mgo.ErrNotFound)
+
+	_, err = testRepo.ChangePollVote(voter, poll.ID, 1)
+	assert.Equal(t, ErrNotVoted, err)
+
+	//Closed poll
+	poll = newTestPoll(past, PollVote{UserID: 8, Option: 2})
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind).Times(2)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll).Times(2)
+
+	_, err = testRepo.VotePoll(&user.User{ID: 9}, poll.ID, 0)
+	assert.Equal(t, ErrPollClosed, err)
+	_, err = testRepo.ChangePollVote(voter, poll.ID, 0)
+	assert.Equal(t, ErrPollClosed, err)
+
+	//Unknown option
+	poll = newTestPoll("")
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+
+	_, err = testRepo.VotePoll(voter, poll.ID, 3)
+	assert.Equal(t, ErrNoOption, err)
+
+	//Not a poll
+	mockDB.EXPECT().Find(bson.M{"_id": testPost3.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, testPost3)
+
+	_, err = testRepo.VotePoll(voter, testPost3.ID, 0)
+	assert.Equal(t, ErrNotPoll, err)
+
+	//Err update
+	poll = newTestPoll("")
+	mockDB.EXPECT().Find(bson.M{"_id": poll.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, poll)
+	mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Internal server error"))
+
+	_, err = testRepo.VotePoll(voter, poll.ID, 0)
+	assert.EqualError(t, err, "Error update BD: Internal server error")
+}
+
+func TestAddPoll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	responsePost, err := testRepo.AddPoll(testPost3.Author, "music", "Best band?", "", &Poll{
+		Options: []string{"Beatles", "Queen"},
+	}, Flags{}, nil)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "poll", responsePost.Type)
+	assert.Equal(t, []PollVote{}, responsePost.Poll.Votes)
+}
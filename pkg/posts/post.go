@@ -6,6 +6,12 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// Kinds of items which can be saved or reported.
+const (
+	ItemPost    = "post"
+	ItemComment = "comment"
+)
+
 type Post struct {
 	Author           *user.User      `bson:"author"`
 	Category         string          `bson:"category"`
@@ -15,11 +21,72 @@ type Post struct {
 	Score            int             `bson:"score"`
 	Text             string          `bson:"text,omitempty"`
 	Link             string          `bson:"url,omitempty"`
+	Domain           string          `bson:"domain,omitempty"`         // normalized host of Link
+	NormalizedLink   string          `bson:"normalizedLink,omitempty"` // finds the posts of the same page
 	Title            string          `bson:"title"`
 	Type             string          `bson:"type"`
 	UpvotePercentage int             `bson:"upvotePercentage"`
 	Views            int             `bson:"views"`
 	Votes            []Vote          `bson:"votes"` // userID, vote=1,-1
+	Preview          *LinkPreview    `bson:"preview,omitempty"`
+	Image            *Image          `bson:"image,omitempty"`
+	Poll             *Poll           `bson:"poll,omitempty"`
+	Crosspost        *Crosspost      `bson:"crosspost,omitempty"`
+	Flair            string          `bson:"flair,omitempty"`
+	NSFW             bool            `bson:"nsfw"`
+	Spoiler          bool            `bson:"spoiler"`
+	Sticky           bool            `bson:"sticky"`
+	Locked           bool            `bson:"locked"`   // no new comments
+	Archived         bool            `bson:"archived"` // no new comments and votes
+	Pending          bool            `bson:"pending"`  // waits for a moderator approval
+	Outbox           []StagedEvent   `bson:"outbox,omitempty" json:"-"`
+	StickySlot       string          `bson:"stickySlot,omitempty" json:"-"` // category:n, unique among the sticky posts
+}
+
+// Flags are the marks a post is created with, SetFlags changes them later.
+type Flags struct {
+	Flair   string
+	NSFW    bool
+	Spoiler bool
+}
+
+func (p *Post) setFlags(flags Flags) {
+	p.Flair = flags.Flair
+	p.NSFW = flags.NSFW
+	p.Spoiler = flags.Spoiler
+}
+
+// LinkPreview is what the linked page tells about itself, it is filled
+// in the background after a link post is created.
+type LinkPreview struct {
+	Title       string `json:"title,omitempty" bson:"title,omitempty"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	Image       string `json:"image,omitempty" bson:"image,omitempty"`
+	SiteName    string `json:"siteName,omitempty" bson:"siteName,omitempty"`
+	Favicon     string `json:"favicon,omitempty" bson:"favicon,omitempty"`
+}
+
+// Image is the uploaded picture of an image post. Key and ThumbnailKey
+// address the files in the blob store and are never shown to clients.
+type Image struct {
+	URL          string `json:"url" bson:"url"`
+	Thumbnail    string `json:"thumbnail" bson:"thumbnail"`
+	Width        int    `json:"width" bson:"width"`
+	Height       int    `json:"height" bson:"height"`
+	ContentType  string `json:"contentType" bson:"contentType"`
+	Key          string `json:"-" bson:"key"`
+	ThumbnailKey string `json:"-" bson:"thumbnailKey"`
+}
+
+// Crosspost points to the post this one shares into another category.
+// Title, author and category of the original are copied, so a crosspost
+// doesn't need the original to be shown and survives its deletion.
+type Crosspost struct {
+	PostID   bson.ObjectId `json:"id" bson:"id"`
+	Title    string        `json:"title,omitempty" bson:"title,omitempty"`
+	Author   *user.User    `json:"author,omitempty" bson:"author,omitempty"`
+	Category string        `json:"category" bson:"category"`
+	Deleted  bool          `json:"deleted" bson:"deleted"`
 }
 
 type Vote struct {
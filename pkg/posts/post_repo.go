@@ -1,6 +1,7 @@
 package posts
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"reddit/pkg/user"
@@ -17,13 +18,26 @@ type PostsRepo struct {
 type FindInterface interface {
 	One(interface{}) error
 	All(interface{}) error
+	Count() (int, error)
 }
 
 type PostRepositoryDBInterface interface {
-	Find(interface{}) *mgo.Query // FindInterface
+	Find(interface{}) FindInterface
 	Insert(...interface{}) error
 	Update(interface{}, interface{}) error
+	UpdateAll(interface{}, interface{}) (*mgo.ChangeInfo, error)
 	Remove(interface{}) error
+	EnsureIndex(mgo.Index) error
+}
+
+// MongoCollection lets *mgo.Collection be used as PostRepositoryDBInterface,
+// mgo returns the concrete *mgo.Query from Find so it can't satisfy it alone.
+type MongoCollection struct {
+	*mgo.Collection
+}
+
+func (c *MongoCollection) Find(query interface{}) FindInterface {
+	return c.Collection.Find(query)
 }
 
 func NewRepo(collection PostRepositoryDBInterface) *PostsRepo {
@@ -50,6 +64,38 @@ func (repo *PostsRepo) GetCategory(category string) ([]*Post, error) {
 	return posts, nil
 }
 
+func (repo *PostsRepo) GetByCategories(categories []string) ([]*Post, error) {
+	posts := []*Post{}
+	err := repo.DB.Find(bson.M{"category": bson.M{"$in": categories}}).All(&posts)
+	if err != nil {
+		log.Printf("DB error")
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetByDomain returns the link posts to the normalized domain.
+func (repo *PostsRepo) GetByDomain(domain string) ([]*Post, error) {
+	posts := []*Post{}
+	err := repo.DB.Find(bson.M{"domain": domain}).All(&posts)
+	if err != nil {
+		log.Printf("DB error")
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetByLink returns the posts of the link normalized by NormalizeLink.
+func (repo *PostsRepo) GetByLink(normalized string) ([]*Post, error) {
+	posts := []*Post{}
+	err := repo.DB.Find(bson.M{"normalizedLink": normalized}).All(&posts)
+	if err != nil {
+		log.Printf("DB error")
+		return nil, err
+	}
+	return posts, nil
+}
+
 func (repo *PostsRepo) GetByID(id bson.ObjectId) (*Post, error) {
 	var post *Post
 	err := repo.DB.Find(bson.M{"_id": id}).One(&post)
@@ -70,16 +116,10 @@ func (repo *PostsRepo) GetByUserLogin(login string) ([]*Post, error) {
 	return posts, nil
 }
 
-func (repo *PostsRepo) Add(
-	user *user.User,
-	category string,
-	title string,
-	typePost string,
-	text string,
-	link string) (*Post, error) {
+func newPost(user *user.User, category, title, typePost string) *Post {
 	nowTime := time.Now()
 	timestamp := nowTime.Format(time.RFC3339)
-	newPost := &Post{
+	return &Post{
 		ID:               bson.NewObjectId(),
 		Author:           user,
 		Category:         category,
@@ -87,25 +127,40 @@ func (repo *PostsRepo) Add(
 		Created:          timestamp,
 		Score:            1,
 		Title:            title,
+		Type:             typePost,
 		UpvotePercentage: 100,
 		Views:            0,
-		Votes:            make([]Vote, 0),
+		Votes: []Vote{{
+			UserID: user.ID,
+			Rating: 1,
+		}},
 	}
+}
+
+func (repo *PostsRepo) Add(
+	user *user.User,
+	category string,
+	title string,
+	typePost string,
+	text string,
+	link string,
+	flags Flags,
+	announce Announce) (*Post, error) {
+	newPost := newPost(user, category, title, typePost)
+	newPost.setFlags(flags)
 	if typePost == "link" {
 		newPost.Link = link
-		newPost.Text = ""
-		newPost.Type = typePost
+		newPost.Domain = Domain(link)
+		newPost.NormalizedLink = NormalizeLink(link)
 	} else {
-		newPost.Link = ""
 		newPost.Text = text
-		newPost.Type = typePost
 	}
-	newPost.Votes = append(newPost.Votes, Vote{
-		UserID: user.ID,
-		Rating: 1,
-	})
+	_, err := announce.stage(newPost)
+	if err != nil {
+		return nil, err
+	}
 
-	err := repo.DB.Insert(&newPost)
+	err = repo.DB.Insert(&newPost)
 	if err != nil {
 		log.Printf("Insert error")
 		return nil, err
@@ -113,37 +168,328 @@ func (repo *PostsRepo) Add(
 	return newPost, nil
 }
 
-func (repo *PostsRepo) AddComment(postID, commentID bson.ObjectId) (*Post, error) {
+func (repo *PostsRepo) AddImage(
+	user *user.User,
+	category string,
+	title string,
+	image *Image,
+	flags Flags,
+	announce Announce) (*Post, error) {
+	newPost := newPost(user, category, title, "image")
+	newPost.setFlags(flags)
+	newPost.Image = image
+	_, err := announce.stage(newPost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.DB.Insert(&newPost)
+	if err != nil {
+		log.Printf("Insert error")
+		return nil, err
+	}
+	return newPost, nil
+}
+
+func (repo *PostsRepo) AddPoll(
+	user *user.User,
+	category string,
+	title string,
+	text string,
+	poll *Poll,
+	flags Flags,
+	announce Announce) (*Post, error) {
+	newPost := newPost(user, category, title, "poll")
+	newPost.setFlags(flags)
+	newPost.Text = text
+	if poll.Votes == nil {
+		poll.Votes = make([]PollVote, 0)
+	}
+	newPost.Poll = poll
+	_, err := announce.stage(newPost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.DB.Insert(&newPost)
+	if err != nil {
+		log.Printf("Insert error")
+		return nil, err
+	}
+	return newPost, nil
+}
+
+func (repo *PostsRepo) VotePoll(user *user.User, postID bson.ObjectId, option int) (*Post, error) {
+	return repo.pollVote(user, postID, option, false)
+}
+
+func (repo *PostsRepo) ChangePollVote(user *user.User, postID bson.ObjectId, option int) (*Post, error) {
+	return repo.pollVote(user, postID, option, true)
+}
+
+func (repo *PostsRepo) pollVote(user *user.User, postID bson.ObjectId, option int, change bool) (*Post, error) {
+	elem, err := repo.GetByID(postID)
+	if err != nil {
+		return nil, fmt.Errorf("DB err: %v", err)
+	}
+	if elem.Poll == nil {
+		return nil, ErrNotPoll
+	}
+	if elem.Archived {
+		return nil, ErrArchived
+	}
+	err = elem.Poll.vote(user.ID, option, change, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// the vote is written by a conditional update, so two concurrent
+	// requests can neither vote twice nor overwrite each other's votes
+	selector := bson.M{"_id": postID, "poll.votes.user": bson.M{"$ne": user.ID}}
+	update := bson.M{"$push": bson.M{"poll.votes": PollVote{UserID: user.ID, Option: option}}}
+	conflict := ErrAlreadyVoted
+	if change {
+		selector = bson.M{"_id": postID, "poll.votes.user": user.ID}
+		update = bson.M{"$set": bson.M{"poll.votes.$.option": option}}
+		conflict = ErrNotVoted
+	}
+	err = repo.DB.Update(selector, update)
+	if err == mgo.ErrNotFound {
+		return nil, conflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error update BD: %v", err)
+	}
+	return elem, nil
+}
+
+var ErrOriginalDeleted = errors.New("Original post was deleted")
+
+// Crosspost shares original into another category. Crossposting a crosspost
+// points to the same original, so there is never a chain of them.
+func (repo *PostsRepo) Crosspost(
+	user *user.User,
+	original *Post,
+	category string,
+	title string,
+	flags Flags,
+	announce Announce) (*Post, error) {
+	crosspost := original.Crosspost
+	if crosspost == nil {
+		crosspost = &Crosspost{
+			PostID:   original.ID,
+			Title:    original.Title,
+			Author:   original.Author,
+			Category: original.Category,
+		}
+	}
+	if crosspost.Deleted {
+		return nil, ErrOriginalDeleted
+	}
+	if title == "" {
+		title = crosspost.Title
+	}
+	newPost := newPost(user, category, title, "crosspost")
+	newPost.setFlags(flags)
+	newPost.Crosspost = crosspost
+	_, err := announce.stage(newPost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.DB.Insert(&newPost)
+	if err != nil {
+		log.Printf("Insert error")
+		return nil, err
+	}
+	return newPost, nil
+}
+
+// AddComment pushes the comment into the post, the other fields of the post
+// aren't written, so concurrent changes of them stay.
+func (repo *PostsRepo) AddComment(postID, commentID bson.ObjectId, announce Announce) (*Post, error) {
 	post, err := repo.GetByID(postID)
 	if err != nil {
 		return nil, fmt.Errorf("Error getting post from BD: %v", err)
 	}
 	post.CommentsID = append(post.CommentsID, commentID)
+	staged, err := announce.stage(post)
+	if err != nil {
+		return nil, err
+	}
 	err = repo.DB.Update(
 		bson.M{"_id": postID},
-		post)
+		pushStaged(bson.M{"$push": bson.M{"comments": commentID}}, staged))
 	if err != nil {
 		return nil, fmt.Errorf("Error update BD: %v", err)
 	}
 	return post, nil
 }
 
-func (repo *PostsRepo) UpViews(postID bson.ObjectId) error {
-	post, err := repo.GetByID(postID)
+// MaxStickyPosts is how many posts can be pinned to the top of a category.
+const MaxStickyPosts = 2
+
+var (
+	ErrTooManySticky = fmt.Errorf("Category already has %d sticky posts", MaxStickyPosts)
+	ErrLocked        = errors.New("Post is locked")
+	ErrArchived      = errors.New("Post is archived")
+)
+
+// EnsureIndexes makes the sticky slots unique, so a category never has more
+// than MaxStickyPosts sticky posts however many moderators pin at once.
+func (repo *PostsRepo) EnsureIndexes() error {
+	return repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"stickySlot"},
+		Unique: true,
+		Sparse: true,
+	})
+}
+
+func stickySlot(category string, slot int) string {
+	return fmt.Sprintf("%s:%d", category, slot)
+}
+
+// SetSticky pins the post to the top of its category or unpins it. A pinned
+// post takes one of the free sticky slots of its category, the unique index
+// refuses the slots taken by others.
+func (repo *PostsRepo) SetSticky(post *Post, sticky bool) error {
+	if !sticky {
+		err := repo.DB.Update(
+			bson.M{"_id": post.ID},
+			bson.M{"$set": bson.M{"sticky": false}, "$unset": bson.M{"stickySlot": ""}})
+		if err != nil {
+			return fmt.Errorf("Error update BD: %v", err)
+		}
+		post.Sticky = false
+		post.StickySlot = ""
+		return nil
+	}
+	if post.Sticky {
+		return nil
+	}
+	for slot := 1; slot <= MaxStickyPosts; slot++ {
+		err := repo.DB.Update(
+			bson.M{"_id": post.ID},
+			bson.M{"$set": bson.M{"sticky": true, "stickySlot": stickySlot(post.Category, slot)}})
+		if mgo.IsDup(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Error update BD: %v", err)
+		}
+		post.Sticky = true
+		post.StickySlot = stickySlot(post.Category, slot)
+		return nil
+	}
+	return ErrTooManySticky
+}
+
+func (repo *PostsRepo) SetLocked(postID bson.ObjectId, locked bool) error {
+	err := repo.DB.Update(
+		bson.M{"_id": postID},
+		bson.M{"$set": bson.M{"locked": locked}})
 	if err != nil {
-		return fmt.Errorf("Error getting post from BD: %v", err)
+		return fmt.Errorf("Error update BD: %v", err)
+	}
+	return nil
+}
+
+// SetPending hides the post from listings until a moderator approves it.
+func (repo *PostsRepo) SetPending(post *Post, pending bool, announce Announce) error {
+	post.Pending = pending
+	staged, err := announce.stage(post)
+	if err != nil {
+		return err
 	}
-	post.Views++
 	err = repo.DB.Update(
+		bson.M{"_id": post.ID},
+		pushStaged(bson.M{"$set": bson.M{"pending": pending}}, staged))
+	if err != nil {
+		return fmt.Errorf("Error update BD: %v", err)
+	}
+	return nil
+}
+
+// Karma is the sum of the scores of the user's posts.
+func (repo *PostsRepo) Karma(userID int64) (int, error) {
+	var posts []*Post
+	err := repo.DB.Find(bson.M{"author.id": userID}).All(&posts)
+	if err != nil {
+		return 0, fmt.Errorf("DB err: %v", err)
+	}
+	karma := 0
+	for _, post := range posts {
+		karma += post.Score
+	}
+	return karma, nil
+}
+
+// CountByAuthorSince counts the posts of the user created since the time,
+// the creation time is read from the object IDs.
+func (repo *PostsRepo) CountByAuthorSince(userID int64, since time.Time) (int, error) {
+	count, err := repo.DB.Find(bson.M{
+		"author.id": userID,
+		"_id":       bson.M{"$gte": bson.NewObjectIdWithTime(since)},
+	}).Count()
+	if err != nil {
+		return 0, fmt.Errorf("DB err: %v", err)
+	}
+	return count, nil
+}
+
+// ArchiveOlderThan archives the posts created before cutoff and returns how
+// many of them were archived. Created is compared as a string, which is right
+// as long as the server keeps its time zone.
+func (repo *PostsRepo) ArchiveOlderThan(cutoff time.Time) (int, error) {
+	info, err := repo.DB.UpdateAll(
+		bson.M{
+			"created":  bson.M{"$lt": cutoff.Format(time.RFC3339)},
+			"archived": bson.M{"$ne": true},
+		},
+		bson.M{"$set": bson.M{"archived": true}})
+	if err != nil {
+		return 0, fmt.Errorf("Error update BD: %v", err)
+	}
+	return info.Updated, nil
+}
+
+// AddViews increments the view counters of the posts. Posts which were
+// updated are removed from the map, deleted posts are dropped silently.
+func (repo *PostsRepo) AddViews(views map[bson.ObjectId]int) error {
+	for postID, count := range views {
+		err := repo.DB.Update(
+			bson.M{"_id": postID},
+			bson.M{"$inc": bson.M{"views": count}})
+		if err != nil && err != mgo.ErrNotFound {
+			return fmt.Errorf("Error update BD: %v", err)
+		}
+		delete(views, postID)
+	}
+	return nil
+}
+
+func (repo *PostsRepo) SetPreview(postID bson.ObjectId, preview *LinkPreview) error {
+	err := repo.DB.Update(
 		bson.M{"_id": postID},
-		post)
+		bson.M{"$set": bson.M{"preview": preview}})
 	if err != nil {
 		return fmt.Errorf("Error update BD: %v", err)
 	}
 	return nil
 }
 
-func (repo *PostsRepo) DeleteComment(postID, commentID bson.ObjectId) (*Post, error) {
+func (repo *PostsRepo) SetFlags(postID bson.ObjectId, flair string, nsfw, spoiler bool) error {
+	err := repo.DB.Update(
+		bson.M{"_id": postID},
+		bson.M{"$set": bson.M{"flair": flair, "nsfw": nsfw, "spoiler": spoiler}})
+	if err != nil {
+		return fmt.Errorf("Error update BD: %v", err)
+	}
+	return nil
+}
+
+// DeleteComment pulls the comment out of the post.
+func (repo *PostsRepo) DeleteComment(postID, commentID bson.ObjectId, announce Announce) (*Post, error) {
 	post, err := repo.GetByID(postID)
 	if err != nil {
 		return nil, fmt.Errorf("Error getting post from BD: %v", err)
@@ -155,102 +501,148 @@ func (repo *PostsRepo) DeleteComment(postID, commentID bson.ObjectId) (*Post, er
 			break
 		}
 	}
+	staged, err := announce.stage(post)
+	if err != nil {
+		return nil, err
+	}
 
 	err = repo.DB.Update(
 		bson.M{"_id": postID},
-		post)
+		pushStaged(bson.M{"$pull": bson.M{"comments": commentID}}, staged))
 	if err != nil {
 		return nil, fmt.Errorf("Error update BD: %v", err)
 	}
 	return post, nil
 }
 
-func (repo *PostsRepo) Upvote(user *user.User, postID bson.ObjectId) (*Post, error) {
-	elem, err := repo.GetByID(postID)
-	if err != nil {
-		return nil, fmt.Errorf("DB err: %v", err)
-	}
-	nUpVotes := 0
-	iUserVote := -1
-	for i, vote := range elem.Votes {
-		if vote.UserID == user.ID {
-			iUserVote = i
+// maxVoteTries is how many times a vote is read and written again when
+// other votes came in between.
+const maxVoteTries = 5
+
+var ErrVoteConflict = errors.New("Votes of the post keep changing, try again")
+
+func (repo *PostsRepo) Upvote(user *user.User, postID bson.ObjectId, announce Announce) (*Post, error) {
+	return repo.vote(user, postID, 1, announce)
+}
+
+func (repo *PostsRepo) Downvote(user *user.User, postID bson.ObjectId, announce Announce) (*Post, error) {
+	return repo.vote(user, postID, -1, announce)
+}
+
+// vote writes the votes, the score and the upvote percentage only if the
+// votes are still as they were read, else it reads them again. So two
+// voters never overwrite each other and the other fields of the post, e.g.
+// the views or the staged events, are never written back.
+func (repo *PostsRepo) vote(user *user.User, postID bson.ObjectId, rating int, announce Announce) (*Post, error) {
+	for try := 0; try < maxVoteTries; try++ {
+		elem, err := repo.GetByID(postID)
+		if err != nil {
+			return nil, fmt.Errorf("DB err: %v", err)
 		}
-		if vote.Rating == 1 {
-			nUpVotes++
+		if elem.Archived {
+			return nil, ErrArchived
 		}
-	}
-	if iUserVote != -1 {
-		if elem.Votes[iUserVote].Rating == -1 {
-			nUpVotes++
-			elem.Score += 2
-			elem.Votes[iUserVote].Rating = 1
+		read := append([]Vote{}, elem.Votes...)
+		elem.castVote(user.ID, rating)
+		staged, err := announce.stage(elem)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		nUpVotes += 1
-		elem.Score++
-		elem.Votes = append(elem.Votes, Vote{
-			UserID: user.ID,
-			Rating: 1,
-		})
-	}
 
-	elem.UpvotePercentage = nUpVotes * 100 / len(elem.Votes)
-
-	err = repo.DB.Update(
-		bson.M{"_id": postID},
-		elem)
-	if err != nil {
-		return nil, fmt.Errorf("Error update BD: %v", err)
+		err = repo.DB.Update(
+			bson.M{"_id": postID, "votes": read},
+			pushStaged(bson.M{"$set": bson.M{
+				"votes":            elem.Votes,
+				"score":            elem.Score,
+				"upvotePercentage": elem.UpvotePercentage,
+			}}, staged))
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error update BD: %v", err)
+		}
+		return elem, nil
 	}
-	return elem, nil
+	return nil, ErrVoteConflict
 }
 
-func (repo *PostsRepo) Downvote(user *user.User, postID bson.ObjectId) (*Post, error) {
-	elem, err := repo.GetByID(postID)
-	if err != nil {
-		return nil, fmt.Errorf("DB err: %v", err)
+// castVote sets the vote of the user, rating is 1 or -1, and counts the
+// score and the upvote percentage again.
+func (post *Post) castVote(userID int64, rating int) {
+	voted := false
+	for i := range post.Votes {
+		if post.Votes[i].UserID == userID {
+			post.Score += rating - post.Votes[i].Rating
+			post.Votes[i].Rating = rating
+			voted = true
+		}
+	}
+	if !voted {
+		post.Score += rating
+		post.Votes = append(post.Votes, Vote{
+			UserID: userID,
+			Rating: rating,
+		})
 	}
 	nUpVotes := 0
-	iUserVote := -1
-	for i, vote := range elem.Votes {
-		if vote.UserID == user.ID {
-			iUserVote = i
-		}
+	for _, vote := range post.Votes {
 		if vote.Rating == 1 {
 			nUpVotes++
 		}
 	}
-	if iUserVote != -1 {
-		if elem.Votes[iUserVote].Rating == 1 {
-			elem.Score -= 2
-			nUpVotes--
-			elem.Votes[iUserVote].Rating = -1
+	post.UpvotePercentage = nUpVotes * 100 / len(post.Votes)
+}
+
+// maxDeleteTries is how many times the staged events of a post are collected
+// before its removal when new ones keep coming.
+const maxDeleteTries = 5
+
+var ErrDeleteConflict = errors.New("Events of the post keep coming, try again")
+
+// Delete removes the post. The events staged in the post would be lost with
+// it, so the post is removed only when it has none and collect moves them out
+// meanwhile. Without collect nothing delivers the staged events and the post
+// is removed as is.
+func (repo *PostsRepo) Delete(postID bson.ObjectId, collect func() error) (bool, error) {
+	selector := bson.M{"_id": postID}
+	if collect != nil {
+		selector["outbox.id"] = bson.M{"$exists": false}
+	}
+	for try := 0; ; try++ {
+		err := repo.DB.Remove(selector)
+		if err == nil {
+			break
+		}
+		if err != mgo.ErrNotFound {
+			return false, err
+		}
+		if collect == nil {
+			return false, nil
+		}
+		n, err := repo.DB.Find(bson.M{"_id": postID}).Count()
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			return false, nil
+		}
+		if try == maxDeleteTries-1 {
+			return false, ErrDeleteConflict
+		}
+		// the post is there with staged events
+		err = collect()
+		if err != nil {
+			return false, err
 		}
-	} else {
-		elem.Score--
-		elem.Votes = append(elem.Votes, Vote{
-			UserID: user.ID,
-			Rating: -1,
-		})
 	}
-
-	elem.UpvotePercentage = nUpVotes * 100 / len(elem.Votes)
-	err = repo.DB.Update(
-		bson.M{"_id": postID},
-		elem)
+	// crossposts stay, but don't show who wrote what anymore
+	_, err := repo.DB.UpdateAll(
+		bson.M{"crosspost.id": postID},
+		bson.M{"$set": bson.M{"crosspost.deleted": true},
+			"$unset": bson.M{"crosspost.title": "", "crosspost.author": ""}})
 	if err != nil {
-		return nil, fmt.Errorf("Error update BD: %v", err)
-	}
-	return elem, nil
-}
-
-func (repo *PostsRepo) Delete(postID bson.ObjectId) (bool, error) {
-	err := repo.DB.Remove(bson.M{"_id": postID})
-	if err == mgo.ErrNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
+		log.Printf("Can't mark crossposts of deleted post: %v", err)
 	}
 	return true, nil
 }
@@ -1,11 +1,12 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: repo.go
+// Source: post_repo.go
 
 // Package posts is a generated GoMock package.
 package posts
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	mgo_v2 "gopkg.in/mgo.v2"
 	reflect "reflect"
 )
 
@@ -60,6 +61,21 @@ func (mr *MockFindInterfaceMockRecorder) All(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockFindInterface)(nil).All), arg0)
 }
 
+// Count mocks base method
+func (m *MockFindInterface) Count() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count
+func (mr *MockFindInterfaceMockRecorder) Count() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockFindInterface)(nil).Count))
+}
+
 // MockPostRepositoryDBInterface is a mock of PostRepositoryDBInterface interface
 type MockPostRepositoryDBInterface struct {
 	ctrl     *gomock.Controller
@@ -129,6 +145,21 @@ func (mr *MockPostRepositoryDBInterfaceMockRecorder) Update(arg0, arg1 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockPostRepositoryDBInterface)(nil).Update), arg0, arg1)
 }
 
+// UpdateAll mocks base method
+func (m *MockPostRepositoryDBInterface) UpdateAll(arg0, arg1 interface{}) (*mgo_v2.ChangeInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAll", arg0, arg1)
+	ret0, _ := ret[0].(*mgo_v2.ChangeInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAll indicates an expected call of UpdateAll
+func (mr *MockPostRepositoryDBInterfaceMockRecorder) UpdateAll(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAll", reflect.TypeOf((*MockPostRepositoryDBInterface)(nil).UpdateAll), arg0, arg1)
+}
+
 // Remove mocks base method
 func (m *MockPostRepositoryDBInterface) Remove(arg0 interface{}) error {
 	m.ctrl.T.Helper()
@@ -142,3 +173,17 @@ func (mr *MockPostRepositoryDBInterfaceMockRecorder) Remove(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockPostRepositoryDBInterface)(nil).Remove), arg0)
 }
+
+// EnsureIndex mocks base method
+func (m *MockPostRepositoryDBInterface) EnsureIndex(arg0 mgo_v2.Index) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndex", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndex indicates an expected call of EnsureIndex
+func (mr *MockPostRepositoryDBInterfaceMockRecorder) EnsureIndex(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndex", reflect.TypeOf((*MockPostRepositoryDBInterface)(nil).EnsureIndex), arg0)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reddit/pkg/user"
 	"testing"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -145,6 +146,94 @@ func TestGetCategory(t *testing.T) {
 	assert.EqualError(t, err, "Internal error")
 }
 
+func TestGetByDomain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	expectPosts := []*Post{testPost2}
+	mockDB.EXPECT().Find(bson.M{"domain": "example.com"}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).SetArg(0, expectPosts)
+
+	responsePosts, err := testRepo.GetByDomain("example.com")
+	assert.Equal(t, expectPosts, responsePosts)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Find(bson.M{"domain": "example.com"}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	responseErrPosts, err := testRepo.GetByDomain("example.com")
+	assert.Empty(t, responseErrPosts)
+	assert.EqualError(t, err, "Internal error")
+}
+
+func TestGetByLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	expectPosts := []*Post{testPost2}
+	mockDB.EXPECT().Find(bson.M{"normalizedLink": "example.com/news"}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).SetArg(0, expectPosts)
+
+	responsePosts, err := testRepo.GetByLink("example.com/news")
+	assert.Equal(t, expectPosts, responsePosts)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Find(bson.M{"normalizedLink": "example.com/news"}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	responseErrPosts, err := testRepo.GetByLink("example.com/news")
+	assert.Empty(t, responseErrPosts)
+	assert.EqualError(t, err, "Internal error")
+}
+
+func TestGetByCategories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	expectPosts := []*Post{testPost1, testPost2}
+	categories := []string{"music", "funny"}
+	mockDB.EXPECT().Find(bson.M{"category": bson.M{"$in": categories}}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).SetArg(0, expectPosts)
+
+	responsePosts, err := testRepo.GetByCategories(categories)
+	assert.Equal(t, expectPosts, responsePosts)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Find(bson.M{"category": bson.M{"$in": categories}}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	responseErrPosts, err := testRepo.GetByCategories(categories)
+
+	assert.Empty(t, responseErrPosts)
+	assert.EqualError(t, err, "Internal error")
+}
+
+func TestSortByHot(t *testing.T) {
+	old := &Post{Score: 100, Created: "2020-05-01T10:00:00+03:00"}
+	fresh := &Post{Score: 1, Created: "2020-05-12T10:00:00+03:00"}
+	freshPopular := &Post{Score: 50, Created: "2020-05-12T10:00:00+03:00"}
+	buried := &Post{Score: -10, Created: "2020-05-12T10:00:00+03:00"}
+
+	list := []*Post{buried, old, fresh, freshPopular}
+	SortByHot(list)
+	assert.Equal(t, []*Post{freshPopular, fresh, buried, old}, list)
+}
+
 func TestGetByID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -220,7 +309,7 @@ func TestAddPost(t *testing.T) {
 	//Add text post
 	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
 	var responsePost *Post
-	responsePost, err := testRepo.Add(user, category, title, typePost, text, link)
+	responsePost, err := testRepo.Add(user, category, title, typePost, text, link, Flags{}, nil)
 	assert.Equal(t, expectPosts.Author, responsePost.Author)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
@@ -229,59 +318,82 @@ func TestAddPost(t *testing.T) {
 	text = ""
 	link = "www.google.com"
 	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
-	responsePost, err = testRepo.Add(user, category, title, typePost, text, link)
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{}, nil)
 	assert.Equal(t, expectPosts.Author, responsePost.Author)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
+	//Link post keeps the domain
+	link = "https://www.Example.com:443/news"
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{}, nil)
+	assert.Equal(t, "example.com", responsePost.Domain)
+	assert.Equal(t, "example.com/news", responsePost.NormalizedLink)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//Post is marked on creation
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{Flair: "Live", NSFW: true, Spoiler: true}, nil)
+	assert.Equal(t, "Live", responsePost.Flair)
+	assert.True(t, responsePost.NSFW)
+	assert.True(t, responsePost.Spoiler)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//The event of the new post is inserted with it
+	staged := StagedEvent{ID: "5ebaf9ee3c04c17c56f51250", Type: "post.created", Data: `{}`}
+	mockDB.EXPECT().Insert(gomock.Any()).DoAndReturn(func(docs ...interface{}) error {
+		inserted := *docs[0].(**Post)
+		assert.Equal(t, []StagedEvent{staged}, inserted.Outbox)
+		return nil
+	})
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{}, func(post *Post) ([]StagedEvent, error) {
+		assert.Equal(t, title, post.Title)
+		return []StagedEvent{staged}, nil
+	})
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//No post without its event
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{}, func(post *Post) ([]StagedEvent, error) {
+		return nil, fmt.Errorf("Bad event")
+	})
+	assert.Empty(t, responsePost)
+	assert.EqualError(t, err, "Bad event")
+
 	//BD error
 	mockDB.EXPECT().Insert(gomock.Any()).Return(fmt.Errorf("Internal error"))
-	responsePost, err = testRepo.Add(user, category, title, typePost, text, link)
+	responsePost, err = testRepo.Add(user, category, title, typePost, text, link, Flags{}, nil)
 
 	assert.Empty(t, responsePost)
 	assert.EqualError(t, err, "Internal error")
 }
 
-func TestUpdatePostByNewComment(t *testing.T) {
+func TestAddImagePost(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockDB := NewMockPostRepositoryDBInterface(ctrl)
-	mockDBFind := NewMockFindInterface(ctrl)
 	testRepo := NewRepo(mockDB)
 
-	expectPosts := testPost3
-	commentID := bson.NewObjectId()
-
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
-	var responsePost *Post
-
-	responsePost, err := testRepo.AddComment(expectPosts.ID, commentID)
-	assert.Equal(t, expectPosts.Author, responsePost.Author)
+	image := &Image{
+		URL:       "/media/5ebaf9ee3c04c17c56f51244.png",
+		Thumbnail: "/media/5ebaf9ee3c04c17c56f51244_thumb.png",
+		Width:     640,
+		Height:    480,
+	}
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	responsePost, err := testRepo.AddImage(testPost3.Author, testPost3.Category, testPost3.Title, image, Flags{}, nil)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "image", responsePost.Type)
+	assert.Equal(t, image, responsePost.Image)
+	assert.Equal(t, []Vote{{UserID: testPost3.Author.ID, Rating: 1}}, responsePost.Votes)
 
-	//Err get by id
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
-
-	responsePostErr, err := testRepo.AddComment(expectPosts.ID, commentID)
-	assert.Empty(t, responsePostErr)
-	assert.EqualError(t, err, "Error getting post from BD: Internal error")
-
-	//Err AddComment
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(fmt.Errorf("Internal server error"))
-
-	responsePostErr2, err := testRepo.AddComment(expectPosts.ID, commentID)
-	assert.Empty(t, responsePostErr2)
-	assert.EqualError(t, err, "Error update BD: Internal server error")
+	//BD error
+	mockDB.EXPECT().Insert(gomock.Any()).Return(fmt.Errorf("Internal error"))
+	responsePost, err = testRepo.AddImage(testPost3.Author, testPost3.Category, testPost3.Title, image, Flags{}, nil)
+	assert.Empty(t, responsePost)
+	assert.EqualError(t, err, "Internal error")
 }
 
-func TestUpdatePostByUpViews(t *testing.T) {
+func TestUpdatePostByNewComment(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -290,31 +402,50 @@ func TestUpdatePostByUpViews(t *testing.T) {
 	testRepo := NewRepo(mockDB)
 
 	expectPosts := testPost3
+	commentID := bson.NewObjectId()
 
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
 
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, bson.M{"$push": bson.M{"comments": commentID}}).Return(nil)
+	var responsePost *Post
+
+	responsePost, err := testRepo.AddComment(expectPosts.ID, commentID, nil)
+	assert.Equal(t, expectPosts.Author, responsePost.Author)
+	assert.Contains(t, responsePost.CommentsID, commentID)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
-	err := testRepo.UpViews(expectPosts.ID)
+	//The event of the comment is pushed in the same update
+	staged := []StagedEvent{{ID: "5ebaf9ee3c04c17c56f51250", Type: "comment.added", Data: `{}`}}
+	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
+	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, bson.M{"$push": bson.M{
+		"comments": commentID,
+		"outbox":   bson.M{"$each": staged},
+	}}).Return(nil)
+
+	_, err = testRepo.AddComment(expectPosts.ID, commentID, func(post *Post) ([]StagedEvent, error) {
+		return staged, nil
+	})
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
 	//Err get by id
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
 
-	err = testRepo.UpViews(expectPosts.ID)
+	responsePostErr, err := testRepo.AddComment(expectPosts.ID, commentID, nil)
+	assert.Empty(t, responsePostErr)
 	assert.EqualError(t, err, "Error getting post from BD: Internal error")
 
 	//Err AddComment
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
 
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(fmt.Errorf("Internal server error"))
+	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, bson.M{"$push": bson.M{"comments": commentID}}).Return(fmt.Errorf("Internal server error"))
 
-	err = testRepo.UpViews(expectPosts.ID)
+	responsePostErr2, err := testRepo.AddComment(expectPosts.ID, commentID, nil)
+	assert.Empty(t, responsePostErr2)
 	assert.EqualError(t, err, "Error update BD: Internal server error")
-
 }
 
 func TestUpdatePostByDeleteComment(t *testing.T) {
@@ -331,10 +462,10 @@ func TestUpdatePostByDeleteComment(t *testing.T) {
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
 
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, bson.M{"$pull": bson.M{"comments": commentID}}).Return(nil)
 	var responsePost *Post
 
-	responsePost, err := testRepo.DeleteComment(expectPosts.ID, commentID)
+	responsePost, err := testRepo.DeleteComment(expectPosts.ID, commentID, nil)
 	assert.Equal(t, expectPosts.Author, responsePost.Author)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
@@ -342,7 +473,7 @@ func TestUpdatePostByDeleteComment(t *testing.T) {
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
 
-	responsePostErr, err := testRepo.DeleteComment(expectPosts.ID, commentID)
+	responsePostErr, err := testRepo.DeleteComment(expectPosts.ID, commentID, nil)
 	assert.Empty(t, responsePostErr)
 	assert.EqualError(t, err, "Error getting post from BD: Internal error")
 
@@ -350,13 +481,42 @@ func TestUpdatePostByDeleteComment(t *testing.T) {
 	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
 
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(fmt.Errorf("Internal server error"))
+	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, bson.M{"$pull": bson.M{"comments": commentID}}).Return(fmt.Errorf("Internal server error"))
 
-	responsePostErr2, err := testRepo.DeleteComment(expectPosts.ID, commentID)
+	responsePostErr2, err := testRepo.DeleteComment(expectPosts.ID, commentID, nil)
 	assert.Empty(t, responsePostErr2)
 	assert.EqualError(t, err, "Error update BD: Internal server error")
 }
 
+// votedPost is a post with the votes of the users, 1 or -1, by user id.
+func votedPost(votes ...Vote) *Post {
+	post := &Post{
+		ID:       testPost1.ID,
+		Author:   testPost1.Author,
+		Category: "music",
+		Votes:    votes,
+	}
+	nUpVotes := 0
+	for _, vote := range votes {
+		post.Score += vote.Rating
+		if vote.Rating == 1 {
+			nUpVotes++
+		}
+	}
+	post.UpvotePercentage = nUpVotes * 100 / len(votes)
+	return post
+}
+
+// votesUpdate is the update of the votes of the post read with read.
+func votesUpdate(read, written *Post) (bson.M, bson.M) {
+	return bson.M{"_id": read.ID, "votes": read.Votes},
+		bson.M{"$set": bson.M{
+			"votes":            written.Votes,
+			"score":            written.Score,
+			"upvotePercentage": written.UpvotePercentage,
+		}}
+}
+
 func TestUpdatePostByUpvote(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -365,53 +525,99 @@ func TestUpdatePostByUpvote(t *testing.T) {
 	mockDBFind := NewMockFindInterface(ctrl)
 	testRepo := NewRepo(mockDB)
 
-	expectPosts := testPost1
+	postID := testPost1.ID
 	user := &user.User{
 		ID:       8,
 		Username: "igor",
 	}
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}), votedPost(Vote{1, 1}, Vote{8, 1}))).Return(nil)
 
-	responsePost, err := testRepo.Upvote(user, expectPosts.ID)
+	responsePost, err := testRepo.Upvote(user, postID, nil)
 
-	assert.Equal(t, expectPosts.Category, responsePost.Category)
+	assert.Equal(t, 2, responsePost.Score)
+	assert.Equal(t, 100, responsePost.UpvotePercentage)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
-	//Add downwote post
-	expectPosts.Votes[0].Rating = -1
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
+	//Upvote of a downvote
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}, Vote{8, -1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}, Vote{8, -1}), votedPost(Vote{1, 1}, Vote{8, 1}))).Return(nil)
+
+	responsePost, err = testRepo.Upvote(user, postID, nil)
+
+	assert.Equal(t, 2, responsePost.Score)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	//Another vote came in between, the votes are read again
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}), votedPost(Vote{1, 1}, Vote{8, 1}))).Return(mgo.ErrNotFound)
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}, Vote{9, -1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}, Vote{9, -1}), votedPost(Vote{1, 1}, Vote{9, -1}, Vote{8, 1}))).Return(nil)
 
-	responsePost, err = testRepo.Upvote(expectPosts.Author, expectPosts.ID)
+	responsePost, err = testRepo.Upvote(user, postID, nil)
 
-	assert.Equal(t, expectPosts.Category, responsePost.Category)
+	assert.Equal(t, 1, responsePost.Score)
+	assert.Equal(t, 66, responsePost.UpvotePercentage)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
+	//Votes keep changing
+	for try := 0; try < maxVoteTries; try++ {
+		mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+		mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+		mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mgo.ErrNotFound)
+	}
+
+	responsePostErr, err := testRepo.Upvote(user, postID, nil)
+	assert.Empty(t, responsePostErr)
+	assert.Equal(t, ErrVoteConflict, err)
+
 	//Err get by id
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
 
-	responsePostErr, err := testRepo.Upvote(expectPosts.Author, expectPosts.ID)
+	responsePostErr, err = testRepo.Upvote(user, postID, nil)
 	assert.Empty(t, responsePostErr)
 	assert.EqualError(t, err, "DB err: Internal error")
 
-	//Err AddComment
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(fmt.Errorf("Internal server error"))
+	//Err update
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Internal server error"))
 
-	responsePostErr2, err := testRepo.Upvote(expectPosts.Author, expectPosts.ID)
+	responsePostErr2, err := testRepo.Upvote(user, postID, nil)
 
 	assert.Empty(t, responsePostErr2)
 	assert.EqualError(t, err, "Error update BD: Internal server error")
 }
 
+func TestVoteStagesEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	voter := &user.User{ID: 8, Username: "igor"}
+	staged := []StagedEvent{{ID: "5ebaf9ee3c04c17c56f51250", Type: "vote.cast", Data: `{"score":2}`}}
+	mockDB.EXPECT().Find(bson.M{"_id": testPost1.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	// the vote and its event are one write, the event is pushed
+	selector, update := votesUpdate(votedPost(Vote{1, 1}), votedPost(Vote{1, 1}, Vote{8, 1}))
+	update["$push"] = bson.M{"outbox": bson.M{"$each": staged}}
+	mockDB.EXPECT().Update(selector, update).Return(nil)
+
+	_, err := testRepo.Upvote(voter, testPost1.ID, func(voted *Post) ([]StagedEvent, error) {
+		assert.Equal(t, 2, voted.Score)
+		return staged, nil
+	})
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+}
+
 func TestUpdatePostByDownvote(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -420,48 +626,45 @@ func TestUpdatePostByDownvote(t *testing.T) {
 	mockDBFind := NewMockFindInterface(ctrl)
 	testRepo := NewRepo(mockDB)
 
-	expectPosts := testPost1
+	postID := testPost1.ID
 	user := &user.User{
 		ID:       9,
 		Username: "lera",
 	}
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}), votedPost(Vote{1, 1}, Vote{9, -1}))).Return(nil)
 
-	responsePost, err := testRepo.Downvote(user, expectPosts.ID)
+	responsePost, err := testRepo.Downvote(user, postID, nil)
 
-	assert.Equal(t, expectPosts.Category, responsePost.Category)
+	assert.Equal(t, 0, responsePost.Score)
+	assert.Equal(t, 50, responsePost.UpvotePercentage)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
-	//Add downwote post
-	expectPosts.Votes[0].Rating = 1
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(nil)
+	//Downvote of an upvote
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}, Vote{9, 1}))
+	mockDB.EXPECT().Update(votesUpdate(votedPost(Vote{1, 1}, Vote{9, 1}), votedPost(Vote{1, 1}, Vote{9, -1}))).Return(nil)
 
-	responsePost, err = testRepo.Downvote(expectPosts.Author, expectPosts.ID)
+	responsePost, err = testRepo.Downvote(user, postID, nil)
 
-	assert.Equal(t, expectPosts.Category, responsePost.Category)
+	assert.Equal(t, 0, responsePost.Score)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
 	//Err get by id
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
 	mockDBFind.EXPECT().One(gomock.Any()).Return(fmt.Errorf("Internal error"))
 
-	responsePostErr, err := testRepo.Downvote(expectPosts.Author, expectPosts.ID)
+	responsePostErr, err := testRepo.Downvote(user, postID, nil)
 	assert.Empty(t, responsePostErr)
 	assert.EqualError(t, err, "DB err: Internal error")
 
-	//Err AddComment
-	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
-	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, expectPosts)
-
-	mockDB.EXPECT().Update(bson.M{"_id": expectPosts.ID}, expectPosts).Return(fmt.Errorf("Internal server error"))
+	//Err update
+	mockDB.EXPECT().Find(bson.M{"_id": postID}).Return(mockDBFind)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, votedPost(Vote{1, 1}))
+	mockDB.EXPECT().Update(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Internal server error"))
 
-	responsePostErr2, err := testRepo.Downvote(expectPosts.Author, expectPosts.ID)
+	responsePostErr2, err := testRepo.Downvote(user, postID, nil)
 
 	assert.Empty(t, responsePostErr2)
 	assert.EqualError(t, err, "Error update BD: Internal server error")
@@ -476,23 +679,431 @@ func TestDeletePost(t *testing.T) {
 
 	expectPosts := testPost1
 
+	orphanSelector := bson.M{"crosspost.id": expectPosts.ID}
+	orphanUpdate := bson.M{"$set": bson.M{"crosspost.deleted": true},
+		"$unset": bson.M{"crosspost.title": "", "crosspost.author": ""}}
+
 	mockDB.EXPECT().Remove(bson.M{"_id": expectPosts.ID}).Return(nil)
+	mockDB.EXPECT().UpdateAll(orphanSelector, orphanUpdate).Return(&mgo.ChangeInfo{Updated: 2}, nil)
 
-	isDelete, err := testRepo.Delete(expectPosts.ID)
+	isDelete, err := testRepo.Delete(expectPosts.ID, nil)
+	assert.True(t, isDelete)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//Err marking crossposts doesn't undo the delete
+	mockDB.EXPECT().Remove(bson.M{"_id": expectPosts.ID}).Return(nil)
+	mockDB.EXPECT().UpdateAll(orphanSelector, orphanUpdate).Return(nil, fmt.Errorf("Internal error"))
+
+	isDelete, err = testRepo.Delete(expectPosts.ID, nil)
 	assert.True(t, isDelete)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
 	//Err not found
 	mockDB.EXPECT().Remove(bson.M{"_id": expectPosts.ID}).Return(mgo.ErrNotFound)
 
-	isDelete, err = testRepo.Delete(expectPosts.ID)
+	isDelete, err = testRepo.Delete(expectPosts.ID, nil)
 	assert.False(t, isDelete)
 	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
 
 	//Err  BD error
 	mockDB.EXPECT().Remove(bson.M{"_id": expectPosts.ID}).Return(fmt.Errorf("Internal error"))
 
-	isDelete, err = testRepo.Delete(expectPosts.ID)
+	isDelete, err = testRepo.Delete(expectPosts.ID, nil)
 	assert.False(t, isDelete)
 	assert.EqualError(t, err, "Internal error")
+
+	//Staged events are collected before the post goes
+	mockDBFind := NewMockFindInterface(ctrl)
+	unstaged := bson.M{"_id": expectPosts.ID, "outbox.id": bson.M{"$exists": false}}
+	collected := 0
+	collect := func() error {
+		collected++
+		return nil
+	}
+	gomock.InOrder(
+		mockDB.EXPECT().Remove(unstaged).Return(mgo.ErrNotFound),
+		mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind),
+		mockDBFind.EXPECT().Count().Return(1, nil),
+		mockDB.EXPECT().Remove(unstaged).Return(nil),
+		mockDB.EXPECT().UpdateAll(orphanSelector, orphanUpdate).Return(&mgo.ChangeInfo{}, nil),
+	)
+
+	isDelete, err = testRepo.Delete(expectPosts.ID, collect)
+	assert.True(t, isDelete)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, 1, collected)
+
+	//Post is gone
+	mockDB.EXPECT().Remove(unstaged).Return(mgo.ErrNotFound)
+	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().Count().Return(0, nil)
+
+	isDelete, err = testRepo.Delete(expectPosts.ID, collect)
+	assert.False(t, isDelete)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, 1, collected)
+
+	//Err collecting keeps the post
+	mockDB.EXPECT().Remove(unstaged).Return(mgo.ErrNotFound)
+	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind)
+	mockDBFind.EXPECT().Count().Return(1, nil)
+
+	isDelete, err = testRepo.Delete(expectPosts.ID, func() error { return fmt.Errorf("outbox is down") })
+	assert.False(t, isDelete)
+	assert.EqualError(t, err, "outbox is down")
+
+	//Events keep coming
+	collected = 0
+	mockDB.EXPECT().Remove(unstaged).Return(mgo.ErrNotFound).Times(maxDeleteTries)
+	mockDB.EXPECT().Find(bson.M{"_id": expectPosts.ID}).Return(mockDBFind).Times(maxDeleteTries)
+	mockDBFind.EXPECT().Count().Return(1, nil).Times(maxDeleteTries)
+
+	isDelete, err = testRepo.Delete(expectPosts.ID, collect)
+	assert.False(t, isDelete)
+	assert.Equal(t, ErrDeleteConflict, err)
+	assert.Equal(t, maxDeleteTries-1, collected)
+}
+
+func TestCrosspost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+	sharer := &user.User{
+		ID:       8,
+		Username: "igor",
+	}
+
+	//Crosspost keeps own votes and comments
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	crosspost, err := testRepo.Crosspost(sharer, testPost1, "funny", "", Flags{}, nil)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "crosspost", crosspost.Type)
+	assert.Equal(t, "funny", crosspost.Category)
+	assert.Equal(t, "Lorem", crosspost.Title)
+	assert.Equal(t, []bson.ObjectId{}, crosspost.CommentsID)
+	assert.Equal(t, []Vote{{UserID: 8, Rating: 1}}, crosspost.Votes)
+	assert.Equal(t, &Crosspost{
+		PostID:   testPost1.ID,
+		Title:    "Lorem",
+		Author:   testPost1.Author,
+		Category: "music",
+	}, crosspost.Crosspost)
+
+	//Crosspost of a crosspost points to the original
+	mockDB.EXPECT().Insert(gomock.Any()).Return(nil)
+	second, err := testRepo.Crosspost(testPost1.Author, crosspost, "news", "Look at this", Flags{Flair: "Video", NSFW: true, Spoiler: true}, nil)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, "Look at this", second.Title)
+	assert.Equal(t, "Video", second.Flair)
+	assert.True(t, second.NSFW)
+	assert.True(t, second.Spoiler)
+	assert.Equal(t, testPost1.ID, second.Crosspost.PostID)
+	assert.Equal(t, "music", second.Crosspost.Category)
+
+	//Original is gone
+	_, err = testRepo.Crosspost(sharer, &Post{Crosspost: &Crosspost{PostID: testPost1.ID, Deleted: true}}, "news", "", Flags{}, nil)
+	assert.Equal(t, ErrOriginalDeleted, err)
+
+	//BD error
+	mockDB.EXPECT().Insert(gomock.Any()).Return(fmt.Errorf("Internal error"))
+	_, err = testRepo.Crosspost(sharer, testPost1, "funny", "", Flags{}, nil)
+	assert.EqualError(t, err, "Internal error")
+}
+
+func TestSetPreview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	preview := &LinkPreview{Title: "Lorem"}
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"preview": preview}}).Return(nil)
+
+	err := testRepo.SetPreview(testPost1.ID, preview)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"preview": preview}}).Return(fmt.Errorf("Internal error"))
+
+	err = testRepo.SetPreview(testPost1.ID, preview)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestAddViews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$inc": bson.M{"views": 3}}).Return(nil)
+	views := map[bson.ObjectId]int{testPost1.ID: 3}
+	err := testRepo.AddViews(views)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Empty(t, views)
+
+	//Deleted post
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$inc": bson.M{"views": 1}}).Return(mgo.ErrNotFound)
+	views = map[bson.ObjectId]int{testPost1.ID: 1}
+	err = testRepo.AddViews(views)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Empty(t, views)
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$inc": bson.M{"views": 2}}).Return(fmt.Errorf("Internal error"))
+	views = map[bson.ObjectId]int{testPost1.ID: 2}
+	err = testRepo.AddViews(views)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+	assert.Equal(t, map[bson.ObjectId]int{testPost1.ID: 2}, views)
+}
+
+func TestSetFlags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID},
+		bson.M{"$set": bson.M{"flair": "discussion", "nsfw": true, "spoiler": false}}).Return(nil)
+	err := testRepo.SetFlags(testPost1.ID, "discussion", true, false)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID},
+		bson.M{"$set": bson.M{"flair": "", "nsfw": false, "spoiler": true}}).Return(fmt.Errorf("Internal error"))
+	err = testRepo.SetFlags(testPost1.ID, "", false, true)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestStickyFirst(t *testing.T) {
+	list := []*Post{{Title: "a"}, {Title: "b", Sticky: true}, {Title: "c"}, {Title: "d", Sticky: true}}
+	StickyFirst(list)
+	titles := []string{}
+	for _, post := range list {
+		titles = append(titles, post.Title)
+	}
+	assert.Equal(t, []string{"b", "d", "a", "c"}, titles)
+}
+
+func TestSetSticky(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	post := &Post{ID: testPost1.ID, Category: "music"}
+	pin := func(slot string) bson.M {
+		return bson.M{"$set": bson.M{"sticky": true, "stickySlot": slot}}
+	}
+	dup := &mgo.LastError{Code: 11000, Err: "E11000 duplicate key error"}
+
+	//The first slot is taken, the second is free
+	gomock.InOrder(
+		mockDB.EXPECT().Update(bson.M{"_id": post.ID}, pin("music:1")).Return(dup),
+		mockDB.EXPECT().Update(bson.M{"_id": post.ID}, pin("music:2")).Return(nil),
+	)
+
+	err := testRepo.SetSticky(post, true)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.True(t, post.Sticky)
+	assert.Equal(t, "music:2", post.StickySlot)
+
+	//Already sticky
+	err = testRepo.SetSticky(post, true)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//Unsticky frees the slot
+	mockDB.EXPECT().Update(
+		bson.M{"_id": post.ID},
+		bson.M{"$set": bson.M{"sticky": false}, "$unset": bson.M{"stickySlot": ""}},
+	).Return(nil)
+
+	err = testRepo.SetSticky(post, false)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.False(t, post.Sticky)
+	assert.Empty(t, post.StickySlot)
+
+	//Too many, every slot is taken
+	gomock.InOrder(
+		mockDB.EXPECT().Update(bson.M{"_id": post.ID}, pin("music:1")).Return(dup),
+		mockDB.EXPECT().Update(bson.M{"_id": post.ID}, pin("music:2")).Return(dup),
+	)
+
+	err = testRepo.SetSticky(post, true)
+	assert.Equal(t, ErrTooManySticky, err)
+	assert.False(t, post.Sticky)
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": post.ID}, pin("music:1")).Return(fmt.Errorf("Internal error"))
+
+	err = testRepo.SetSticky(post, true)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().EnsureIndex(mgo.Index{Key: []string{"stickySlot"}, Unique: true, Sparse: true}).Return(nil)
+	assert.Nil(t, testRepo.EnsureIndexes())
+}
+
+func TestSetLocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"locked": true}}).Return(nil)
+
+	err := testRepo.SetLocked(testPost1.ID, true)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"locked": false}}).Return(fmt.Errorf("Internal error"))
+
+	err = testRepo.SetLocked(testPost1.ID, false)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestSetPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	post := &Post{ID: testPost1.ID}
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"pending": true}}).Return(nil)
+
+	err := testRepo.SetPending(post, true, nil)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.True(t, post.Pending)
+
+	//The approval pushes the event of the post in the same update
+	staged := []StagedEvent{{ID: "5ebaf9ee3c04c17c56f51250", Type: "post.created", Data: `{}`}}
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{
+		"$set":  bson.M{"pending": false},
+		"$push": bson.M{"outbox": bson.M{"$each": staged}},
+	}).Return(nil)
+
+	err = testRepo.SetPending(post, false, func(post *Post) ([]StagedEvent, error) {
+		assert.False(t, post.Pending)
+		return staged, nil
+	})
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+
+	//BD error
+	mockDB.EXPECT().Update(bson.M{"_id": testPost1.ID}, bson.M{"$set": bson.M{"pending": false}}).Return(fmt.Errorf("Internal error"))
+
+	err = testRepo.SetPending(post, false, nil)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestKarma(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	mockDB.EXPECT().Find(bson.M{"author.id": int64(1)}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).SetArg(0, []*Post{{Score: 5}, {Score: -2}})
+
+	karma, err := testRepo.Karma(1)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, 3, karma)
+
+	//BD error
+	mockDB.EXPECT().Find(bson.M{"author.id": int64(1)}).Return(mockDBFind)
+	mockDBFind.EXPECT().All(gomock.Any()).Return(fmt.Errorf("Internal error"))
+
+	_, err = testRepo.Karma(1)
+	assert.EqualError(t, err, "DB err: Internal error")
+}
+
+func TestCountByAuthorSince(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	since := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	selector := bson.M{
+		"author.id": int64(1),
+		"_id":       bson.M{"$gte": bson.NewObjectIdWithTime(since)},
+	}
+
+	mockDB.EXPECT().Find(selector).Return(mockDBFind)
+	mockDBFind.EXPECT().Count().Return(4, nil)
+
+	count, err := testRepo.CountByAuthorSince(1, since)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, 4, count)
+
+	//BD error
+	mockDB.EXPECT().Find(selector).Return(mockDBFind)
+	mockDBFind.EXPECT().Count().Return(0, fmt.Errorf("Internal error"))
+
+	_, err = testRepo.CountByAuthorSince(1, since)
+	assert.EqualError(t, err, "DB err: Internal error")
+}
+
+func TestArchiveOlderThan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	cutoff := time.Date(2020, 5, 12, 22, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	selector := bson.M{
+		"created":  bson.M{"$lt": "2020-05-12T22:00:00+03:00"},
+		"archived": bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{"archived": true}}
+
+	mockDB.EXPECT().UpdateAll(selector, update).Return(&mgo.ChangeInfo{Updated: 3}, nil)
+
+	archived, err := testRepo.ArchiveOlderThan(cutoff)
+	assert.Empty(t, err, fmt.Sprintf("Unexpected error: %v", err))
+	assert.Equal(t, 3, archived)
+
+	//BD error
+	mockDB.EXPECT().UpdateAll(selector, update).Return(nil, fmt.Errorf("Internal error"))
+
+	_, err = testRepo.ArchiveOlderThan(cutoff)
+	assert.EqualError(t, err, "Error update BD: Internal error")
+}
+
+func TestVoteArchived(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := NewMockPostRepositoryDBInterface(ctrl)
+	mockDBFind := NewMockFindInterface(ctrl)
+	testRepo := NewRepo(mockDB)
+
+	archived := &Post{ID: testPost1.ID, Archived: true, Poll: &Poll{Options: []string{"a", "b"}}}
+	mockDB.EXPECT().Find(bson.M{"_id": archived.ID}).Return(mockDBFind).Times(3)
+	mockDBFind.EXPECT().One(gomock.Any()).SetArg(0, archived).Times(3)
+
+	_, err := testRepo.Upvote(testPost1.Author, archived.ID, nil)
+	assert.Equal(t, ErrArchived, err)
+	_, err = testRepo.Downvote(testPost1.Author, archived.ID, nil)
+	assert.Equal(t, ErrArchived, err)
+	_, err = testRepo.VotePoll(testPost1.Author, archived.ID, 0)
+	assert.Equal(t, ErrArchived, err)
 }
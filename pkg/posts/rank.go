@@ -0,0 +1,41 @@
+package posts
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// redditEpoch is the reference point of the "hot" formula, a post gets the
+// same boost from one order of magnitude of score as from 12.5 hours of age.
+const redditEpoch = 1134028003
+
+func Hot(post *Post) float64 {
+	order := math.Log10(math.Max(math.Abs(float64(post.Score)), 1))
+	sign := 0.0
+	if post.Score > 0 {
+		sign = 1
+	} else if post.Score < 0 {
+		sign = -1
+	}
+	created, err := time.Parse(time.RFC3339, post.Created)
+	if err != nil {
+		return sign * order
+	}
+	seconds := float64(created.Unix() - redditEpoch)
+	return sign*order + seconds/45000
+}
+
+// SortByHot orders posts from the hottest to the coldest one.
+func SortByHot(posts []*Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return Hot(posts[i]) > Hot(posts[j])
+	})
+}
+
+// StickyFirst moves the sticky posts to the top keeping the order otherwise.
+func StickyFirst(posts []*Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Sticky && !posts[j].Sticky
+	})
+}
@@ -0,0 +1,121 @@
+package posts
+
+import (
+	"log"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SavedItem is a post or a comment bookmarked by the user. PostID is the
+// post the comment belongs to, for saved posts it equals ItemID.
+type SavedItem struct {
+	UserID int64         `json:"user,string" bson:"user"`
+	Kind   string        `json:"type" bson:"kind"`
+	ItemID bson.ObjectId `json:"id" bson:"item"`
+	PostID bson.ObjectId `json:"post" bson:"post"`
+	Saved  string        `json:"saved" bson:"saved"`
+}
+
+type SavedRepo struct {
+	DB *mgo.Collection
+}
+
+func NewSavedRepo(collection *mgo.Collection) *SavedRepo {
+	return &SavedRepo{DB: collection}
+}
+
+func (repo *SavedRepo) EnsureIndexes() error {
+	err := repo.DB.EnsureIndex(mgo.Index{
+		Key:    []string{"user", "kind", "item"},
+		Unique: true,
+	})
+	if err != nil {
+		return err
+	}
+	err = repo.DB.EnsureIndex(mgo.Index{Key: []string{"user", "-saved"}})
+	if err != nil {
+		return err
+	}
+	return repo.DB.EnsureIndexKey("post")
+}
+
+// Save bookmarks the item, saving it again keeps the original date.
+func (repo *SavedRepo) Save(userID int64, kind string, itemID, postID bson.ObjectId) error {
+	_, err := repo.DB.Upsert(
+		bson.M{"user": userID, "kind": kind, "item": itemID},
+		bson.M{"$setOnInsert": &SavedItem{
+			UserID: userID,
+			Kind:   kind,
+			ItemID: itemID,
+			PostID: postID,
+			Saved:  time.Now().Format(time.RFC3339),
+		}},
+	)
+	if err != nil {
+		log.Printf("Upsert error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SavedRepo) Unsave(userID int64, kind string, itemID bson.ObjectId) (bool, error) {
+	err := repo.DB.Remove(bson.M{"user": userID, "kind": kind, "item": itemID})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetByUser returns a page of the items saved by the user, newest first, and
+// the total number of them. Empty kind means both posts and comments.
+func (repo *SavedRepo) GetByUser(userID int64, kind string, skip, limit int) ([]*SavedItem, int, error) {
+	query := bson.M{"user": userID}
+	if kind != "" {
+		query["kind"] = kind
+	}
+	total, err := repo.DB.Find(query).Count()
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, 0, err
+	}
+	items := []*SavedItem{}
+	err = repo.DB.Find(query).Sort("-saved", "-_id").Skip(skip).Limit(limit).All(&items)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// SavedPosts tells which of the posts are saved by the user.
+func (repo *SavedRepo) SavedPosts(userID int64, postIDs []bson.ObjectId) (map[bson.ObjectId]bool, error) {
+	items := []*SavedItem{}
+	err := repo.DB.Find(bson.M{
+		"user": userID,
+		"kind": ItemPost,
+		"item": bson.M{"$in": postIDs},
+	}).All(&items)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	saved := make(map[bson.ObjectId]bool, len(items))
+	for _, item := range items {
+		saved[item.ItemID] = true
+	}
+	return saved, nil
+}
+
+// RemoveItem forgets a deleted post or comment for every user. Removing a
+// post also removes the saved comments of it.
+func (repo *SavedRepo) RemoveItem(itemID bson.ObjectId) error {
+	_, err := repo.DB.RemoveAll(bson.M{"$or": []bson.M{
+		{"item": itemID},
+		{"post": itemID},
+	}})
+	return err
+}
@@ -0,0 +1,173 @@
+package posts
+
+import (
+	"fmt"
+	"net/url"
+	"reddit/pkg/category"
+	"reddit/pkg/validation"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxTitleLength   = 300
+	MaxTextLength    = 40000
+	MaxLinkLength    = 2048
+	MaxCommentLength = 10000
+	MaxImageSize     = 10 << 20
+
+	MaxFlairs           = 20
+	MaxFlairLength      = 32
+	MinPollOptions      = 2
+	MaxPollOptions      = 10
+	MaxPollOptionLength = 100
+)
+
+// PostTypes are the post types which can be created with a plain JSON request.
+var PostTypes = []string{"text", "link", "poll"}
+
+func validType(typePost string) bool {
+	for _, allowed := range PostTypes {
+		if typePost == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidURL reports whether link is an absolute http(s) URL with a host.
+func ValidURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := u.Hostname()
+	return host != "" && !strings.ContainsAny(host, " _")
+}
+
+func validateTitle(errs *validation.Errors, title string) {
+	errs.Required("title", title, MaxTitleLength)
+}
+
+func validateCategory(errs *validation.Errors, categoryName string) {
+	if !category.ValidName(categoryName) {
+		errs.Add("category", categoryName, category.NameHint)
+	}
+}
+
+func ValidatePost(categoryName, title, typePost, text, link string) validation.Errors {
+	errs := validation.Errors{}
+	validateCategory(&errs, categoryName)
+	validateTitle(&errs, title)
+	if !validType(typePost) {
+		errs.Add("type", typePost, "must be one of: "+strings.Join(PostTypes, ", "))
+		return errs
+	}
+	if typePost == "link" {
+		if link == "" {
+			errs.Add("url", link, "is required")
+		} else if utf8.RuneCountInString(link) > MaxLinkLength {
+			errs.Add("url", "", fmt.Sprintf("must be at most %d characters", MaxLinkLength))
+		} else if !ValidURL(link) {
+			errs.Add("url", link, "must be a valid http or https URL")
+		}
+	} else {
+		errs.MaxLength("text", text, MaxTextLength)
+	}
+	return errs
+}
+
+// ValidatePoll checks options and closing time of a new poll, now is
+// passed in to compare the closing time with.
+func ValidatePoll(options []string, closes string, now time.Time) validation.Errors {
+	errs := validation.Errors{}
+	if len(options) < MinPollOptions || len(options) > MaxPollOptions {
+		errs.Add("options", "", fmt.Sprintf("must have %d-%d options", MinPollOptions, MaxPollOptions))
+	}
+	seen := make(map[string]bool)
+	for i, option := range options {
+		param := fmt.Sprintf("options[%d]", i)
+		trimmed := strings.TrimSpace(option)
+		switch {
+		case trimmed == "":
+			errs.Add(param, option, "is required")
+		case utf8.RuneCountInString(option) > MaxPollOptionLength:
+			errs.Add(param, "", fmt.Sprintf("must be at most %d characters", MaxPollOptionLength))
+		case seen[trimmed]:
+			errs.Add(param, option, "duplicates another option")
+		}
+		seen[trimmed] = true
+	}
+	errs.Future("closes", closes, now)
+	return errs
+}
+
+// ValidateCrosspost checks sharing original into categoryName, an empty
+// title means the title of the original.
+func ValidateCrosspost(original *Post, categoryName, title string) validation.Errors {
+	errs := validation.Errors{}
+	validateCategory(&errs, categoryName)
+	originalCategory := original.Category
+	if original.Crosspost != nil {
+		originalCategory = original.Crosspost.Category
+	}
+	if categoryName == originalCategory || categoryName == original.Category {
+		errs.Add("category", categoryName, "must differ from the category of the original")
+	}
+	if title != "" {
+		validateTitle(&errs, title)
+	}
+	return errs
+}
+
+var colorRegexp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ValidateFlairs checks the flairs a moderator defines for a category.
+func ValidateFlairs(flairs []category.Flair) validation.Errors {
+	errs := validation.Errors{}
+	if len(flairs) > MaxFlairs {
+		errs.Add("flairs", "", fmt.Sprintf("must have at most %d flairs", MaxFlairs))
+	}
+	seen := make(map[string]bool)
+	for i, flair := range flairs {
+		param := fmt.Sprintf("flairs[%d]", i)
+		switch {
+		case strings.TrimSpace(flair.Name) == "":
+			errs.Add(param+".name", flair.Name, "is required")
+		case utf8.RuneCountInString(flair.Name) > MaxFlairLength:
+			errs.Add(param+".name", "", fmt.Sprintf("must be at most %d characters", MaxFlairLength))
+		case seen[flair.Name]:
+			errs.Add(param+".name", flair.Name, "duplicates another flair")
+		}
+		seen[flair.Name] = true
+		if flair.Color != "" && !colorRegexp.MatchString(flair.Color) {
+			errs.Add(param+".color", flair.Color, "must look like #a1b2c3")
+		}
+	}
+	return errs
+}
+
+// ValidateImagePost checks the form fields of an image upload, imageSize is
+// the size of the uploaded file or 0 if there is none.
+func ValidateImagePost(categoryName, title string, imageSize int64) validation.Errors {
+	errs := validation.Errors{}
+	validateCategory(&errs, categoryName)
+	validateTitle(&errs, title)
+	if imageSize <= 0 {
+		errs.Add("image", "", "is required")
+	} else if imageSize > MaxImageSize {
+		errs.Add("image", "", fmt.Sprintf("must be at most %d MB", MaxImageSize>>20))
+	}
+	return errs
+}
+
+func ValidateComment(body string) validation.Errors {
+	errs := validation.Errors{}
+	errs.Required("comment", body, MaxCommentLength)
+	return errs
+}
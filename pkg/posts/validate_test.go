@@ -0,0 +1,119 @@
+package posts
+
+import (
+	"reddit/pkg/category"
+	"reddit/pkg/validation"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePost(t *testing.T) {
+	//Valid posts
+	assert.Empty(t, ValidatePost("music", "Lorem", "text", "Something", ""))
+	assert.Empty(t, ValidatePost("music", "Lorem", "text", "", ""))
+	assert.Empty(t, ValidatePost("news", "Lorem", "link", "", "https://golang.org/doc?x=1"))
+
+	//Everything wrong at once
+	errs := ValidatePost("Bad Category", " ", "video", "", "")
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "category", Value: "Bad Category", Msg: "must be 2-21 lowercase letters, digits or underscores"},
+		{Location: "body", Param: "title", Value: " ", Msg: "is required"},
+		{Location: "body", Param: "type", Value: "video", Msg: "must be one of: text, link, poll"},
+	}, errs)
+
+	//Lengths
+	errs = ValidatePost("music", strings.Repeat("я", MaxTitleLength+1), "text", strings.Repeat("a", MaxTextLength+1), "")
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "title", Value: "", Msg: "must be at most 300 characters"},
+		{Location: "body", Param: "text", Value: "", Msg: "must be at most 40000 characters"},
+	}, errs)
+	assert.Empty(t, ValidatePost("music", strings.Repeat("я", MaxTitleLength), "text", "", ""))
+
+	//Links
+	badLinks := []string{
+		"www.google.com",
+		"ftp://golang.org/",
+		"javascript:alert(1)",
+		"http://",
+		"https://bad host.com/",
+		"http://%zz",
+	}
+	for _, link := range badLinks {
+		errs = ValidatePost("music", "Lorem", "link", "", link)
+		assert.Equal(t, validation.Errors{
+			{Location: "body", Param: "url", Value: link, Msg: "must be a valid http or https URL"},
+		}, errs, link)
+	}
+	errs = ValidatePost("music", "Lorem", "link", "", "")
+	assert.Equal(t, "is required", errs[0].Msg)
+	errs = ValidatePost("music", "Lorem", "link", "", "https://golang.org/"+strings.Repeat("a", MaxLinkLength))
+	assert.Equal(t, "must be at most 2048 characters", errs[0].Msg)
+}
+
+func TestValidatePoll(t *testing.T) {
+	now := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	assert.Empty(t, ValidatePoll([]string{"Yes", "No"}, "", now))
+	assert.Empty(t, ValidatePoll([]string{"Yes", "No"}, "2020-05-13T22:00:00+03:00", now))
+
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "options", Value: "", Msg: "must have 2-10 options"},
+		{Location: "body", Param: "options[0]", Value: " ", Msg: "is required"},
+	}, ValidatePoll([]string{" "}, "", now))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "options[1]", Value: "Yes ", Msg: "duplicates another option"},
+		{Location: "body", Param: "options[2]", Value: "", Msg: "must be at most 100 characters"},
+		{Location: "body", Param: "closes", Value: "2020-05-12T21:00:00Z", Msg: "must be in the future"},
+	}, ValidatePoll([]string{"Yes", "Yes ", strings.Repeat("a", MaxPollOptionLength+1)}, "2020-05-12T21:00:00Z", now))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "options", Value: "", Msg: "must have 2-10 options"},
+		{Location: "body", Param: "closes", Value: "tomorrow", Msg: "must be an RFC3339 time"},
+	}, ValidatePoll(nil, "tomorrow", now))
+}
+
+func TestValidateCrosspost(t *testing.T) {
+	original := &Post{Category: "music"}
+	assert.Empty(t, ValidateCrosspost(original, "funny", ""))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "category", Value: "music", Msg: "must differ from the category of the original"},
+		{Location: "body", Param: "title", Value: "", Msg: "must be at most 300 characters"},
+	}, ValidateCrosspost(original, "music", strings.Repeat("a", MaxTitleLength+1)))
+
+	crosspost := &Post{Category: "funny", Crosspost: &Crosspost{Category: "music"}}
+	assert.Empty(t, ValidateCrosspost(crosspost, "news", "Again"))
+	assert.Equal(t, "must differ from the category of the original", ValidateCrosspost(crosspost, "music", "")[0].Msg)
+	assert.Equal(t, "must differ from the category of the original", ValidateCrosspost(crosspost, "funny", "")[0].Msg)
+}
+
+func TestValidateImagePost(t *testing.T) {
+	assert.Empty(t, ValidateImagePost("music", "Lorem", 1024))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "title", Value: "", Msg: "is required"},
+		{Location: "body", Param: "image", Value: "", Msg: "is required"},
+	}, ValidateImagePost("music", "", 0))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "image", Value: "", Msg: "must be at most 10 MB"},
+	}, ValidateImagePost("music", "Lorem", MaxImageSize+1))
+}
+
+func TestValidateComment(t *testing.T) {
+	assert.Empty(t, ValidateComment("Nice post"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "comment", Value: "\n", Msg: "is required"},
+	}, ValidateComment("\n"))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "comment", Value: "", Msg: "must be at most 10000 characters"},
+	}, ValidateComment(strings.Repeat("a", MaxCommentLength+1)))
+}
+
+func TestValidateFlairs(t *testing.T) {
+	assert.Empty(t, ValidateFlairs([]category.Flair{{Name: "discussion", Color: "#FF00aa"}, {Name: "news"}}))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "flairs[0].name", Value: " ", Msg: "is required"},
+		{Location: "body", Param: "flairs[1].name", Value: "", Msg: "must be at most 32 characters"},
+		{Location: "body", Param: "flairs[1].color", Value: "#fff", Msg: "must look like #a1b2c3"},
+	}, ValidateFlairs([]category.Flair{{Name: " "}, {Name: strings.Repeat("a", MaxFlairLength+1), Color: "#fff"}}))
+	assert.Equal(t, "must have at most 20 flairs", ValidateFlairs(make([]category.Flair, MaxFlairs+1))[0].Msg)
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, then Rate requests per
+// second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every makes a limit of one request per interval after the burst.
+func Every(interval time.Duration, burst int) Limit {
+	return Limit{Rate: float64(time.Second) / float64(interval), Burst: burst}
+}
+
+// fillTime is how long an empty bucket takes to get full again.
+func (l Limit) fillTime() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Store keeps the buckets. Take spends a token of the bucket and returns 0 or
+// how long to wait for the next token if the bucket is empty.
+type Store interface {
+	Take(key string, limit Limit) (time.Duration, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryStore keeps the buckets of a single server process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepEvery is how often the full buckets are forgotten, a full bucket is
+// the same as no bucket.
+const sweepEvery = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (time.Duration, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= sweepEvery {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	if b.tokens < 1 {
+		wait := time.Duration(math.Ceil((1 - b.tokens) / limit.Rate * float64(time.Second)))
+		return wait, nil
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second)))
+	return 0, nil
+}
+
+// sweep forgets the buckets which are full by now, s.mu must be held.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limit := Every(10*time.Second, 2)
+
+	//The burst goes through
+	for i := 0; i < 2; i++ {
+		wait, err := store.Take("posts:user:1", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+	wait, err := store.Take("posts:user:1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, wait)
+
+	//Other keys have their own buckets
+	wait, _ = store.Take("posts:user:2", limit)
+	assert.Equal(t, time.Duration(0), wait)
+
+	//A token comes back after the interval
+	now = now.Add(4 * time.Second)
+	wait, _ = store.Take("posts:user:1", limit)
+	assert.Equal(t, 6*time.Second, wait)
+	now = now.Add(6 * time.Second)
+	wait, _ = store.Take("posts:user:1", limit)
+	assert.Equal(t, time.Duration(0), wait)
+	wait, _ = store.Take("posts:user:1", limit)
+	assert.Equal(t, 10*time.Second, wait)
+
+	//Full buckets are forgotten
+	now = now.Add(time.Hour)
+	store.Take("posts:user:3", limit)
+	assert.Equal(t, 1, len(store.buckets))
+}
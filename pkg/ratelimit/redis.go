@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// takeScript refills and spends a bucket kept in a Redis hash in one atomic
+// step. It returns 0 or the milliseconds to wait for the next token.
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+local wait = 0
+if tokens < 1 then
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+else
+	tokens = tokens - 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return wait
+`
+
+// ErrRedisDown is returned without trying Redis for a while after it couldn't
+// be dialed.
+var ErrRedisDown = errors.New("redis is down, try later")
+
+// RedisStore keeps the buckets in Redis, so all the servers behind a load
+// balancer share them. It speaks the Redis protocol over a single
+// connection, the commands take turns on it: each is one short script, so
+// the connection isn't the bottleneck of a rate limiter.
+//
+// When the connection drops, the command on it fails and isn't retried, the
+// next command dials again. When the dial fails, the commands fail with
+// ErrRedisDown for Backoff instead of each waiting for Timeout in turn. The
+// middleware lets the requests through while the store fails.
+type RedisStore struct {
+	Addr    string
+	Prefix  string
+	Timeout time.Duration
+	Backoff time.Duration
+
+	mu        sync.Mutex
+	conn      net.Conn
+	rd        *bufio.Reader
+	downUntil time.Time
+	now       func() time.Time
+}
+
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{
+		Addr:    addr,
+		Prefix:  "ratelimit:",
+		Timeout: time.Second,
+		Backoff: time.Second,
+		now:     time.Now,
+	}
+}
+
+func (s *RedisStore) Take(key string, limit Limit) (time.Duration, error) {
+	now := s.now().UnixNano() / int64(time.Millisecond)
+	ttl := limit.fillTime()/time.Millisecond + 1000
+	reply, err := s.do("EVAL", takeScript, "1", s.Prefix+key,
+		strconv.FormatFloat(limit.Rate, 'f', -1, 64),
+		strconv.Itoa(limit.Burst),
+		strconv.FormatInt(now, 10),
+		strconv.FormatInt(int64(ttl), 10),
+	)
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// Close closes the connection, the next command opens a new one.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reset()
+}
+
+func (s *RedisStore) reset() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.rd = nil
+	return err
+}
+
+// do sends a command and reads its reply. A broken connection is dropped,
+// but the command isn't retried: a retried EVAL could spend a token twice.
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if s.now().Before(s.downUntil) {
+			return nil, ErrRedisDown
+		}
+		conn, err := net.DialTimeout("tcp", s.Addr, s.Timeout)
+		if err != nil {
+			s.downUntil = s.now().Add(s.Backoff)
+			return nil, err
+		}
+		s.conn = conn
+		s.rd = bufio.NewReader(conn)
+	}
+	s.conn.SetDeadline(time.Now().Add(s.Timeout))
+	_, err := s.conn.Write(encodeCommand(args))
+	if err != nil {
+		s.reset()
+		return nil, err
+	}
+	reply, err := readReply(s.rd)
+	if _, isRedisErr := err.(redisError); err != nil && !isRedisErr {
+		s.reset()
+	}
+	return reply, err
+}
+
+// redisError is an error reply of the server, the connection is fine after it.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// encodeCommand makes a RESP array of bulk strings.
+func encodeCommand(args []string) []byte {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	return buf
+}
+
+// readReply reads a RESP reply: strings, integers, nils and arrays of them.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("bad redis reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		_, err = io.ReadFull(rd, buf)
+		if err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			items[i], err = readReply(rd)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("bad redis reply %q", string(kind)+line)
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis answers the commands with the replies in turn and keeps the
+// commands it got.
+func fakeRedis(t *testing.T, replies ...string) (string, chan []interface{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := make(chan []interface{}, len(replies))
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		for _, reply := range replies {
+			command, err := readReply(rd)
+			if err != nil {
+				return
+			}
+			commands <- command.([]interface{})
+			conn.Write([]byte(reply))
+		}
+	}()
+	return listener.Addr().String(), commands
+}
+
+func TestRedisStore(t *testing.T) {
+	addr, commands := fakeRedis(t, ":0\r\n", ":1500\r\n", "-ERR unknown command\r\n")
+	store := NewRedisStore(addr)
+	defer store.Close()
+	store.now = func() time.Time { return time.Unix(1589310000, 0) }
+	limit := Every(10*time.Second, 2)
+
+	wait, err := store.Take("posts:user:1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+	command := <-commands
+	assert.Equal(t, []interface{}{"EVAL", takeScript, "1", "ratelimit:posts:user:1", "0.1", "2", "1589310000000", "21000"}, command)
+
+	wait, err = store.Take("posts:user:1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, wait)
+
+	_, err = store.Take("posts:user:1", limit)
+	assert.EqualError(t, err, "redis: ERR unknown command")
+}
+
+func TestReadReply(t *testing.T) {
+	rd := bufio.NewReader(strings.NewReader("+OK\r\n$5\r\nhello\r\n$-1\r\n*2\r\n:1\r\n$0\r\n\r\n"))
+	for _, expected := range []interface{}{"OK", "hello", nil, []interface{}{int64(1), ""}} {
+		reply, err := readReply(rd)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, reply)
+	}
+	_, err := readReply(bufio.NewReader(strings.NewReader("?\r\n")))
+	assert.Error(t, err)
+}
+
+func TestRedisStoreReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		// the first connection drops in the middle of the command
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		readReply(bufio.NewReader(conn))
+		conn.Close()
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readReply(bufio.NewReader(conn))
+		conn.Write([]byte(":0\r\n"))
+	}()
+	store := NewRedisStore(listener.Addr().String())
+	defer store.Close()
+	limit := Every(10*time.Second, 2)
+
+	_, err = store.Take("posts:user:1", limit)
+	assert.Error(t, err)
+	wait, err := store.Take("posts:user:1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+}
+
+func TestRedisStoreBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	store := NewRedisStore(listener.Addr().String())
+	now := time.Unix(1589310000, 0)
+	store.now = func() time.Time { return now }
+	limit := Every(10*time.Second, 2)
+
+	_, err = store.Take("posts:user:1", limit)
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrRedisDown, err)
+	now = now.Add(store.Backoff / 2)
+	_, err = store.Take("posts:user:1", limit)
+	assert.Equal(t, ErrRedisDown, err)
+	// the store dials again after the backoff
+	now = now.Add(store.Backoff)
+	_, err = store.Take("posts:user:1", limit)
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrRedisDown, err)
+}
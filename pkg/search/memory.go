@@ -0,0 +1,148 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Title words count more than words in the post text or comments.
+const (
+	titleWeight   = 3
+	textWeight    = 1
+	commentWeight = 1
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// MemoryIndex is an inverted index kept in process memory. It suits tests
+// and small deployments, everything is lost on restart and must be reindexed.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[bson.ObjectId]*Document
+	lengths  map[bson.ObjectId]int
+	postings map[string]map[bson.ObjectId]int // term -> post -> weighted term frequency
+	totalLen int
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[bson.ObjectId]*Document),
+		lengths:  make(map[bson.ObjectId]int),
+		postings: make(map[string]map[bson.ObjectId]int),
+	}
+}
+
+func (idx *MemoryIndex) Index(doc *Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(doc.PostID)
+
+	frequencies := make(map[string]int)
+	length := 0
+	add := func(text string, weight int) {
+		for _, term := range Tokenize(text) {
+			frequencies[term] += weight
+			length += weight
+		}
+	}
+	add(doc.Title, titleWeight)
+	add(doc.Text, textWeight)
+	for _, comment := range doc.Comments {
+		add(comment, commentWeight)
+	}
+
+	for term, frequency := range frequencies {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = make(map[bson.ObjectId]int)
+			idx.postings[term] = posting
+		}
+		posting[doc.PostID] = frequency
+	}
+	idx.docs[doc.PostID] = doc
+	idx.lengths[doc.PostID] = length
+	idx.totalLen += length
+	return nil
+}
+
+func (idx *MemoryIndex) Remove(postID bson.ObjectId) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(postID)
+	return nil
+}
+
+func (idx *MemoryIndex) remove(postID bson.ObjectId) {
+	if _, ok := idx.docs[postID]; !ok {
+		return
+	}
+	for term, posting := range idx.postings {
+		delete(posting, postID)
+		if len(posting) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= idx.lengths[postID]
+	delete(idx.lengths, postID)
+	delete(idx.docs, postID)
+}
+
+func (idx *MemoryIndex) Search(q *Query) (*Result, error) {
+	terms := Tokenize(q.Text)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	nDocs := float64(len(idx.docs))
+	avgLen := 1.0
+	if len(idx.docs) != 0 && idx.totalLen != 0 {
+		avgLen = float64(idx.totalLen) / nDocs
+	}
+	scores := make(map[bson.ObjectId]float64)
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (nDocs-df+0.5)/(df+0.5))
+		for postID, frequency := range posting {
+			if !q.matches(idx.docs[postID]) {
+				continue
+			}
+			tf := float64(frequency)
+			norm := 1 - b + b*float64(idx.lengths[postID])/avgLen
+			scores[postID] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for postID, score := range scores {
+		hits = append(hits, Hit{PostID: postID, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		createdI, createdJ := idx.docs[hits[i].PostID].Created, idx.docs[hits[j].PostID].Created
+		if !createdI.Equal(createdJ) {
+			return createdI.After(createdJ)
+		}
+		return hits[i].PostID < hits[j].PostID
+	})
+	return q.page(hits), nil
+}
@@ -0,0 +1,178 @@
+package search
+
+import (
+	"math"
+	"reddit/pkg/posts"
+	"reddit/pkg/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	golangTitle = &Document{
+		PostID:   bson.ObjectIdHex("5ebaf9ee3c04c17c56f51241"),
+		Title:    "Golang generics are coming",
+		Text:     "The draft design was published",
+		Category: "programming",
+		Author:   "rvasily",
+		Type:     "text",
+		Created:  time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC),
+	}
+	golangText = &Document{
+		PostID:   bson.ObjectIdHex("5ebaf9ee3c04c17c56f51242"),
+		Title:    "Weekly news",
+		Text:     "Rust, golang and zig release notes",
+		Category: "news",
+		Author:   "igor",
+		Type:     "text",
+		Created:  time.Date(2020, 5, 11, 12, 0, 0, 0, time.UTC),
+	}
+	golangComment = &Document{
+		PostID:   bson.ObjectIdHex("5ebaf9ee3c04c17c56f51243"),
+		Title:    "New album",
+		Category: "music",
+		Author:   "igor",
+		Type:     "link",
+		Comments: []string{"Listening to it while writing golang"},
+		Created:  time.Date(2020, 5, 12, 12, 0, 0, 0, time.UTC),
+	}
+)
+
+func newTestIndex() *MemoryIndex {
+	idx := NewMemoryIndex()
+	idx.Index(golangTitle)
+	idx.Index(golangText)
+	idx.Index(golangComment)
+	return idx
+}
+
+func hitIDs(result *Result) []bson.ObjectId {
+	ids := make([]bson.ObjectId, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.PostID)
+	}
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"golang", "generics", "coming", "2020"},
+		Tokenize("Golang: the generics are coming in 2020!"))
+	assert.Equal(t, []string{"привет", "мир"}, Tokenize("Привет, мир"))
+	assert.Empty(t, Tokenize(" ,. the "))
+}
+
+func TestMemorySearchRanking(t *testing.T) {
+	idx := newTestIndex()
+
+	result, err := idx.Search(&Query{Text: "Golang", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, golangTitle.PostID, result.Hits[0].PostID)
+	assert.Len(t, result.Hits, 3)
+
+	result, err = idx.Search(&Query{Text: "golang release", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, golangText.PostID, result.Hits[0].PostID)
+
+	result, err = idx.Search(&Query{Text: "python", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Total)
+	assert.Empty(t, result.Hits)
+
+	_, err = idx.Search(&Query{Text: " the ", Limit: 10})
+	assert.Equal(t, ErrEmptyQuery, err)
+}
+
+func TestMemorySearchFilters(t *testing.T) {
+	idx := newTestIndex()
+
+	result, _ := idx.Search(&Query{Text: "golang", Category: "music", Limit: 10})
+	assert.Equal(t, []bson.ObjectId{golangComment.PostID}, hitIDs(result))
+
+	result, _ = idx.Search(&Query{Text: "golang", Author: "igor", Limit: 10})
+	assert.ElementsMatch(t, []bson.ObjectId{golangText.PostID, golangComment.PostID}, hitIDs(result))
+
+	result, _ = idx.Search(&Query{Text: "golang", Type: "link", Limit: 10})
+	assert.Equal(t, []bson.ObjectId{golangComment.PostID}, hitIDs(result))
+
+	result, _ = idx.Search(&Query{
+		Text:  "golang",
+		From:  time.Date(2020, 5, 11, 0, 0, 0, 0, time.UTC),
+		To:    time.Date(2020, 5, 11, 23, 59, 59, 0, time.UTC),
+		Limit: 10,
+	})
+	assert.Equal(t, []bson.ObjectId{golangText.PostID}, hitIDs(result))
+}
+
+func TestMemorySearchHidden(t *testing.T) {
+	idx := newTestIndex()
+	nsfw := *golangText
+	nsfw.NSFW = true
+	nsfw.Flair = "Release"
+	idx.Index(&nsfw)
+	pending := *golangComment
+	pending.Pending = true
+	idx.Index(&pending)
+
+	// hidden posts don't count in the total
+	result, _ := idx.Search(&Query{Text: "golang", HideNSFW: true, Limit: 1})
+	assert.Equal(t, []bson.ObjectId{golangTitle.PostID}, hitIDs(result))
+	assert.Equal(t, 1, result.Total)
+
+	result, _ = idx.Search(&Query{Text: "golang", Flair: "Release", Limit: 10})
+	assert.Equal(t, []bson.ObjectId{golangText.PostID}, hitIDs(result))
+	assert.Equal(t, 1, result.Total)
+}
+
+func TestMemorySearchPagination(t *testing.T) {
+	idx := newTestIndex()
+
+	all, _ := idx.Search(&Query{Text: "golang", Limit: 10})
+	first, _ := idx.Search(&Query{Text: "golang", Page: 1, Limit: 2})
+	second, _ := idx.Search(&Query{Text: "golang", Page: 2, Limit: 2})
+	third, _ := idx.Search(&Query{Text: "golang", Page: 3, Limit: 2})
+	huge, _ := idx.Search(&Query{Text: "golang", Page: math.MaxInt64, Limit: 100})
+
+	assert.Equal(t, 3, second.Total)
+	assert.Equal(t, hitIDs(all), append(hitIDs(first), hitIDs(second)...))
+	assert.Empty(t, third.Hits)
+	assert.Empty(t, huge.Hits)
+	assert.Equal(t, 3, huge.Total)
+}
+
+func TestMemoryIndexUpdates(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Remove(golangTitle.PostID)
+	result, _ := idx.Search(&Query{Text: "generics", Limit: 10})
+	assert.Equal(t, 0, result.Total)
+
+	updated := *golangComment
+	updated.Comments = []string{"Nice guitar"}
+	idx.Index(&updated)
+	result, _ = idx.Search(&Query{Text: "golang", Limit: 10})
+	assert.Equal(t, []bson.ObjectId{golangText.PostID}, hitIDs(result))
+	result, _ = idx.Search(&Query{Text: "guitar", Limit: 10})
+	assert.Equal(t, []bson.ObjectId{golangComment.PostID}, hitIDs(result))
+}
+
+func TestNewDocument(t *testing.T) {
+	post := &posts.Post{
+		ID:       golangTitle.PostID,
+		Author:   &user.User{ID: 1, Username: "rvasily"},
+		Category: "programming",
+		Created:  "2020-05-12T22:33:02+03:00",
+		Title:    "Lorem",
+		Text:     "Ipsum",
+		Type:     "text",
+	}
+	comments := []*posts.Comment{{Body: "dolor"}}
+
+	doc := NewDocument(post, comments)
+	assert.Equal(t, "rvasily", doc.Author)
+	assert.Equal(t, []string{"dolor"}, doc.Comments)
+	assert.Equal(t, int64(1589311982), doc.Created.Unix())
+}
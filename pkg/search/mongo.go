@@ -0,0 +1,149 @@
+package search
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoSearcher uses MongoDB text indexes over the posts and comments
+// collections. Mongo keeps the indexes up to date itself, so Index and
+// Remove have nothing to do.
+type MongoSearcher struct {
+	Posts    *mgo.Collection
+	Comments *mgo.Collection
+}
+
+type textHit struct {
+	ID       bson.ObjectId   `bson:"_id"`
+	Score    float64         `bson:"score"`
+	Created  string          `bson:"created"`
+	Comments []bson.ObjectId `bson:"comments"`
+}
+
+func NewMongoSearcher(posts, comments *mgo.Collection) *MongoSearcher {
+	return &MongoSearcher{Posts: posts, Comments: comments}
+}
+
+func (s *MongoSearcher) EnsureIndexes() error {
+	err := s.Posts.EnsureIndex(mgo.Index{
+		Key:     []string{"$text:title", "$text:text"},
+		Weights: map[string]int{"title": titleWeight, "text": textWeight},
+		Name:    "posts_text",
+	})
+	if err != nil {
+		return err
+	}
+	return s.Comments.EnsureIndex(mgo.Index{
+		Key:  []string{"$text:body"},
+		Name: "comments_text",
+	})
+}
+
+func (s *MongoSearcher) Index(doc *Document) error {
+	return nil
+}
+
+func (s *MongoSearcher) Remove(postID bson.ObjectId) error {
+	return nil
+}
+
+func (s *MongoSearcher) Search(q *Query) (*Result, error) {
+	if len(Tokenize(q.Text)) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	filters := bson.M{"pending": bson.M{"$ne": true}}
+	if q.Category != "" {
+		filters["category"] = q.Category
+	}
+	if q.Author != "" {
+		filters["author.username"] = q.Author
+	}
+	if q.Type != "" {
+		filters["type"] = q.Type
+	}
+	if q.Flair != "" {
+		filters["flair"] = q.Flair
+	}
+	if q.HideNSFW {
+		filters["nsfw"] = bson.M{"$ne": true}
+	}
+	textSearch := bson.M{"$search": q.Text}
+	withScore := bson.M{"_id": 1, "created": 1, "score": bson.M{"$meta": "textScore"}}
+
+	postQuery := bson.M{"$text": textSearch}
+	for key, value := range filters {
+		postQuery[key] = value
+	}
+	postHits := []*textHit{}
+	err := s.Posts.Find(postQuery).Select(withScore).All(&postHits)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+
+	commentHits := []*textHit{}
+	err = s.Comments.Find(bson.M{"$text": textSearch}).Select(withScore).All(&commentHits)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, err
+	}
+	commentScores := make(map[bson.ObjectId]float64, len(commentHits))
+	commentIDs := make([]bson.ObjectId, 0, len(commentHits))
+	for _, hit := range commentHits {
+		commentScores[hit.ID] = hit.Score
+		commentIDs = append(commentIDs, hit.ID)
+	}
+	if len(commentIDs) != 0 {
+		commentedQuery := bson.M{"comments": bson.M{"$in": commentIDs}}
+		for key, value := range filters {
+			commentedQuery[key] = value
+		}
+		commentedPosts := []*textHit{}
+		err = s.Posts.Find(commentedQuery).
+			Select(bson.M{"_id": 1, "created": 1, "comments": 1}).
+			All(&commentedPosts)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			return nil, err
+		}
+		for _, post := range commentedPosts {
+			for _, commentID := range post.Comments {
+				post.Score += commentWeight * commentScores[commentID]
+			}
+		}
+		postHits = append(postHits, commentedPosts...)
+	}
+
+	scores := make(map[bson.ObjectId]float64)
+	created := make(map[bson.ObjectId]time.Time)
+	for _, hit := range postHits {
+		createdAt, _ := time.Parse(time.RFC3339, hit.Created)
+		if !q.From.IsZero() && createdAt.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && createdAt.After(q.To) {
+			continue
+		}
+		scores[hit.ID] += hit.Score
+		created[hit.ID] = createdAt
+	}
+	hits := make([]Hit, 0, len(scores))
+	for postID, score := range scores {
+		hits = append(hits, Hit{PostID: postID, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		createdI, createdJ := created[hits[i].PostID], created[hits[j].PostID]
+		if !createdI.Equal(createdJ) {
+			return createdI.After(createdJ)
+		}
+		return hits[i].PostID < hits[j].PostID
+	})
+	return q.page(hits), nil
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"errors"
+	"reddit/pkg/paging"
+	"reddit/pkg/posts"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrEmptyQuery = errors.New("Empty search query")
+)
+
+// Document is everything a searcher needs to know about a post.
+type Document struct {
+	PostID   bson.ObjectId
+	Title    string
+	Text     string
+	Comments []string
+	Category string
+	Author   string
+	Type     string
+	Flair    string
+	NSFW     bool
+	Pending  bool
+	Created  time.Time
+}
+
+// Query is a full-text query with optional filters, zero values mean "any".
+// Posts waiting for approval are never found.
+type Query struct {
+	Text     string
+	Category string
+	Author   string
+	Type     string
+	Flair    string
+	HideNSFW bool
+	From     time.Time
+	To       time.Time
+	Page     int
+	Limit    int
+}
+
+type Hit struct {
+	PostID bson.ObjectId
+	Score  float64
+}
+
+// Result holds one page of hits ordered by relevance and the total number of matches.
+type Result struct {
+	Hits  []Hit
+	Total int
+}
+
+type Searcher interface {
+	Index(*Document) error
+	Remove(bson.ObjectId) error
+	Search(*Query) (*Result, error)
+}
+
+func NewDocument(post *posts.Post, comments []*posts.Comment) *Document {
+	doc := &Document{
+		PostID:   post.ID,
+		Title:    post.Title,
+		Text:     post.Text,
+		Comments: make([]string, 0, len(comments)),
+		Category: post.Category,
+		Type:     post.Type,
+		Flair:    post.Flair,
+		NSFW:     post.NSFW,
+		Pending:  post.Pending,
+	}
+	if post.Author != nil {
+		doc.Author = post.Author.Username
+	}
+	doc.Created, _ = time.Parse(time.RFC3339, post.Created)
+	for _, comment := range comments {
+		doc.Comments = append(doc.Comments, comment.Body)
+	}
+	return doc
+}
+
+func (q *Query) matches(doc *Document) bool {
+	if doc.Pending {
+		return false
+	}
+	if q.Category != "" && q.Category != doc.Category {
+		return false
+	}
+	if q.Author != "" && q.Author != doc.Author {
+		return false
+	}
+	if q.Type != "" && q.Type != doc.Type {
+		return false
+	}
+	if q.Flair != "" && q.Flair != doc.Flair {
+		return false
+	}
+	if q.HideNSFW && doc.NSFW {
+		return false
+	}
+	if !q.From.IsZero() && doc.Created.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && doc.Created.After(q.To) {
+		return false
+	}
+	return true
+}
+
+// page cuts the sorted hits down to the requested page.
+func (q *Query) page(hits []Hit) *Result {
+	result := &Result{
+		Hits:  []Hit{},
+		Total: len(hits),
+	}
+	page, limit := q.Page, q.Limit
+	if page < 1 {
+		page = 1
+	}
+	from, to := paging.Bounds(page, limit, len(hits))
+	result.Hits = hits[from:to]
+	return result
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"to": true, "was": true, "with": true,
+}
+
+// Tokenize splits text into lowercase terms without punctuation and stop words.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if stopWords[field] {
+			continue
+		}
+		terms = append(terms, field)
+	}
+	return terms
+}
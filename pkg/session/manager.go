@@ -131,3 +131,29 @@ func (sm *SessionsManager) Create(w http.ResponseWriter, userS *user.User) (int6
 	w.Write(resp)
 	return sessID, nil
 }
+
+// RevokeAll ends every session of the user, the tokens issued for them stop
+// passing Check.
+func (sm *SessionsManager) RevokeAll(userID int64) (int64, error) {
+	result, err := sm.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Revoked reports whether the session was ended, e.g. by RevokeAll. An
+// expired session isn't revoked, only its token stops passing Check.
+func (sm *SessionsManager) Revoked(sess *Session) (bool, error) {
+	var userID int64
+	err := sm.DB.
+		QueryRow("SELECT `user_id` FROM sessions WHERE id = ?", sess.ID).
+		Scan(&userID)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return userID != sess.User.ID, nil
+}
@@ -0,0 +1,105 @@
+package unfurl
+
+import (
+	"html"
+	"net/url"
+	"reddit/pkg/posts"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRegexp   = regexp.MustCompile(`(?is)<(meta|link)\s([^>]*)>`)
+	titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	attrRegexp  = regexp.MustCompile(`(?s)([a-zA-Z_:.-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>/]+))`)
+	spaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+const maxFieldLength = 500
+
+func parseAttrs(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attrRegexp.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(match[1])
+		value := match[2] + match[3] + match[4]
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return attrs
+}
+
+func clean(text string) string {
+	text = strings.TrimSpace(spaceRegexp.ReplaceAllString(text, " "))
+	if runes := []rune(text); len(runes) > maxFieldLength {
+		text = string(runes[:maxFieldLength])
+	}
+	return text
+}
+
+// resolve makes link absolute and drops anything that isn't http(s),
+// a "javascript:" image must not get to the frontend.
+func resolve(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+	return abs.String()
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ExtractPreview reads OpenGraph, Twitter card and plain HTML metadata of
+// a page. OpenGraph wins over Twitter cards which win over plain HTML.
+func ExtractPreview(page []byte, base *url.URL) *posts.LinkPreview {
+	meta := make(map[string]string)
+	favicon := ""
+	for _, match := range tagRegexp.FindAllStringSubmatch(string(page), -1) {
+		attrs := parseAttrs(match[2])
+		if strings.ToLower(match[1]) == "link" {
+			for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+				if rel == "icon" && favicon == "" {
+					favicon = attrs["href"]
+				}
+			}
+			continue
+		}
+		key := strings.ToLower(first(attrs["property"], attrs["name"]))
+		if key == "" {
+			continue
+		}
+		if _, ok := meta[key]; !ok {
+			meta[key] = attrs["content"]
+		}
+	}
+	title := ""
+	if match := titleRegexp.FindSubmatch(page); match != nil {
+		title = html.UnescapeString(string(match[1]))
+	}
+	if favicon == "" {
+		favicon = "/favicon.ico"
+	}
+
+	return &posts.LinkPreview{
+		Title:       clean(first(meta["og:title"], meta["twitter:title"], title)),
+		Description: clean(first(meta["og:description"], meta["twitter:description"], meta["description"])),
+		Image:       resolve(base, first(meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])),
+		SiteName:    clean(first(meta["og:site_name"], base.Hostname())),
+		Favicon:     resolve(base, favicon),
+	}
+}
@@ -0,0 +1,93 @@
+package unfurl
+
+import (
+	"context"
+	"reddit/pkg/posts"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type PreviewStore interface {
+	SetPreview(bson.ObjectId, *posts.LinkPreview) error
+}
+
+type job struct {
+	postID bson.ObjectId
+	link   string
+}
+
+// Queue unfurls links in background workers so creating a post never waits
+// for a foreign site. When the queue is full new links are dropped.
+type Queue struct {
+	Unfurler *Unfurler
+	Store    PreviewStore
+	Logger   *zap.SugaredLogger
+	Timeout  time.Duration
+	jobs     chan job
+	wg       sync.WaitGroup
+	// mu guards closed, so no link is sent after jobs is closed
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewQueue(unfurler *Unfurler, store PreviewStore, logger *zap.SugaredLogger, workers, size int) *Queue {
+	q := &Queue{
+		Unfurler: unfurler,
+		Store:    store,
+		Logger:   logger,
+		Timeout:  DefaultTimeout,
+		jobs:     make(chan job, size),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *Queue) Enqueue(postID bson.ObjectId, link string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		q.Logger.Infof("Unfurl queue is closed, skip post %v", postID.Hex())
+		return false
+	}
+	select {
+	case q.jobs <- job{postID: postID, link: link}:
+		return true
+	default:
+		q.Logger.Errorf("Unfurl queue is full, skip post %v", postID.Hex())
+		return false
+	}
+}
+
+// Close stops accepting links and waits for the workers to finish.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), q.Timeout)
+		preview, err := q.Unfurler.Unfurl(ctx, j.link)
+		cancel()
+		if err != nil {
+			q.Logger.Infof("Can't unfurl %v: %v", j.link, err)
+			continue
+		}
+		err = q.Store.SetPreview(j.postID, preview)
+		if err != nil {
+			q.Logger.Errorf("Can't save preview of post %v: %v", j.postID.Hex(), err)
+		}
+	}
+}
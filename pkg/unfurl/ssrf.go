@@ -0,0 +1,82 @@
+package unfurl
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrPrivateAddress = errors.New("Address is not public")
+)
+
+var privateNets = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}()
+
+// IsPrivateIP reports whether ip belongs to loopback, private, link-local,
+// multicast or otherwise reserved ranges which must never be fetched.
+func IsPrivateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, ipNet := range privateNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeDialer checks the address right before connecting, after DNS has been
+// resolved, so a public name pointing to an internal IP is refused as well.
+func safeDialer(timeout time.Duration, allowPrivate bool) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || IsPrivateIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reddit/pkg/posts"
+	"time"
+)
+
+var (
+	ErrBadScheme   = errors.New("Only http and https links can be unfurled")
+	ErrNotHTML     = errors.New("Linked page is not HTML")
+	ErrTooManyHops = errors.New("Too many redirects")
+	ErrBadStatus   = errors.New("Linked page answered with an error")
+)
+
+const (
+	DefaultMaxBytes = 512 << 10
+	DefaultTimeout  = 5 * time.Second
+	maxRedirects    = 5
+)
+
+// Unfurler downloads linked pages and extracts their preview metadata.
+// Only the first MaxBytes of a page are read, which is plenty for <head>.
+type Unfurler struct {
+	Client    *http.Client
+	MaxBytes  int64
+	UserAgent string
+}
+
+// New creates an unfurler which refuses to connect to private addresses
+// unless allowPrivate is set, that is meant for tests only.
+func New(timeout time.Duration, maxBytes int64, allowPrivate bool) *Unfurler {
+	dialer := safeDialer(timeout, allowPrivate)
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyHops
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrBadScheme
+			}
+			return nil
+		},
+	}
+	return &Unfurler{
+		Client:    client,
+		MaxBytes:  maxBytes,
+		UserAgent: "redditclone-unfurler/1.0",
+	}
+}
+
+func (u *Unfurler) Unfurl(ctx context.Context, link string) (*posts.LinkPreview, error) {
+	target, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, ErrBadScheme
+	}
+	req, err := http.NewRequest("GET", target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", u.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%w: %v", ErrBadStatus, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+	page, err := ioutil.ReadAll(io.LimitReader(resp.Body, u.MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	return ExtractPreview(page, resp.Request.URL), nil
+}
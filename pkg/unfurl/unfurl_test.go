@@ -0,0 +1,201 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reddit/pkg/posts"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ogPage = `<!DOCTYPE html>
+<html><head>
+<title>Plain title</title>
+<meta property="og:title" content="Gophers &amp; friends">
+<meta property="og:description" content='All about
+   Go'>
+<meta property="og:image" content="/img/gopher.png">
+<meta property="og:site_name" content="Go Blog">
+<meta name="twitter:title" content="Twitter title">
+<link rel="shortcut icon" href="/static/favicon.png">
+</head><body>Hello</body></html>`
+
+const twitterPage = `<html><head>
+<TITLE>Plain title</TITLE>
+<meta name="twitter:title" content="Twitter title" />
+<meta name="description" content="Plain description" />
+<meta name="twitter:image:src" content="javascript:alert(1)" />
+</head></html>`
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, ogPage)
+	})
+	mux.HandleFunc("/twitter", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, twitterPage)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/og", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat(" ", 4096)+`<title>Too far</title></head></html>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, ogPage)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	private := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "172.31.255.255", "192.168.1.1",
+		"169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"}
+	public := []string{"8.8.8.8", "172.32.0.1", "93.184.216.34", "2606:4700:4700::1111"}
+	for _, ip := range private {
+		assert.True(t, IsPrivateIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range public {
+		assert.False(t, IsPrivateIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestUnfurlBlocksPrivateAddresses(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	u := New(time.Second, DefaultMaxBytes, false)
+	_, err := u.Unfurl(context.Background(), server.URL+"/og")
+	assert.True(t, errors.Is(err, ErrPrivateAddress), fmt.Sprintf("Unexpected error: %v", err))
+
+	_, err = u.Unfurl(context.Background(), "file:///etc/passwd")
+	assert.Equal(t, ErrBadScheme, err)
+}
+
+func TestUnfurl(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	u := New(time.Second, DefaultMaxBytes, true)
+
+	preview, err := u.Unfurl(context.Background(), server.URL+"/og")
+	assert.Nil(t, err)
+	assert.Equal(t, &posts.LinkPreview{
+		Title:       "Gophers & friends",
+		Description: "All about Go",
+		Image:       server.URL + "/img/gopher.png",
+		SiteName:    "Go Blog",
+		Favicon:     server.URL + "/static/favicon.png",
+	}, preview)
+
+	preview, err = u.Unfurl(context.Background(), server.URL+"/twitter")
+	assert.Nil(t, err)
+	assert.Equal(t, &posts.LinkPreview{
+		Title:       "Twitter title",
+		Description: "Plain description",
+		SiteName:    "127.0.0.1",
+		Favicon:     server.URL + "/favicon.ico",
+	}, preview)
+
+	preview, err = u.Unfurl(context.Background(), server.URL+"/redirect")
+	assert.Nil(t, err)
+	assert.Equal(t, "Gophers & friends", preview.Title)
+}
+
+func TestUnfurlLimits(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	u := New(100*time.Millisecond, 1024, true)
+
+	preview, err := u.Unfurl(context.Background(), server.URL+"/huge")
+	assert.Nil(t, err)
+	assert.Equal(t, "", preview.Title)
+
+	_, err = u.Unfurl(context.Background(), server.URL+"/slow")
+	assert.NotNil(t, err)
+
+	_, err = u.Unfurl(context.Background(), server.URL+"/loop")
+	assert.True(t, errors.Is(err, ErrTooManyHops), fmt.Sprintf("Unexpected error: %v", err))
+
+	_, err = u.Unfurl(context.Background(), server.URL+"/json")
+	assert.Equal(t, ErrNotHTML, err)
+
+	_, err = u.Unfurl(context.Background(), server.URL+"/missing")
+	assert.True(t, errors.Is(err, ErrBadStatus), fmt.Sprintf("Unexpected error: %v", err))
+}
+
+type fakeStore struct {
+	mu       sync.Mutex
+	previews map[bson.ObjectId]*posts.LinkPreview
+}
+
+func (s *fakeStore) SetPreview(postID bson.ObjectId, preview *posts.LinkPreview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previews[postID] = preview
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+
+	store := &fakeStore{previews: make(map[bson.ObjectId]*posts.LinkPreview)}
+	q := NewQueue(New(time.Second, DefaultMaxBytes, true), store, zapLogger.Sugar(), 2, 10)
+
+	okID, badID := bson.NewObjectId(), bson.NewObjectId()
+	assert.True(t, q.Enqueue(okID, server.URL+"/og"))
+	assert.True(t, q.Enqueue(badID, server.URL+"/missing"))
+	q.Close()
+
+	assert.Len(t, store.previews, 1)
+	assert.Equal(t, "Gophers & friends", store.previews[okID].Title)
+}
+
+func TestQueueEnqueueAfterClose(t *testing.T) {
+	zapLogger, _ := zap.NewProduction()
+	defer zapLogger.Sync()
+
+	store := &fakeStore{previews: make(map[bson.ObjectId]*posts.LinkPreview)}
+	q := NewQueue(New(time.Second, DefaultMaxBytes, true), store, zapLogger.Sugar(), 1, 10)
+	q.Close()
+	q.Close()
+
+	assert.False(t, q.Enqueue(bson.NewObjectId(), "http://example.com"))
+	assert.Empty(t, store.previews)
+}
+
+func TestExtractPreviewResolvesAgainstBase(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	preview := ExtractPreview([]byte(`<meta content="img.png" property="og:image"><link href="//cdn.example.com/i.ico" rel="icon">`), base)
+	assert.Equal(t, "https://example.com/blog/img.png", preview.Image)
+	assert.Equal(t, "https://cdn.example.com/i.ico", preview.Favicon)
+	assert.Equal(t, "example.com", preview.SiteName)
+}
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"reddit/pkg/validation"
+	"time"
+)
+
+const MaxBanReasonLength = 500
+
+// Ban keeps the user away from the whole site. Times are RFC3339, empty
+// Expires means the ban never ends. The table keeps unix seconds like the
+// sessions table does.
+type Ban struct {
+	UserID  int64  `json:"user,string"`
+	Reason  string `json:"reason,omitempty"`
+	Created string `json:"created"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// ValidateBan checks the reason and the expiry of a ban, both are optional.
+// now is passed in to compare the expiry with. Category bans are checked the
+// same way.
+func ValidateBan(reason, expires string, now time.Time) validation.Errors {
+	errs := validation.Errors{}
+	errs.MaxLength("reason", reason, MaxBanReasonLength)
+	errs.Future("expires", expires, now)
+	return errs
+}
+
+type BansRepo struct {
+	DB *sql.DB
+}
+
+func NewBansRepo(db *sql.DB) *BansRepo {
+	return &BansRepo{DB: db}
+}
+
+// Ban bans the user, banning again replaces the reason and the expiry.
+func (repo *BansRepo) Ban(ban *Ban) error {
+	created := time.Now()
+	var expires int64
+	if ban.Expires != "" {
+		expiresTime, err := time.Parse(time.RFC3339, ban.Expires)
+		if err != nil {
+			return fmt.Errorf("Bad expiry: %v", err)
+		}
+		expires = expiresTime.Unix()
+	}
+	_, err := repo.DB.Exec(
+		"INSERT INTO bans (`user_id`, `reason`, `create_time`, `exp_time`) VALUES (?, ?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `reason` = VALUES(`reason`), "+
+			"`create_time` = VALUES(`create_time`), `exp_time` = VALUES(`exp_time`)",
+		ban.UserID,
+		ban.Reason,
+		created.Unix(),
+		expires,
+	)
+	if err != nil {
+		return fmt.Errorf("BD error: %v", err)
+	}
+	ban.Created = created.Format(time.RFC3339)
+	return nil
+}
+
+func (repo *BansRepo) Unban(userID int64) (bool, error) {
+	result, err := repo.DB.Exec("DELETE FROM bans WHERE user_id = ?", userID)
+	if err != nil {
+		return false, fmt.Errorf("BD error: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("BD error: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// Get returns the active ban of the user or nil if the user isn't banned.
+func (repo *BansRepo) Get(userID int64) (*Ban, error) {
+	ban := &Ban{UserID: userID}
+	var created, expires int64
+	err := repo.DB.
+		QueryRow("SELECT reason, create_time, exp_time FROM bans WHERE user_id = ? AND (exp_time = 0 OR exp_time > ?)",
+			userID, time.Now().Unix()).
+		Scan(&ban.Reason, &created, &expires)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("BD error: %v", err)
+	}
+	ban.Created = time.Unix(created, 0).Format(time.RFC3339)
+	if expires != 0 {
+		ban.Expires = time.Unix(expires, 0).Format(time.RFC3339)
+	}
+	return ban, nil
+}
@@ -0,0 +1,101 @@
+package user
+
+import (
+	"fmt"
+	"reddit/pkg/validation"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestBans(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewBansRepo(db)
+
+	mock.
+		ExpectExec("INSERT INTO bans").
+		WithArgs(1, "spam", sqlmock.AnyArg(), 1893456000).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	ban := &Ban{UserID: 1, Reason: "spam", Expires: "2030-01-01T00:00:00Z"}
+	assert.Nil(repo.Ban(ban))
+	assert.NotEmpty(ban.Created)
+
+	assert.Error(repo.Ban(&Ban{UserID: 1, Expires: "tomorrow"}))
+
+	// active ban
+	mock.
+		ExpectQuery("SELECT reason, create_time, exp_time FROM bans WHERE").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reason", "create_time", "exp_time"}).AddRow("spam", 1589312000, 0))
+	ban, err = repo.Get(1)
+	assert.Nil(err)
+	assert.Equal("spam", ban.Reason)
+	assert.Equal(time.Unix(1589312000, 0).Format(time.RFC3339), ban.Created)
+	assert.Empty(ban.Expires)
+
+	mock.
+		ExpectQuery("SELECT reason, create_time, exp_time FROM bans WHERE").
+		WithArgs(3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reason", "create_time", "exp_time"}).AddRow("", 1589312000, 1893456000))
+	ban, err = repo.Get(3)
+	assert.Nil(err)
+	assert.Equal(time.Unix(1893456000, 0).Format(time.RFC3339), ban.Expires)
+
+	// not banned or expired
+	mock.
+		ExpectQuery("SELECT reason, create_time, exp_time FROM bans WHERE").
+		WithArgs(2, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reason", "create_time", "exp_time"}))
+	ban, err = repo.Get(2)
+	assert.Nil(err)
+	assert.Nil(ban)
+
+	mock.
+		ExpectQuery("SELECT reason, create_time, exp_time FROM bans WHERE").
+		WithArgs(2, sqlmock.AnyArg()).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.Get(2)
+	assert.EqualError(err, "BD error: db_error")
+
+	mock.
+		ExpectExec("DELETE FROM bans WHERE").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	ok, err := repo.Unban(1)
+	assert.Nil(err)
+	assert.True(ok)
+
+	mock.
+		ExpectExec("DELETE FROM bans WHERE").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	ok, err = repo.Unban(2)
+	assert.Nil(err)
+	assert.False(ok)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestValidateBan(t *testing.T) {
+	now := time.Date(2020, 5, 12, 22, 33, 2, 0, time.UTC)
+	assert.Empty(t, ValidateBan("", "", now))
+	assert.Empty(t, ValidateBan("Spam", "2020-05-19T22:33:02Z", now))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "reason", Value: "", Msg: "must be at most 500 characters"},
+		{Location: "body", Param: "expires", Value: "2020-05-01T00:00:00Z", Msg: "must be in the future"},
+	}, ValidateBan(strings.Repeat("a", MaxBanReasonLength+1), "2020-05-01T00:00:00Z", now))
+	assert.Equal(t, validation.Errors{
+		{Location: "body", Param: "expires", Value: "next week", Msg: "must be an RFC3339 time"},
+	}, ValidateBan("", "next week", now))
+}
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Preferences are per-user settings, a user without a stored row has the
+// zero value.
+type Preferences struct {
+	ShowNSFW bool `json:"showNSFW"`
+}
+
+type PreferencesRepo struct {
+	DB *sql.DB
+}
+
+func NewPreferencesRepo(db *sql.DB) *PreferencesRepo {
+	return &PreferencesRepo{DB: db}
+}
+
+func (repo *PreferencesRepo) Get(userID int64) (*Preferences, error) {
+	prefs := &Preferences{}
+	err := repo.DB.
+		QueryRow("SELECT show_nsfw FROM preferences WHERE user_id = ?", userID).
+		Scan(&prefs.ShowNSFW)
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("BD error: %v", err)
+	}
+	return prefs, nil
+}
+
+func (repo *PreferencesRepo) Set(userID int64, prefs *Preferences) error {
+	_, err := repo.DB.Exec(
+		"INSERT INTO preferences (`user_id`, `show_nsfw`) VALUES (?, ?) "+
+			"ON DUPLICATE KEY UPDATE `show_nsfw` = VALUES(`show_nsfw`)",
+		userID,
+		prefs.ShowNSFW,
+	)
+	if err != nil {
+		return fmt.Errorf("BD error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestPreferences(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewPreferencesRepo(db)
+
+	// stored preferences
+	mock.
+		ExpectQuery("SELECT show_nsfw FROM preferences WHERE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"show_nsfw"}).AddRow(true))
+	prefs, err := repo.Get(1)
+	assert.Nil(err)
+	assert.Equal(&Preferences{ShowNSFW: true}, prefs)
+
+	// nothing stored yet
+	mock.
+		ExpectQuery("SELECT show_nsfw FROM preferences WHERE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"show_nsfw"}))
+	prefs, err = repo.Get(2)
+	assert.Nil(err)
+	assert.Equal(&Preferences{}, prefs)
+
+	// query error
+	mock.
+		ExpectQuery("SELECT show_nsfw FROM preferences WHERE").
+		WithArgs(2).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.Get(2)
+	assert.EqualError(err, "BD error: db_error")
+
+	mock.
+		ExpectExec("INSERT INTO preferences").
+		WithArgs(1, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Nil(repo.Set(1, &Preferences{ShowNSFW: true}))
+
+	mock.
+		ExpectExec("INSERT INTO preferences").
+		WithArgs(1, false).
+		WillReturnError(fmt.Errorf("db_error"))
+	assert.EqualError(repo.Set(1, &Preferences{}), "BD error: db_error")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
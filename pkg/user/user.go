@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 )
@@ -35,6 +36,9 @@ func (repo *UserRepo) Authorize(login, pass string) (*User, error) {
 		QueryRow("SELECT `id`, `password` FROM users WHERE username = ?", login).
 		Scan(&userID, &passwordDB)
 	fmt.Printf("This : %v, %v, %v", err, userID, passwordDB)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoUser
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +55,7 @@ func (repo *UserRepo) Authorize(login, pass string) (*User, error) {
 
 func (repo *UserRepo) Add(login, pass string) (int64, error) {
 	result, err := repo.DB.Exec(
-		"INSERT INTO users (`username`, `password`) VALUES (?, ?)",
+		"INSERT INTO users (`username`, `password`, `create_time`) VALUES (?, ?, UNIX_TIMESTAMP())",
 		login,
 		pass,
 	)
@@ -83,3 +87,40 @@ func (repo *UserRepo) GetByID(id int64) (*User, error) {
 	}
 	return user, nil
 }
+
+func (repo *UserRepo) GetByUsername(login string) (*User, error) {
+	var password string
+	var userID int64
+	err := repo.DB.
+		QueryRow("SELECT id, password FROM users WHERE username = ?",
+			login).
+		Scan(&userID, &password)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoUser
+	}
+	if err != nil {
+		return nil, fmt.Errorf("BD error: %v", err)
+	}
+	user := &User{
+		ID:       userID,
+		Username: login,
+		password: password,
+	}
+	return user, nil
+}
+
+// Created tells when the user has signed up. Users who signed up before the
+// time was recorded look as old as the epoch.
+func (repo *UserRepo) Created(userID int64) (time.Time, error) {
+	var created int64
+	err := repo.DB.
+		QueryRow("SELECT create_time FROM users WHERE id = ?", userID).
+		Scan(&created)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrNoUser
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("BD error: %v", err)
+	}
+	return time.Unix(created, 0), nil
+}
@@ -1,8 +1,10 @@
 package user
 
 import (
+	"database/sql"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
@@ -187,6 +189,17 @@ func TestAuthorize(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 
+	//no user
+	mock.
+		ExpectQuery("SELECT `id`, `password` FROM users WHERE").
+		WithArgs(testUser.Username).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.Authorize(testUser.Username, testUser.password)
+	if !assert.Equal(ErrNoUser, err) {
+		return
+	}
+
 	//bad pass
 
 	rows = sqlmock.
@@ -211,3 +224,64 @@ func TestAuthorize(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestGetByUsername(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepo(db)
+
+	mock.
+		ExpectQuery("SELECT id, password FROM users WHERE").
+		WithArgs("rvasily").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, "lovelove"))
+	user, err := repo.GetByUsername("rvasily")
+	assert.Nil(err)
+	assert.Equal(&User{ID: 1, Username: "rvasily", password: "lovelove"}, user)
+
+	// no such user
+	mock.
+		ExpectQuery("SELECT id, password FROM users WHERE").
+		WithArgs("nobody").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "password"}))
+	_, err = repo.GetByUsername("nobody")
+	assert.Equal(ErrNoUser, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreated(t *testing.T) {
+	assert := assert.New(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepo(db)
+
+	mock.
+		ExpectQuery("SELECT create_time FROM users WHERE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"create_time"}).AddRow(1589312000))
+	created, err := repo.Created(1)
+	assert.Nil(err)
+	assert.Equal(time.Unix(1589312000, 0), created)
+
+	mock.
+		ExpectQuery("SELECT create_time FROM users WHERE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"create_time"}))
+	_, err = repo.Created(3)
+	assert.Equal(ErrNoUser, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package validation collects the errors of request fields in the shape the
+// frontend already uses for form errors, so every package can check its own
+// requests.
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+	Location string `json:"location"`
+	Param    string `json:"param"`
+	Value    string `json:"value"`
+	Msg      string `json:"msg"`
+}
+
+type Errors []FieldError
+
+func (errs *Errors) Add(param, value, msg string) {
+	*errs = append(*errs, FieldError{
+		Location: "body",
+		Param:    param,
+		Value:    value,
+		Msg:      msg,
+	})
+}
+
+// MaxLength adds an error if value is longer than max characters, the long
+// value isn't echoed back.
+func (errs *Errors) MaxLength(param, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		errs.Add(param, "", fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// Required adds an error if value is blank or longer than max characters.
+func (errs *Errors) Required(param, value string, max int) {
+	if strings.TrimSpace(value) == "" {
+		errs.Add(param, value, "is required")
+		return
+	}
+	errs.MaxLength(param, value, max)
+}
+
+// Future adds an error unless value is an RFC3339 time after now, an empty
+// value is fine.
+func (errs *Errors) Future(param, value string, now time.Time) {
+	if value == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		errs.Add(param, value, "must be an RFC3339 time")
+	} else if !t.After(now) {
+		errs.Add(param, value, "must be in the future")
+	}
+}
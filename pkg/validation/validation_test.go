@@ -0,0 +1,37 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequired(t *testing.T) {
+	errs := Errors{}
+	errs.Required("title", "News", 10)
+	assert.Empty(t, errs)
+	errs.Required("title", " ", 10)
+	errs.Required("text", strings.Repeat("я", 11), 10)
+	errs.MaxLength("reason", strings.Repeat("a", 11), 10)
+	assert.Equal(t, Errors{
+		{Location: "body", Param: "title", Value: " ", Msg: "is required"},
+		{Location: "body", Param: "text", Value: "", Msg: "must be at most 10 characters"},
+		{Location: "body", Param: "reason", Value: "", Msg: "must be at most 10 characters"},
+	}, errs)
+}
+
+func TestFuture(t *testing.T) {
+	now := time.Date(2020, 5, 12, 19, 0, 0, 0, time.UTC)
+	errs := Errors{}
+	errs.Future("expires", "", now)
+	errs.Future("expires", "2020-05-13T19:00:00Z", now)
+	assert.Empty(t, errs)
+	errs.Future("expires", "tomorrow", now)
+	errs.Future("expires", "2020-05-12T19:00:00Z", now)
+	assert.Equal(t, Errors{
+		{Location: "body", Param: "expires", Value: "tomorrow", Msg: "must be an RFC3339 time"},
+		{Location: "body", Param: "expires", Value: "2020-05-12T19:00:00Z", Msg: "must be in the future"},
+	}, errs)
+}
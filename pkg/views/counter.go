@@ -0,0 +1,169 @@
+package views
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	DefaultWindow     = 24 * time.Hour
+	DefaultFlushEvery = 10 * time.Second
+	DefaultMaxViewers = 1000000
+)
+
+// Store receives the views gathered since the last flush. It removes the
+// posts it has saved from the map, so on error only the rest is retried.
+type Store interface {
+	AddViews(map[bson.ObjectId]int) error
+}
+
+// Counter counts a view of a post at most once per viewer within Window.
+// Recent viewers are kept in memory with their expiry time, the counted views
+// are written to the Store in batches by a background goroutine.
+type Counter struct {
+	Store      Store
+	Logger     *zap.SugaredLogger
+	Window     time.Duration
+	MaxViewers int
+
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	pending map[bson.ObjectId]int
+	now     func() time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewCounter(store Store, logger *zap.SugaredLogger, window, flushEvery time.Duration) *Counter {
+	c := newCounter(store, logger, window)
+	go c.run(flushEvery)
+	return c
+}
+
+func newCounter(store Store, logger *zap.SugaredLogger, window time.Duration) *Counter {
+	return &Counter{
+		Store:      store,
+		Logger:     logger,
+		Window:     window,
+		MaxViewers: DefaultMaxViewers,
+		seen:       make(map[string]time.Time),
+		pending:    make(map[bson.ObjectId]int),
+		now:        time.Now,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Count registers a view of the post and reports whether it was counted.
+func (c *Counter) Count(postID bson.ObjectId, viewer string) bool {
+	key := postID.Hex() + "|" + viewer
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if expires, ok := c.seen[key]; ok && now.Before(expires) {
+		return false
+	}
+	if len(c.seen) >= c.MaxViewers {
+		c.prune(now)
+		if len(c.seen) >= c.MaxViewers {
+			// somebody floods us with new viewers, views are not worth the memory
+			return false
+		}
+	}
+	c.seen[key] = now.Add(c.Window)
+	c.pending[postID]++
+	return true
+}
+
+// prune forgets the viewers whose window is over, c.mu must be held.
+func (c *Counter) prune(now time.Time) {
+	for key, expires := range c.seen {
+		if !now.Before(expires) {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// Flush writes the pending views to the Store. Views the Store failed to
+// save are kept for the next flush.
+func (c *Counter) Flush() error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[bson.ObjectId]int)
+	c.prune(c.now())
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	err := c.Store.AddViews(pending)
+	if err != nil {
+		c.mu.Lock()
+		for postID, views := range pending {
+			c.pending[postID] += views
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close stops the background flushes and writes what is left.
+func (c *Counter) Close() {
+	close(c.stop)
+	<-c.done
+}
+
+func (c *Counter) run(flushEvery time.Duration) {
+	defer close(c.done)
+	ticker := time.NewTicker(flushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := c.Flush()
+			if err != nil {
+				c.Logger.Errorf("Can't flush views: %v", err)
+			}
+		case <-c.stop:
+			err := c.Flush()
+			if err != nil {
+				c.Logger.Errorf("Can't flush views: %v", err)
+			}
+			return
+		}
+	}
+}
+
+var botMarkers = []string{"bot", "crawl", "spider", "slurp", "curl", "wget", "python-requests"}
+
+// IsBot is a cheap guess by the User-Agent, real browsers always send one.
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Viewer identifies who looks at the post: the user ID for authenticated
+// requests and the client IP for anonymous ones. The IP must be the one
+// behind the trusted proxies, otherwise all the clients behind the reverse
+// proxy would be one viewer.
+func Viewer(userID int64, clientIP string) string {
+	if userID != 0 {
+		return "u" + strconv.FormatInt(userID, 10)
+	}
+	return "ip" + clientIP
+}
@@ -0,0 +1,99 @@
+package views
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type fakeStore struct {
+	views  map[bson.ObjectId]int
+	failed bson.ObjectId
+}
+
+func (s *fakeStore) AddViews(views map[bson.ObjectId]int) error {
+	for postID, count := range views {
+		if postID == s.failed {
+			continue
+		}
+		s.views[postID] += count
+		delete(views, postID)
+	}
+	if len(views) != 0 {
+		return fmt.Errorf("Internal error")
+	}
+	return nil
+}
+
+func TestCounter(t *testing.T) {
+	store := &fakeStore{views: map[bson.ObjectId]int{}}
+	counter := newCounter(store, zap.NewNop().Sugar(), time.Hour)
+	now := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	counter.now = func() time.Time { return now }
+	first := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	second := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51245")
+
+	assert.True(t, counter.Count(first, "u1"))
+	assert.False(t, counter.Count(first, "u1"))
+	assert.True(t, counter.Count(first, "ip10.0.0.1"))
+	assert.True(t, counter.Count(second, "u1"))
+	assert.NoError(t, counter.Flush())
+	assert.Equal(t, map[bson.ObjectId]int{first: 2, second: 1}, store.views)
+
+	//Nothing new
+	assert.NoError(t, counter.Flush())
+	assert.Equal(t, map[bson.ObjectId]int{first: 2, second: 1}, store.views)
+
+	//The window is over
+	now = now.Add(time.Hour)
+	assert.True(t, counter.Count(first, "u1"))
+	assert.False(t, counter.Count(first, "u1"))
+
+	//Failed views are retried
+	store.failed = first
+	assert.True(t, counter.Count(second, "u2"))
+	assert.Error(t, counter.Flush())
+	assert.Equal(t, map[bson.ObjectId]int{first: 2, second: 2}, store.views)
+	store.failed = ""
+	assert.NoError(t, counter.Flush())
+	assert.Equal(t, map[bson.ObjectId]int{first: 3, second: 2}, store.views)
+}
+
+func TestCounterMaxViewers(t *testing.T) {
+	store := &fakeStore{views: map[bson.ObjectId]int{}}
+	counter := newCounter(store, zap.NewNop().Sugar(), time.Minute)
+	counter.MaxViewers = 2
+	now := time.Date(2020, 5, 12, 22, 0, 0, 0, time.UTC)
+	counter.now = func() time.Time { return now }
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+
+	assert.True(t, counter.Count(postID, "u1"))
+	assert.True(t, counter.Count(postID, "u2"))
+	assert.False(t, counter.Count(postID, "u3"))
+
+	now = now.Add(time.Minute)
+	assert.True(t, counter.Count(postID, "u3"))
+	assert.Len(t, counter.seen, 1)
+}
+
+func TestCounterClose(t *testing.T) {
+	store := &fakeStore{views: map[bson.ObjectId]int{}}
+	counter := NewCounter(store, zap.NewNop().Sugar(), time.Hour, time.Hour)
+	postID := bson.ObjectIdHex("5ebaf9ee3c04c17c56f51244")
+	counter.Count(postID, "u1")
+	counter.Close()
+	assert.Equal(t, map[bson.ObjectId]int{postID: 1}, store.views)
+}
+
+func TestViewer(t *testing.T) {
+	assert.Equal(t, "ip203.0.113.7", Viewer(0, "203.0.113.7"))
+	assert.Equal(t, "u7", Viewer(7, "203.0.113.7"))
+
+	assert.True(t, IsBot(""))
+	assert.True(t, IsBot("Mozilla/5.0 (compatible; Googlebot/2.1)"))
+	assert.False(t, IsBot("Mozilla/5.0 (X11; Linux x86_64) Firefox/76.0"))
+}